@@ -0,0 +1,68 @@
+package httpclient
+
+import (
+	"net/http"
+)
+
+// APIKeyLocation is where SetAPIKey puts the key.
+type APIKeyLocation int
+
+const (
+	// APIKeyHeader sends the key in a request header.
+	APIKeyHeader APIKeyLocation = iota
+	// APIKeyQuery sends the key in a query parameter.
+	APIKeyQuery
+)
+
+type apiKey struct {
+	key, name, prefix string
+	in                APIKeyLocation
+}
+
+// SetAPIKey makes c send key with every request, including those of Files,
+// in the header or query parameter name. A request that sets name itself,
+// e.g. with WithRequestHeader or WithQueryParam, keeps its own value. The
+// name is added to the redaction rules so that the key does not show in
+// logs, debug output or errors, nor in the redirects to another host. It
+// must be called before c is used.
+func (c *httpClient) SetAPIKey(key string, in APIKeyLocation, name string) {
+	c.apiKey = &apiKey{key: key, name: name, in: in}
+	if in == APIKeyQuery {
+		c.redact.add(nil, []string{name}, nil)
+	} else {
+		c.redact.add([]string{name}, nil, nil)
+	}
+}
+
+// SetAPIKeyAuthorization makes c send key with every request in an
+// "Authorization: ApiKey <key>" header, as SetAPIKey does.
+func (c *httpClient) SetAPIKeyAuthorization(key string) {
+	c.SetAPIKey(key, APIKeyHeader, "Authorization")
+	c.apiKey.prefix = "ApiKey "
+}
+
+// SetAPIKey sets the API key of the default client.
+func SetAPIKey(key string, in APIKeyLocation, name string) {
+	defaultClient().SetAPIKey(key, in, name)
+}
+
+// SetAPIKeyAuthorization sets the API key of the default client, sent in
+// the Authorization header.
+func SetAPIKeyAuthorization(key string) {
+	defaultClient().SetAPIKeyAuthorization(key)
+}
+
+// apply adds the key to req unless it is already set.
+func (k *apiKey) apply(req *http.Request) {
+	if k.in == APIKeyQuery {
+		q := req.URL.Query()
+		if _, ok := q[k.name]; !ok {
+			q.Set(k.name, k.key)
+			req.URL.RawQuery = q.Encode()
+		}
+		return
+	}
+	if req.Header.Get(k.name) == "" {
+		req.Header.Set(k.name, k.prefix+k.key)
+	}
+}
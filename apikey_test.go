@@ -0,0 +1,133 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// keyServer echoes the API key it finds in the X-Api-Key header, the
+// Authorization header and the api_key parameter, and fails requests to
+// /fail with a 500.
+func keyServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(r.Header.Get("X-Api-Key") + "|" + r.Header.Get("Authorization") + "|" + r.URL.Query().Get("api_key")))
+	}))
+}
+
+func TestSetAPIKey(t *testing.T) {
+	srv := keyServer()
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		setup func(c *httpClient)
+		url   string
+		opts  []RequestOption
+		want  string
+	}{
+		{"header", func(c *httpClient) { c.SetAPIKey("k1", APIKeyHeader, "X-Api-Key") }, "/", nil, "k1||"},
+		{"header name case", func(c *httpClient) { c.SetAPIKey("k1", APIKeyHeader, "x-api-key") }, "/", nil, "k1||"},
+		{"query", func(c *httpClient) { c.SetAPIKey("k2", APIKeyQuery, "api_key") }, "/?page=2", nil, "||k2"},
+		{"authorization", func(c *httpClient) { c.SetAPIKeyAuthorization("k3") }, "/", nil, "|ApiKey k3|"},
+		{
+			"header per request",
+			func(c *httpClient) { c.SetAPIKey("k1", APIKeyHeader, "X-Api-Key") },
+			"/",
+			[]RequestOption{WithRequestHeader("X-Api-Key", "mine")},
+			"mine||",
+		},
+		{
+			"query per request",
+			func(c *httpClient) { c.SetAPIKey("k2", APIKeyQuery, "api_key") },
+			"/",
+			[]RequestOption{WithQueryParam("api_key", "mine")},
+			"||mine",
+		},
+		{"query in the URL", func(c *httpClient) { c.SetAPIKey("k2", APIKeyQuery, "api_key") }, "/?api_key=url", nil, "||url"},
+		{
+			"authorization per request",
+			func(c *httpClient) { c.SetAPIKeyAuthorization("k3") },
+			"/",
+			[]RequestOption{WithRequestHeader("Authorization", "Bearer t")},
+			"|Bearer t|",
+		},
+	}
+	for _, tt := range tests {
+		c := New(WithBaseURL(srv.URL))
+		tt.setup(c)
+		got, err := c.String(tt.url, tt.opts...)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestSetAPIKeyFiles(t *testing.T) {
+	srv := keyServer()
+	defer srv.Close()
+
+	for _, in := range []APIKeyLocation{APIKeyHeader, APIKeyQuery} {
+		c := New()
+		name, want := "X-Api-Key", "k||"
+		if in == APIKeyQuery {
+			name, want = "api_key", "||k"
+		}
+		c.SetAPIKey("k", in, name)
+		var files []File
+		if err := c.Files([]string{srv.URL + "/a", srv.URL + "/b", srv.URL + "/c?x=1"}, &files); err != nil {
+			t.Fatal(err)
+		}
+		for i, f := range files {
+			if string(f.Data) != want {
+				t.Errorf("%s: file %d: got %q, want %q", name, i, f.Data, want)
+			}
+		}
+	}
+}
+
+func TestSetAPIKeyRedaction(t *testing.T) {
+	srv := keyServer()
+	defer srv.Close()
+	const key = "sk-live-0123456789"
+
+	for _, in := range []APIKeyLocation{APIKeyHeader, APIKeyQuery} {
+		var debug syncBuffer
+		logs := &recordLogger{}
+		c := New(WithBaseURL(srv.URL), WithLogger(logs), WithLogLevel(LevelDebug), WithDebug(&debug))
+		c.SetAPIKey(key, in, "api_key")
+
+		_, err := c.String("/fail?page=2")
+		var herr *Error
+		if !errors.As(err, &herr) || herr.StatusCode != 500 {
+			t.Fatalf("got %v, want a 500", err)
+		}
+		if strings.Contains(err.Error(), key) || strings.Contains(herr.URL, key) {
+			t.Errorf("%d: error shows the key: %v (%s)", in, err, herr.URL)
+		}
+		if in == APIKeyQuery && !strings.Contains(err.Error(), "api_key=[REDACTED]") {
+			t.Errorf("%d: got %v, want the key redacted", in, err)
+		}
+		if strings.Contains(logs.String(), key) {
+			t.Errorf("%d: logs show the key:\n%s", in, logs)
+		}
+		if strings.Contains(debug.String(), key) || !strings.Contains(debug.String(), "[REDACTED]") {
+			t.Errorf("%d: debug output shows the key:\n%s", in, debug.String())
+		}
+	}
+
+	// Nor does the error of a request that could not be sent.
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	c := New()
+	c.SetAPIKey(key, APIKeyQuery, "api_key")
+	if _, err := c.String(dead.URL + "/x"); err == nil || strings.Contains(err.Error(), key) {
+		t.Errorf("got %v, want an error without the key", err)
+	}
+}
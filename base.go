@@ -0,0 +1,92 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ErrOutsideBase is returned for URLs that resolve outside of the base URL
+// of a client created with WithRestrictToBase.
+var ErrOutsideBase = errors.New("httpclient: URL outside of the base URL")
+
+// WithBaseURL makes the client resolve relative URLs against base, as
+// url.URL.ResolveReference does: with a base of "https://api.example.com/v1",
+// "users/42" becomes "https://api.example.com/v1/users/42" (the base path is
+// treated as a directory even without a trailing slash) while "/users/42"
+// becomes "https://api.example.com/users/42". Absolute URLs are used as they
+// are. Query parameters of the base are added to every URL on the same host
+// that does not set them itself.
+//
+// An invalid base makes every request fail.
+func WithBaseURL(base string) Option {
+	return func(c *httpClient) {
+		u, err := url.Parse(base)
+		if err == nil && (u.Scheme == "" || u.Host == "") {
+			err = errors.New("not an absolute URL")
+		}
+		if err != nil {
+			c.base = nil
+			c.baseErr = fmt.Errorf("httpclient: invalid base URL %q: %v", base, err)
+			return
+		}
+		if !strings.HasSuffix(u.Path, "/") {
+			u.Path += "/"
+			if u.RawPath != "" {
+				u.RawPath += "/"
+			}
+		}
+		c.base, c.baseErr = u, nil
+	}
+}
+
+// WithRestrictToBase makes the client refuse URLs that resolve outside of
+// its base URL, be it another host or a path above the base path such as
+// "../../admin", with ErrOutsideBase.
+func WithRestrictToBase() Option {
+	return func(c *httpClient) {
+		c.restrict = true
+	}
+}
+
+// resolve returns rawurl resolved against the base URL of the client.
+func (c *httpClient) resolve(rawurl string) (string, error) {
+	if c.baseErr != nil {
+		return "", c.baseErr
+	}
+	if c.base == nil {
+		return rawurl, nil
+	}
+	ref, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	u := c.base.ResolveReference(ref)
+	if c.base.RawQuery != "" && strings.EqualFold(u.Host, c.base.Host) {
+		q := u.Query()
+		for k, vs := range c.base.Query() {
+			if _, ok := q[k]; !ok {
+				q[k] = vs
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	if c.restrict && !within(u, c.base) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideBase, c.redact.url(u))
+	}
+	return u.String(), nil
+}
+
+// within reports whether u is at or below base.
+func within(u, base *url.URL) bool {
+	if !strings.EqualFold(u.Scheme, base.Scheme) || !strings.EqualFold(u.Host, base.Host) {
+		return false
+	}
+	p := path.Clean("/" + u.Path)
+	if strings.HasSuffix(u.Path, "/") && p != "/" {
+		p += "/"
+	}
+	return p+"/" == base.Path || strings.HasPrefix(p, base.Path)
+}
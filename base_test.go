@@ -0,0 +1,94 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		base, url, want string
+	}{
+		// The base path is a directory, with or without a trailing slash.
+		{"https://api.example.com/v1", "users/42", "https://api.example.com/v1/users/42"},
+		{"https://api.example.com/v1/", "users/42", "https://api.example.com/v1/users/42"},
+		{"https://api.example.com/v1", "./users/", "https://api.example.com/v1/users/"},
+		{"https://api.example.com/v1", "", "https://api.example.com/v1/"},
+		{"https://api.example.com", "users", "https://api.example.com/users"},
+		// A rooted path replaces the base path.
+		{"https://api.example.com/v1", "/v2/users", "https://api.example.com/v2/users"},
+		{"https://api.example.com/v1/users", "../teams", "https://api.example.com/v1/teams"},
+		// Absolute URLs override the base.
+		{"https://api.example.com/v1", "http://other.example.com/x", "http://other.example.com/x"},
+		{"https://api.example.com/v1", "//cdn.example.com/x", "https://cdn.example.com/x"},
+		// The query of the base applies to the URLs of its host that do not
+		// set the parameter.
+		{"https://api.example.com/v1?key=k&v=1", "users?v=2", "https://api.example.com/v1/users?key=k&v=2"},
+		{"https://api.example.com/v1?key=k", "https://other.example.com/", "https://other.example.com/"},
+		{"https://api.example.com/v1", "users?q=a+b#top", "https://api.example.com/v1/users?q=a+b#top"},
+	}
+	for _, tt := range tests {
+		got, err := New(WithBaseURL(tt.base)).resolve(tt.url)
+		if err != nil || got != tt.want {
+			t.Errorf("base %s: resolve(%q) = %q, %v, want %q", tt.base, tt.url, got, err, tt.want)
+		}
+	}
+	if got, err := New().resolve("users/42"); err != nil || got != "users/42" {
+		t.Errorf("without a base: got %q, %v", got, err)
+	}
+}
+
+func TestRestrictToBase(t *testing.T) {
+	c := New(WithBaseURL("https://api.example.com/v1/"), WithRestrictToBase())
+	for _, u := range []string{"users/42", "", "users/../teams", "https://api.example.com/v1/x", "/v1/"} {
+		if _, err := c.resolve(u); err != nil {
+			t.Errorf("%q: %v", u, err)
+		}
+	}
+	for _, u := range []string{
+		"../../admin",
+		"users/../../admin",
+		"/admin",
+		"/v1evil",
+		"https://other.example.com/v1/",
+		"http://api.example.com/v1/",
+		"/v1/..%2fadmin/../../admin",
+	} {
+		if got, err := c.resolve(u); !errors.Is(err, ErrOutsideBase) {
+			t.Errorf("%q: got %q, %v, want ErrOutsideBase", u, got, err)
+		}
+	}
+}
+
+func TestInvalidBaseURL(t *testing.T) {
+	for _, base := range []string{"api.example.com/v1", "://bad", "/v1"} {
+		if _, err := New(WithBaseURL(base)).Bytes("users"); err == nil {
+			t.Errorf("base %q: got no error", base)
+		}
+	}
+}
+
+func TestBaseURLClone(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.RequestURI())
+	}))
+	defer srv.Close()
+
+	v1 := New(WithBaseURL(srv.URL + "/v1"))
+	v2 := v1.Clone(WithBaseURL(srv.URL + "/v2"))
+	for c, want := range map[*httpClient]string{v1: "/v1/users?page=2", v2: "/v2/users?page=2"} {
+		if got, err := c.String("users", WithQueryParam("page", "2")); err != nil || got != want {
+			t.Errorf("got %q, %v, want %q", got, err, want)
+		}
+	}
+	if got, err := v2.String(srv.URL + "/abs"); err != nil || got != "/abs" {
+		t.Errorf("absolute URL: got %q, %v", got, err)
+	}
+	var files []File
+	if err := v1.Files([]string{"a", "b"}, &files); err != nil || string(files[1].Data) != "/v1/b" {
+		t.Errorf("Files: got %v, %v", files, err)
+	}
+}
@@ -0,0 +1,113 @@
+package httpclient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// SetConcurrency limits the downloads of a batch, with Files, Download or
+// DownloadTo, that run at a time to n. Zero, the default, runs them all at
+// once. It must be called before c is used.
+func (c *httpClient) SetConcurrency(n int) {
+	c.concurrency = n
+}
+
+// SetConcurrency limits the downloads of a batch of the default client
+// that run at a time to n.
+func SetConcurrency(n int) {
+	defaultClient().SetConcurrency(n)
+}
+
+// WithConcurrency limits the downloads of the batch that run at a time to
+// n, overriding SetConcurrency.
+func WithConcurrency(n int) RequestOption {
+	return func(o *requestOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithPartialResults makes Files set the files of the downloads that
+// succeeded even when others failed; the File of each failed download is
+// left empty.
+func WithPartialResults() RequestOption {
+	return func(o *requestOptions) {
+		o.partial = true
+	}
+}
+
+// A batchResult holds the outcome of each download of a batch, by index.
+type batchResult struct {
+	errs      []error
+	durations []time.Duration
+	skipped   []bool
+	redact    *redactor
+}
+
+// batch runs fetch for each of urls, in order, with at most the
+// concurrency of o or c running at a time. Downloads that have not started
+// when the context of o is done are skipped and fail with its error.
+func (c *httpClient) batch(urls []string, o *requestOptions, fetch func(i int, url string) error) *batchResult {
+	l := len(urls)
+	b := &batchResult{
+		errs:      make([]error, l),
+		durations: make([]time.Duration, l),
+		skipped:   make([]bool, l),
+		redact:    c.redact,
+	}
+	workers := o.concurrency
+	if workers <= 0 {
+		workers = c.concurrency
+	}
+	if workers <= 0 || workers > l {
+		workers = l
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := o.ctx.Err(); err != nil {
+					b.skipped[i] = true
+					b.errs[i] = err
+					continue
+				}
+				began := time.Now()
+				b.errs[i] = fetch(i, urls[i])
+				b.durations[i] = time.Since(began)
+			}
+		}()
+	}
+	for i := range urls {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return b
+}
+
+// err returns a *BatchError holding the failed downloads, ordered by
+// index, or, if the context of o is done, an *Error wrapping the context
+// error. It returns nil if every download succeeded.
+func (b *batchResult) err(urls []string, o *requestOptions) error {
+	var failed []*FileError
+	for i, err := range b.errs {
+		if err != nil {
+			fe := &FileError{Index: i, URL: b.redact.urlString(urls[i]), Err: err}
+			var e *Error
+			if errors.As(err, &e) {
+				fe.StatusCode = e.StatusCode
+			}
+			failed = append(failed, fe)
+		}
+	}
+	if failed == nil {
+		return nil
+	}
+	if err := o.ctx.Err(); err != nil {
+		return &Error{Message: "Files: " + err.Error(), Err: err}
+	}
+	return &BatchError{Errors: failed}
+}
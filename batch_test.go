@@ -0,0 +1,257 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFilesErrorOrderStress(t *testing.T) {
+	const n = 500
+	rng := rand.New(rand.NewSource(1))
+	fail := make(map[int]bool)
+	for i := 0; i < n; i++ {
+		if rng.Intn(5) == 0 {
+			fail[i] = true
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		// Random delays make the downloads finish out of order.
+		time.Sleep(time.Duration(rand.Intn(2000)) * time.Microsecond)
+		if fail[i] {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, "file %d", i)
+	}))
+	defer srv.Close()
+
+	urls := make([]string, n)
+	var want []int
+	for i := range urls {
+		urls[i] = fmt.Sprintf("%s/%d", srv.URL, i)
+		if fail[i] {
+			want = append(want, i)
+		}
+	}
+
+	c := New(WithHTTPClient(&http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: 32}}))
+	for run := 0; run < 3; run++ {
+		var files []File
+		err := c.Files(urls, &files, WithConcurrency(32), WithPartialResults())
+		var be *BatchError
+		if !errors.As(err, &be) {
+			t.Fatalf("run %d: got %v, want a *BatchError", run, err)
+		}
+		var got []int
+		for _, fe := range be.Errors {
+			got = append(got, fe.Index)
+			if fe.URL != urls[fe.Index] || fe.StatusCode != http.StatusInternalServerError {
+				t.Errorf("run %d: error %d is for %s, status %d", run, fe.Index, fe.URL, fe.StatusCode)
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("run %d: errors at %v, want %v", run, got, want)
+		}
+		if !strings.HasPrefix(err.Error(), be.Errors[0].Error()) {
+			t.Errorf("run %d: message %q does not start with the first error", run, err)
+		}
+		for i, f := range files {
+			if fail[i] {
+				continue
+			}
+			if string(f.Data) != fmt.Sprintf("file %d", i) {
+				t.Fatalf("run %d: file %d holds %q", run, i, f.Data)
+			}
+		}
+	}
+}
+
+// peakServer answers after a short wait, and records the most requests
+// it had in flight at once.
+func peakServer() (*httptest.Server, *int32) {
+	var active, peak int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		io.WriteString(w, "x")
+	}))
+	return srv, &peak
+}
+
+func TestFilesConcurrencyBound(t *testing.T) {
+	srv, peak := peakServer()
+	defer srv.Close()
+	urls := make([]string, 40)
+	for i := range urls {
+		urls[i] = srv.URL
+	}
+	var files []File
+	if err := New().Files(urls, &files, WithConcurrency(4)); err != nil {
+		t.Fatal(err)
+	}
+	if *peak > 4 {
+		t.Errorf("got %d concurrent downloads, want at most 4", *peak)
+	}
+	if len(files) != len(urls) {
+		t.Errorf("got %d files, want %d", len(files), len(urls))
+	}
+}
+
+func TestSetConcurrency(t *testing.T) {
+	urls := make([]string, 12)
+	files := func(c *httpClient, urls []string, opts ...RequestOption) error {
+		var files []File
+		return c.Files(urls, &files, opts...)
+	}
+	download := func(c *httpClient, urls []string, opts ...RequestOption) error {
+		var files []File
+		return c.Download(urls, &files, opts...)
+	}
+	downloadTo := func(c *httpClient, urls []string, opts ...RequestOption) error {
+		for i := range urls {
+			urls[i] += fmt.Sprintf("/%d", i)
+		}
+		_, err := c.DownloadTo(urls, t.TempDir(), opts...)
+		return err
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		opts  []RequestOption
+		fetch func(c *httpClient, urls []string, opts ...RequestOption) error
+		want  int32
+	}{
+		{"Files", 3, nil, files, 3},
+		{"Download", 3, nil, download, 3},
+		{"DownloadTo", 3, nil, downloadTo, 3},
+		{"one", 1, nil, files, 1},
+		{"more than the URLs", 50, nil, files, 12},
+		{"unlimited", 0, nil, files, 12},
+		{"WithConcurrency", 3, []RequestOption{WithConcurrency(5)}, files, 5},
+		{"WithConcurrency zero", 3, []RequestOption{WithConcurrency(0)}, files, 3},
+	}
+	for _, tt := range tests {
+		srv, peak := peakServer()
+		for i := range urls {
+			urls[i] = srv.URL
+		}
+		c := New()
+		c.SetConcurrency(tt.limit)
+		if err := tt.fetch(c, urls, tt.opts...); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if *peak != tt.want {
+			t.Errorf("%s: got %d concurrent downloads, want %d", tt.name, *peak, tt.want)
+		}
+		srv.Close()
+	}
+}
+
+func TestFilesFailureLeavesResultAlone(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bad" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	files := []File{{Name: "previous"}}
+	err := New().Files([]string{srv.URL + "/good", srv.URL + "/bad"}, &files)
+	var be *BatchError
+	if !errors.As(err, &be) || len(be.Errors) != 1 || be.Errors[0].Index != 1 {
+		t.Fatalf("got %v", err)
+	}
+	if !errors.Is(be.Errors[0], ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", be.Errors[0])
+	}
+	if len(files) != 1 || files[0].Name != "previous" {
+		t.Errorf("files changed to %+v", files)
+	}
+}
+
+func TestFilesSmall(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		io.WriteString(w, "small "+r.URL.Path)
+	}))
+	defer srv.Close()
+	var files []File
+	if err := New().Files([]string{srv.URL + "/a", srv.URL + "/b"}, &files); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"a", "b"} {
+		f := files[i]
+		if string(f.Data) != "small /"+name || f.ContentType != "text/plain" || !f.LastModified.Equal(modified) || f.Spill != nil {
+			t.Errorf("file %d: got %+v", i, f)
+		}
+	}
+}
+
+// BenchmarkFilesLarge downloads a batch of 4MB files, with and without a
+// Content-Length, and reports the bytes allocated per batch against
+// reading each body with ioutil.ReadAll.
+func BenchmarkFilesLarge(b *testing.B) {
+	body := make([]byte, 4<<20)
+	urls := make([]string, 8)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("http://example.com/%d", i)
+	}
+	for _, length := range []int64{int64(len(body)), -1} {
+		rt := &staticTransport{body: body, length: length}
+		name := "Sized"
+		if length < 0 {
+			name = "Chunked"
+		}
+		b.Run(name+"/ReadAll", func(b *testing.B) {
+			hc := &http.Client{Transport: rt}
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body) * len(urls)))
+			for i := 0; i < b.N; i++ {
+				for _, u := range urls {
+					resp, err := hc.Get(u)
+					if err != nil {
+						b.Fatal(err)
+					}
+					if _, err := ioutil.ReadAll(resp.Body); err != nil {
+						b.Fatal(err)
+					}
+					resp.Body.Close()
+				}
+			}
+		})
+		b.Run(name+"/Files", func(b *testing.B) {
+			c := New(WithTransport(rt))
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body) * len(urls)))
+			for i := 0; i < b.N; i++ {
+				var files []File
+				if err := c.Files(urls, &files, WithConcurrency(4)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package httpclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxPrealloc is the largest Content-Length trusted to size a body buffer
+// up front when no maximum body size is set.
+const maxPrealloc = 64 << 20
+
+// WithMaxBodySize makes the client refuse response bodies larger than n
+// bytes. Such responses fail with an *Error, before anything is read when
+// the Content-Length is known. By default there is no limit.
+func WithMaxBodySize(n int64) Option {
+	return func(c *httpClient) {
+		c.maxBody = n
+	}
+}
+
+func (c *httpClient) tooLarge(resp *http.Response) error {
+	return c.err(resp, fmt.Sprintf("%s: response body larger than %d bytes", c.redact.url(resp.Request.URL), c.maxBody))
+}
+
+// readAll reads the body of resp. When the Content-Length is known, the
+// body is read into a slice of exactly that size, with a single allocation;
+// a body longer than announced is still read whole. Otherwise the body is
+// read in pooled chunks, copied once into the result.
+func (c *httpClient) readAll(resp *http.Response) ([]byte, error) {
+	n := resp.ContentLength
+	limit := int64(maxPrealloc)
+	if c.maxBody > 0 {
+		limit = c.maxBody
+	}
+	if n < 0 || n > limit {
+		if c.maxBody > 0 && n > c.maxBody {
+			return nil, c.tooLarge(resp)
+		}
+		return readChunks(resp.Body, c.maxBody, func() error { return c.tooLarge(resp) })
+	}
+
+	p := make([]byte, n)
+	if _, err := io.ReadFull(resp.Body, p); err != nil {
+		return nil, err
+	}
+	var one [1]byte
+	if _, err := io.ReadAtLeast(resp.Body, one[:], 1); err == io.EOF {
+		return p, nil
+	} else if err != nil {
+		return nil, err
+	}
+	// The server sent more than it announced.
+	buf := bytes.NewBuffer(append(p, one[0]))
+	if err := c.readRest(resp, buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readBody reads the body of resp into buf, growing it once up front when
+// the Content-Length is known.
+func (c *httpClient) readBody(resp *http.Response, buf *bytes.Buffer) error {
+	if n := resp.ContentLength; n > 0 {
+		if c.maxBody > 0 && n > c.maxBody {
+			return c.tooLarge(resp)
+		}
+		if n <= maxPrealloc {
+			buf.Grow(int(n))
+		}
+	}
+	return c.readRest(resp, buf)
+}
+
+// readRest appends the rest of the body of resp to buf, enforcing the
+// maximum body size.
+func (c *httpClient) readRest(resp *http.Response, buf *bytes.Buffer) error {
+	if c.maxBody <= 0 {
+		_, err := buf.ReadFrom(resp.Body)
+		return err
+	}
+	if _, err := buf.ReadFrom(io.LimitReader(resp.Body, c.maxBody+1-int64(buf.Len()))); err != nil {
+		return err
+	}
+	if int64(buf.Len()) > c.maxBody {
+		return c.tooLarge(resp)
+	}
+	return nil
+}
+
+// WithDisallowUnknownFields makes JSON fail when the response has an
+// object key that does not match a field of the destination.
+func WithDisallowUnknownFields() RequestOption {
+	return func(o *requestOptions) {
+		o.strict = true
+	}
+}
@@ -0,0 +1,142 @@
+package httpclient
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// staticTransport answers every request with body, announcing length as
+// its Content-Length, -1 for none.
+type staticTransport struct {
+	body   []byte
+	length int64
+}
+
+func (t *staticTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	header := http.Header{}
+	if t.length >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(t.length, 10))
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(t.body)),
+		ContentLength: t.length,
+		Request:       req,
+	}, nil
+}
+
+func TestBytesContentLength(t *testing.T) {
+	body := bytes.Repeat([]byte("0123456789"), 1000)
+	tests := []struct {
+		name    string
+		length  int64
+		maxBody int64
+		want    []byte
+		err     string
+	}{
+		{"exact", int64(len(body)), 0, body, ""},
+		{"unknown", -1, 0, body, ""},
+		{"longer than declared", 100, 0, body, ""},
+		{"shorter than declared", int64(len(body)) + 100, 0, nil, "unexpected EOF"},
+		{"declared over the maximum", int64(len(body)), 100, nil, "larger than 100 bytes"},
+		{"longer than declared, over the maximum", 10, 100, nil, "larger than 100 bytes"},
+	}
+	for _, tt := range tests {
+		c := New(WithTransport(&staticTransport{body: body, length: tt.length}))
+		if tt.maxBody > 0 {
+			c = c.Clone(WithMaxBodySize(tt.maxBody))
+		}
+		got, err := c.Bytes("http://example.com/")
+		switch {
+		case tt.err != "":
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got %v, want %q", tt.name, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case !bytes.Equal(got, tt.want):
+			t.Errorf("%s: got %d bytes, want %d", tt.name, len(got), len(tt.want))
+		}
+	}
+}
+
+// TestBytesShortBodyOverTCP has a server announce more than it sends.
+func TestBytesShortBodyOverTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 4096)
+		conn.Read(buf)
+		io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 1000\r\nConnection: close\r\n\r\nonly this")
+	}()
+	_, err = New().Bytes("http://" + l.Addr().String())
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("got %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func benchmarkBytes8MB(b *testing.B, length int64) {
+	body := bytes.Repeat([]byte("x"), 8<<20)
+	if length > 0 {
+		length = int64(len(body))
+	}
+	rt := &staticTransport{body: body, length: length}
+	b.Run("ReadAll", func(b *testing.B) {
+		hc := &http.Client{Transport: rt}
+		b.ReportAllocs()
+		b.SetBytes(int64(len(body)))
+		for i := 0; i < b.N; i++ {
+			resp, err := hc.Get("http://example.com/")
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := ioutil.ReadAll(resp.Body); err != nil {
+				b.Fatal(err)
+			}
+			resp.Body.Close()
+		}
+	})
+	b.Run("Bytes", func(b *testing.B) {
+		c := New(WithTransport(rt))
+		b.ReportAllocs()
+		b.SetBytes(int64(len(body)))
+		for i := 0; i < b.N; i++ {
+			if _, err := c.Bytes("http://example.com/"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkBytes8MB compares reading an 8MB body with ioutil.ReadAll,
+// which regrows its buffer, with Bytes, which sizes it from the
+// Content-Length.
+func BenchmarkBytes8MB(b *testing.B) {
+	benchmarkBytes8MB(b, 1)
+}
+
+// BenchmarkBytes8MBChunked is BenchmarkBytes8MB without a Content-Length,
+// which Bytes reads in pooled chunks.
+func BenchmarkBytes8MBChunked(b *testing.B) {
+	benchmarkBytes8MB(b, -1)
+}
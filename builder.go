@@ -0,0 +1,160 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// RequestBuilder builds a request step by step:
+//
+//	resp, err := client.NewRequest().
+//		Method("POST").
+//		Path("/v1/items").
+//		Query("dry_run", "true").
+//		Header("X-Team", "core").
+//		JSONBody(item).
+//		Do(ctx)
+//
+// Errors in any step are kept and returned by Build and Do. A builder can
+// serve as a template for several requests through Clone.
+type RequestBuilder struct {
+	c      *httpClient
+	method string
+	path   string
+	header http.Header
+	body   []byte
+	opts   []RequestOption
+	errs   []error
+}
+
+// NewRequest returns a builder for a GET request to the base URL of c.
+func (c *httpClient) NewRequest() *RequestBuilder {
+	return &RequestBuilder{c: c, method: "GET", header: make(http.Header)}
+}
+
+// NewRequest returns a builder for a request made with the default client.
+func NewRequest() *RequestBuilder {
+	return defaultClient().NewRequest()
+}
+
+// Method sets the method of the request.
+func (b *RequestBuilder) Method(method string) *RequestBuilder {
+	b.method = method
+	return b
+}
+
+// Path sets the URL of the request, resolved against the base URL of the
+// client. It may contain placeholders set with PathParam.
+func (b *RequestBuilder) Path(path string) *RequestBuilder {
+	b.path = path
+	return b
+}
+
+// PathParam sets a placeholder in the path, as WithPathParam does.
+func (b *RequestBuilder) PathParam(name string, value interface{}) *RequestBuilder {
+	return b.Option(WithPathParam(name, value))
+}
+
+// Query adds a query parameter, as WithQueryParam does.
+func (b *RequestBuilder) Query(key, value string) *RequestBuilder {
+	return b.Option(WithQueryParam(key, value))
+}
+
+// Header adds a request header.
+func (b *RequestBuilder) Header(key, value string) *RequestBuilder {
+	b.header.Add(key, value)
+	return b
+}
+
+// Body sets the request body to the contents of r.
+func (b *RequestBuilder) Body(r io.Reader) *RequestBuilder {
+	p, err := io.ReadAll(r)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("httpclient: request body: %w", err))
+		return b
+	}
+	b.body = p
+	return b
+}
+
+// JSONBody sets the request body to v encoded as JSON, along with the
+// Content-Type header.
+func (b *RequestBuilder) JSONBody(v interface{}) *RequestBuilder {
+	p, err := json.Marshal(v)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("httpclient: request body: %w", err))
+		return b
+	}
+	b.body = p
+	b.header.Set("Content-Type", "application/json")
+	return b
+}
+
+// Option adds request options, such as WithCurlCommand.
+func (b *RequestBuilder) Option(opts ...RequestOption) *RequestBuilder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Clone returns a copy of b that can be changed without affecting b.
+func (b *RequestBuilder) Clone() *RequestBuilder {
+	c := *b
+	c.header = b.header.Clone()
+	c.opts = append([]RequestOption(nil), b.opts...)
+	c.errs = append([]error(nil), b.errs...)
+	return &c
+}
+
+// Build returns the request, or the errors met while building it.
+func (b *RequestBuilder) Build() (*http.Request, error) {
+	req, _, err := b.build(context.Background())
+	return req, err
+}
+
+func (b *RequestBuilder) build(ctx context.Context) (*http.Request, *requestOptions, error) {
+	opts := make([]RequestOption, 0, len(b.opts)+1)
+	o := newRequestOptions(append(append(opts, b.opts...), WithContext(ctx)))
+	var body io.Reader
+	if b.body != nil {
+		body = bytes.NewReader(b.body)
+	}
+	req, err := b.c.newRequest(b.method, b.path, body, o)
+	if err := errors.Join(append(b.errs, err)...); err != nil {
+		return nil, nil, err
+	}
+	for k, vs := range b.header {
+		req.Header[k] = append([]string(nil), vs...)
+	}
+	return req, o, nil
+}
+
+// Do sends the request and reads the response. A response with a status
+// other than 2xx is returned along with an *Error. If its body cannot be
+// read, the *Error is returned alone, with the read error as its cause.
+func (b *RequestBuilder) Do(ctx context.Context) (*Response, error) {
+	req, o, err := b.build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.c.do(req, o)
+	if err != nil {
+		return nil, err
+	}
+	if !b.c.accepted(resp, o) {
+		err = b.c.err(resp, "")
+	}
+	r, rerr := b.c.readResponse(resp, o)
+	if rerr != nil {
+		if herr, ok := err.(*Error); ok {
+			herr.Err = rerr
+			return nil, herr
+		}
+		return nil, rerr
+	}
+	return r, err
+}
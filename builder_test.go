@@ -0,0 +1,265 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// echoServer answers every request with a JSON description of it.
+func echoServer() *httptest.Server {
+	return httptest.NewServer(echoHandler)
+}
+
+// echoHandler describes the request, with a 404 status at /missing.
+var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path == "/missing" {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"query":  r.URL.RawQuery,
+		"header": r.Header,
+		"body":   string(body),
+	})
+})
+
+type echo struct {
+	Method, Path, Query, Body string
+	Header                    http.Header
+}
+
+func TestRequestBuilder(t *testing.T) {
+	srv := echoServer()
+	defer srv.Close()
+	c := New(WithBaseURL(srv.URL))
+
+	resp, err := c.NewRequest().
+		Method("POST").
+		Path("/v1/items/{id}").
+		PathParam("id", 42).
+		Query("dry_run", "true").
+		Query("tag", "a").
+		Query("tag", "b").
+		Header("X-Team", "core").
+		Header("X-Team", "infra").
+		JSONBody(map[string]string{"name": "widget"}).
+		Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || resp.URL != srv.URL+"/v1/items/42?dry_run=true&tag=a&tag=b" {
+		t.Errorf("got %d %s", resp.StatusCode, resp.URL)
+	}
+	var got echo
+	if err := resp.JSON(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Method != "POST" || got.Path != "/v1/items/42" || got.Query != "dry_run=true&tag=a&tag=b" {
+		t.Errorf("got %s %s?%s", got.Method, got.Path, got.Query)
+	}
+	if h := got.Header["X-Team"]; len(h) != 2 || h[0] != "core" || h[1] != "infra" {
+		t.Errorf("X-Team: got %q", h)
+	}
+	if ct := got.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type: got %q", ct)
+	}
+	if got.Body != `{"name":"widget"}` {
+		t.Errorf("body: got %q", got.Body)
+	}
+
+	// A GET to the base URL by default, and Body from a reader.
+	resp, err = c.NewRequest().Do(context.Background())
+	if err != nil || resp.JSON(&got) != nil || got.Method != "GET" || got.Path != "/" {
+		t.Errorf("default: got %+v, %v", got, err)
+	}
+	resp, err = c.NewRequest().Method("PUT").Path("/raw").Body(strings.NewReader("plain")).Do(context.Background())
+	if err != nil || resp.JSON(&got) != nil || got.Method != "PUT" || got.Body != "plain" {
+		t.Errorf("Body: got %+v, %v", got, err)
+	}
+
+	// Option passes request options through.
+	var curl string
+	_, err = c.NewRequest().Path("/opt").Option(WithCurlCommand(func(s string) { curl = s })).Do(context.Background())
+	if err != nil || !strings.Contains(curl, srv.URL+"/opt") {
+		t.Errorf("Option: got %q, %v", curl, err)
+	}
+
+	// A response other than 2xx comes with an *Error.
+	resp, err = c.NewRequest().Path("/missing").Do(context.Background())
+	var herr *Error
+	if !errors.As(err, &herr) || resp == nil || resp.StatusCode != 404 || !strings.Contains(resp.String(), "/missing") {
+		t.Errorf("404: got %v, %v", resp, err)
+	}
+}
+
+func TestRequestBuilderBuild(t *testing.T) {
+	c := New(WithBaseURL("https://api.test/v1/"))
+	c.SetHeader("User-Agent", "builder")
+	req, err := c.NewRequest().
+		Method("DELETE").
+		Path("items/{id}").
+		PathParam("id", "a b").
+		Query("force", "1").
+		Header("If-Match", `"v3"`).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != "DELETE" || req.URL.String() != "https://api.test/v1/items/a%20b?force=1" {
+		t.Errorf("got %s %s", req.Method, req.URL)
+	}
+	if req.Header.Get("If-Match") != `"v3"` || req.Header.Get("User-Agent") != "builder" {
+		t.Errorf("header: got %v", req.Header)
+	}
+	if req.Body != nil {
+		t.Errorf("body: got %v, want none", req.Body)
+	}
+
+	req, err = c.NewRequest().Method("POST").JSONBody([]int{1, 2}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(req.Body)
+	if string(body) != "[1,2]" || req.ContentLength != 5 || req.GetBody == nil {
+		t.Errorf("body: got %q, %d", body, req.ContentLength)
+	}
+}
+
+func TestRequestBuilderErrors(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { requests++ }))
+	defer srv.Close()
+	c := New(WithBaseURL(srv.URL))
+
+	// An invalid URL and a body that cannot be encoded are both reported.
+	b := c.NewRequest().Path("/%zz").JSONBody(make(chan int))
+	for _, f := range []func() error{
+		func() error { _, err := b.Build(); return err },
+		func() error { _, err := b.Do(context.Background()); return err },
+	} {
+		err := f()
+		var jerr *json.UnsupportedTypeError
+		if !errors.As(err, &jerr) {
+			t.Errorf("got %v, want a JSON error", err)
+		}
+		if err == nil || !strings.Contains(err.Error(), "%zz") {
+			t.Errorf("got %v, want the URL error too", err)
+		}
+	}
+
+	// Errors pile up in order and setters go on after one.
+	readErr := errors.New("disk on fire")
+	_, err := c.NewRequest().
+		Body(iotest.ErrReader(readErr)).
+		JSONBody(func() {}).
+		Header("X-After", "1").
+		Build()
+	if !errors.Is(err, readErr) || !strings.Contains(err.Error(), "func()") {
+		t.Errorf("got %v", err)
+	}
+	if i, j := strings.Index(err.Error(), "disk on fire"), strings.Index(err.Error(), "func()"); i > j {
+		t.Errorf("got %v, want the errors in order", err)
+	}
+
+	// An invalid method, and a failed option.
+	if _, err := c.NewRequest().Method("BAD METHOD").Build(); err == nil {
+		t.Error("bad method: got no error")
+	}
+	if _, err := c.NewRequest().Option(WithQueryStruct(42)).Build(); err == nil {
+		t.Error("failed option: got no error")
+	}
+	if requests != 0 {
+		t.Errorf("got %d requests, want none", requests)
+	}
+}
+
+func TestRequestBuilderBrokenBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		w.Write([]byte("only ten b"))
+	}))
+	defer srv.Close()
+	c := New(WithBaseURL(srv.URL))
+
+	// The status error is kept, with the read error as its cause.
+	resp, err := c.NewRequest().Path("/fail").Do(context.Background())
+	var herr *Error
+	if resp != nil || !errors.As(err, &herr) || herr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("status: got %v, %v, want a 502 *Error", resp, err)
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("status: got cause %v, want the read error", herr.Err)
+	}
+
+	// Without one, the read error is returned.
+	resp, err = c.NewRequest().Path("/ok").Do(context.Background())
+	if resp != nil || !errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &herr) {
+		t.Errorf("ok: got %v, %v, want the read error", resp, err)
+	}
+}
+
+func TestRequestBuilderClone(t *testing.T) {
+	srv := echoServer()
+	defer srv.Close()
+	c := New(WithBaseURL(srv.URL))
+
+	template := c.NewRequest().Method("POST").Path("/v1/items").Header("X-Team", "core").Query("v", "1")
+	a := template.Clone().Header("X-Team", "a").Query("id", "a").JSONBody("a")
+	b := template.Clone().Method("PATCH").Query("id", "b").Body(strings.NewReader("b"))
+	bad := template.Clone().JSONBody(make(chan int))
+
+	var got echo
+	resp, err := a.Do(context.Background())
+	if err != nil || resp.JSON(&got) != nil {
+		t.Fatal(err)
+	}
+	if got.Method != "POST" || got.Query != "id=a&v=1" || got.Body != `"a"` || len(got.Header["X-Team"]) != 2 {
+		t.Errorf("a: got %+v", got)
+	}
+	resp, err = b.Do(context.Background())
+	if err != nil || resp.JSON(&got) != nil {
+		t.Fatal(err)
+	}
+	if got.Method != "PATCH" || got.Query != "id=b&v=1" || got.Body != "b" || len(got.Header["X-Team"]) != 1 {
+		t.Errorf("b: got %+v", got)
+	}
+	if _, err := bad.Build(); err == nil {
+		t.Error("bad: got no error")
+	}
+
+	// The template is untouched and can be sent again, by several
+	// goroutines at once.
+	done := make(chan error)
+	for i := 0; i < 4; i++ {
+		go func() {
+			var got echo
+			resp, err := template.Do(context.Background())
+			if err == nil {
+				err = resp.JSON(&got)
+			}
+			if err == nil && (got.Method != "POST" || got.Query != "v=1" || got.Body != "" || len(got.Header["X-Team"]) != 1) {
+				err = errors.New("template changed: " + got.Method + " " + got.Query)
+			}
+			done <- err
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}
+}
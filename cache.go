@@ -0,0 +1,289 @@
+package httpclient
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// cacheBodyLimit is the size of the largest body WithCache stores.
+const cacheBodyLimit = 8 << 20
+
+// A CachedResponse is a 200 response to a GET request kept by a Cache.
+type CachedResponse struct {
+	Header http.Header
+	Body   []byte
+}
+
+// A Cache stores responses for WithCache. They are keyed by URL, followed,
+// for a request with credentials, by a hash of them. It must be safe for
+// concurrent use. The client never changes a CachedResponse once it has
+// been set.
+type Cache interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, r *CachedResponse)
+	Delete(key string)
+}
+
+// WithCache makes GET requests conditional: the 200 responses with an ETag
+// or a Last-Modified header, and a body of up to 8 MiB, are stored in cache,
+// and later requests for the same URL send If-None-Match and
+// If-Modified-Since. When the server answers 304 Not Modified, the client
+// returns the stored response as if it had been sent again, with the
+// headers of the 304 response merged in. Any other 200 response replaces
+// the stored one; a 404 or 410 response removes it.
+//
+// Requests that set a Range or a conditional header themselves, and
+// responses with Cache-Control: no-store or a Vary header other than
+// Accept-Encoding, bypass the cache. Responses to requests with different
+// credentials, i.e. different values of the headers redacted from
+// diagnostics or of those set by SetCredentials, or a different
+// SetDigestAuth user, are stored apart. The conditional headers are added
+// before the request is signed. Stats counts the responses served from it.
+func WithCache(cache Cache) Option {
+	return func(c *httpClient) {
+		c.cache = cache
+	}
+}
+
+// cacheHitKey is the context key of the flag set when a response comes
+// from the cache.
+type cacheHitKey struct{}
+
+// cacheTransport implements WithCache.
+type cacheTransport struct {
+	next  http.RoundTripper
+	cache Cache
+
+	// identity returns the hash of the credentials of a request, or "".
+	identity func(*http.Request) string
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" || req.Header.Get("Range") != "" ||
+		req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return t.next.RoundTrip(req)
+	}
+	key := req.URL.String()
+	if id := t.identity(req); id != "" {
+		key += " " + id
+	}
+	cached, ok := t.cache.Get(key)
+	if ok {
+		etag, modified := cached.Header.Get("ETag"), cached.Header.Get("Last-Modified")
+		req = req.Clone(req.Context())
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		if !ok {
+			return resp, nil
+		}
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, drainLimit))
+		resp.Body.Close()
+		if hit, ok := req.Context().Value(cacheHitKey{}).(*bool); ok {
+			*hit = true
+		}
+		return cachedResponse(req, resp, cached), nil
+	case http.StatusOK:
+		t.cache.Delete(key)
+		if cacheable(resp) {
+			header := resp.Header.Clone()
+			resp.Body = &cacheBody{ReadCloser: resp.Body, store: func(p []byte) {
+				t.cache.Set(key, &CachedResponse{Header: header, Body: p})
+			}}
+		}
+	case http.StatusNotFound, http.StatusGone:
+		t.cache.Delete(key)
+	}
+	return resp, nil
+}
+
+// cacheIdentity returns a hash of the credentials req is sent with, see
+// WithCache, or "" if it has none.
+func (c *httpClient) cacheIdentity(req *http.Request) string {
+	names := make(map[string]bool, len(c.redact.headers))
+	for k := range c.redact.headers {
+		names[k] = true
+	}
+	for _, cred := range c.creds {
+		for k := range cred.header {
+			names[k] = true
+		}
+	}
+	var sorted []string
+	for k := range names {
+		if _, ok := req.Header[k]; ok {
+			sorted = append(sorted, k)
+		}
+	}
+	if len(sorted) == 0 && c.digest == nil {
+		return ""
+	}
+	sort.Strings(sorted)
+	h := sha256.New()
+	for _, k := range sorted {
+		for _, v := range req.Header[k] {
+			fmt.Fprintf(h, "%s: %s\n", k, v)
+		}
+	}
+	if c.digest != nil {
+		fmt.Fprintf(h, "digest: %s\n", c.digest.user)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheable reports whether the 200 response resp may be stored.
+func cacheable(resp *http.Response) bool {
+	if resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "" {
+		return false
+	}
+	if resp.ContentLength > cacheBodyLimit {
+		return false
+	}
+	for _, v := range resp.Header.Values("Cache-Control") {
+		if strings.Contains(strings.ToLower(v), "no-store") {
+			return false
+		}
+	}
+	for _, v := range resp.Header.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" && !strings.EqualFold(f, "Accept-Encoding") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// cachedResponse returns the response that cached stands for, in answer to
+// req, which got the 304 response notModified.
+func cachedResponse(req *http.Request, notModified *http.Response, cached *CachedResponse) *http.Response {
+	header := cached.Header.Clone()
+	for k, vs := range notModified.Header {
+		switch k {
+		case "Content-Length", "Content-Encoding", "Content-Type", "Transfer-Encoding":
+			continue
+		}
+		header[k] = vs
+	}
+	header.Set("Content-Length", fmt.Sprint(len(cached.Body)))
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+		Uncompressed:  notModified.Uncompressed,
+		Request:       req,
+		TLS:           notModified.TLS,
+	}
+}
+
+// cacheBody keeps what is read from a body and stores it once the body
+// has been read to the end, unless it grew larger than cacheBodyLimit.
+type cacheBody struct {
+	io.ReadCloser
+	buf   bytes.Buffer
+	store func([]byte)
+}
+
+func (b *cacheBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.store == nil {
+		return n, err
+	}
+	b.buf.Write(p[:n])
+	switch {
+	case b.buf.Len() > cacheBodyLimit:
+		b.store, b.buf = nil, bytes.Buffer{}
+	case err == io.EOF:
+		b.store(append([]byte(nil), b.buf.Bytes()...))
+		b.store = nil
+	}
+	return n, err
+}
+
+// MemoryCache is a Cache that keeps responses in memory, up to a total
+// body size, dropping the least recently used ones to make room for new
+// ones.
+type MemoryCache struct {
+	mu      sync.Mutex
+	max     int64
+	size    int64
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key string
+	r   *CachedResponse
+}
+
+// NewMemoryCache returns a MemoryCache that holds up to maxBytes of bodies.
+func NewMemoryCache(maxBytes int64) *MemoryCache {
+	return &MemoryCache{max: maxBytes, lru: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Get returns the response stored for key.
+func (m *MemoryCache) Get(key string) (*CachedResponse, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	m.lru.MoveToFront(e)
+	return e.Value.(*memoryEntry).r, true
+}
+
+// Set stores r for key, unless its body alone is larger than the cache.
+func (m *MemoryCache) Set(key string, r *CachedResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(key)
+	n := int64(len(r.Body))
+	if n > m.max {
+		return
+	}
+	for m.size+n > m.max {
+		m.remove(m.lru.Back().Value.(*memoryEntry).key)
+	}
+	m.entries[key] = m.lru.PushFront(&memoryEntry{key: key, r: r})
+	m.size += n
+}
+
+// Delete removes the response stored for key.
+func (m *MemoryCache) Delete(key string) {
+	m.mu.Lock()
+	m.remove(key)
+	m.mu.Unlock()
+}
+
+func (m *MemoryCache) remove(key string) {
+	if e, ok := m.entries[key]; ok {
+		m.lru.Remove(e)
+		delete(m.entries, key)
+		m.size -= int64(len(e.Value.(*memoryEntry).r.Body))
+	}
+}
@@ -0,0 +1,107 @@
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// etagServer serves a body per Authorization header with an ETag, and
+// answers 304 to a matching If-None-Match. It counts the full responses.
+func etagServer(full *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf("%q", "v1"+r.Header.Get("Authorization"))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(full, 1)
+		w.Header().Set("ETag", etag)
+		io.WriteString(w, "body for "+r.Header.Get("Authorization"))
+	}))
+}
+
+func TestCache(t *testing.T) {
+	var full int32
+	srv := etagServer(&full)
+	defer srv.Close()
+	c := New(WithCache(NewMemoryCache(1 << 20)))
+	for i := 0; i < 3; i++ {
+		got, err := c.String(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != "body for " {
+			t.Errorf("got %q", got)
+		}
+	}
+	if full != 1 {
+		t.Errorf("server sent %d full responses, want 1", full)
+	}
+	if hits := c.Stats().CacheHits; hits != 2 {
+		t.Errorf("got %d cache hits, want 2", hits)
+	}
+}
+
+func TestCacheKeepsCredentialsApart(t *testing.T) {
+	var full int32
+	srv := etagServer(&full)
+	defer srv.Close()
+	cache := NewMemoryCache(1 << 20)
+	for _, user := range []string{"Bearer a", "Bearer b", "Bearer a"} {
+		c := New(WithCache(cache))
+		got, err := c.String(srv.URL, WithRequestHeader("Authorization", user))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "body for " + user; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	if full != 2 {
+		t.Errorf("server sent %d full responses, want 2", full)
+	}
+	if len(cache.entries) != 2 {
+		t.Errorf("cache has %d entries, want 2", len(cache.entries))
+	}
+}
+
+func TestCacheConditionalHeadersAreSigned(t *testing.T) {
+	var full int32
+	srv := etagServer(&full)
+	defer srv.Close()
+	s := &recordingSigner{}
+	c := New(WithCache(NewMemoryCache(1<<20)), WithSigner(s))
+	for i := 0; i < 2; i++ {
+		if _, err := c.String(srv.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := s.headers[1].Get("If-None-Match"); !strings.Contains(got, "v1") {
+		t.Errorf("signer saw If-None-Match %q, want the ETag", got)
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	m := NewMemoryCache(10)
+	m.Set("a", &CachedResponse{Body: []byte("aaaa")})
+	m.Set("b", &CachedResponse{Body: []byte("bbbb")})
+	m.Get("a")
+	m.Set("c", &CachedResponse{Body: []byte("cccc")})
+	if _, ok := m.Get("b"); ok {
+		t.Error("least recently used entry b was kept")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := m.Get(k); !ok {
+			t.Errorf("entry %s was dropped", k)
+		}
+	}
+	m.Set("d", &CachedResponse{Body: make([]byte, 11)})
+	if _, ok := m.Get("d"); ok {
+		t.Error("entry larger than the cache was stored")
+	}
+}
@@ -0,0 +1,246 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"unicode/utf8"
+)
+
+// RecordMode selects what a client created with WithRecorder does.
+type RecordMode int
+
+const (
+	// ModeRecord sends requests over the network and records them, along
+	// with their responses, to the cassette.
+	ModeRecord RecordMode = iota
+
+	// ModeReplay answers requests from the cassette without going to the
+	// network. Requests without a matching interaction fail.
+	ModeReplay
+)
+
+// CassetteMatcher selects which parts of a request have to match a recorded
+// one for it to be replayed. Method and URL are compared by default.
+type CassetteMatcher struct {
+	IgnoreMethod bool
+	IgnoreQuery  bool
+
+	// Headers lists headers whose values have to match.
+	Headers []string
+
+	// Body requires request bodies to match.
+	Body bool
+}
+
+// WithRecorder records the client's requests to, or replays them from, the
+// JSON cassette at path. Recorded headers are redacted; see WithRedaction.
+//
+// A typical test records once against the real service, commits the
+// cassette and replays it from then on:
+//
+//	client := httpclient.New(httpclient.WithRecorder("testdata/users.json", httpclient.ModeReplay))
+func WithRecorder(path string, mode RecordMode) Option {
+	return func(c *httpClient) {
+		c.cassette = &cassette{path: path, mode: mode}
+	}
+}
+
+// WithRecorderMatcher sets how requests are matched against the
+// interactions of the cassette given to WithRecorder.
+func WithRecorderMatcher(m CassetteMatcher) Option {
+	return func(c *httpClient) {
+		c.cassetteMatcher = m
+	}
+}
+
+type cassette struct {
+	path    string
+	mode    RecordMode
+	matcher CassetteMatcher
+	redact  *redactor
+
+	mu           sync.Mutex
+	loadErr      error
+	interactions []*interaction
+	used         []bool
+}
+
+type interaction struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+type recordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"headers,omitempty"`
+	recordedBody
+}
+
+type recordedResponse struct {
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"headers,omitempty"`
+	recordedBody
+}
+
+// recordedBody keeps text bodies readable in the cassette and encodes
+// anything else in base64.
+type recordedBody struct {
+	Body         string `json:"body,omitempty"`
+	BodyEncoding string `json:"body_encoding,omitempty"`
+}
+
+func newRecordedBody(p []byte) recordedBody {
+	if utf8.Valid(p) {
+		return recordedBody{Body: string(p)}
+	}
+	return recordedBody{Body: base64.StdEncoding.EncodeToString(p), BodyEncoding: "base64"}
+}
+
+func (b recordedBody) bytes() []byte {
+	if b.BodyEncoding == "base64" {
+		p, _ := base64.StdEncoding.DecodeString(b.Body)
+		return p
+	}
+	return []byte(b.Body)
+}
+
+func (k *cassette) load() {
+	p, err := ioutil.ReadFile(k.path)
+	if err != nil {
+		if k.mode == ModeReplay {
+			k.loadErr = err
+		}
+		return
+	}
+	var doc struct {
+		Interactions []*interaction `json:"interactions"`
+	}
+	if err := json.Unmarshal(p, &doc); err != nil {
+		k.loadErr = fmt.Errorf("httpclient: cassette %s: %v", k.path, err)
+		return
+	}
+	if k.mode == ModeReplay {
+		k.interactions = doc.Interactions
+		k.used = make([]bool, len(doc.Interactions))
+	}
+}
+
+func (k *cassette) save() error {
+	p, err := json.MarshalIndent(map[string]interface{}{"interactions": k.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(k.path, p, 0644)
+}
+
+func (k *cassette) matches(r *recordedRequest, req *http.Request, body []byte) bool {
+	if !k.matcher.IgnoreMethod && r.Method != req.Method {
+		return false
+	}
+	u := *req.URL
+	if k.matcher.IgnoreQuery {
+		u.RawQuery = ""
+		if ru, err := url.Parse(r.URL); err == nil {
+			ru.RawQuery = ""
+			if ru.String() != u.String() {
+				return false
+			}
+		}
+	} else if r.URL != u.String() {
+		return false
+	}
+	for _, h := range k.matcher.Headers {
+		if r.Header.Get(h) != req.Header.Get(h) {
+			return false
+		}
+	}
+	return !k.matcher.Body || bytes.Equal(r.bytes(), body)
+}
+
+// cassetteTransport records round trips to, or replays them from, a
+// cassette.
+type cassetteTransport struct {
+	next     http.RoundTripper
+	cassette *cassette
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	k := t.cassette
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	if k.mode == ModeReplay {
+		return t.replay(req, body)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	p, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(p))
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.interactions = append(k.interactions, &interaction{
+		Request: recordedRequest{
+			Method:       req.Method,
+			URL:          req.URL.String(),
+			Header:       k.redact.header(req.Header),
+			recordedBody: newRecordedBody(body),
+		},
+		Response: recordedResponse{
+			StatusCode:   resp.StatusCode,
+			Header:       k.redact.header(resp.Header),
+			recordedBody: newRecordedBody(p),
+		},
+	})
+	if err := k.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// replay serves the first unused interaction matching req, or the last
+// matching one when they have all been used.
+func (t *cassetteTransport) replay(req *http.Request, body []byte) (*http.Response, error) {
+	k := t.cassette
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.loadErr != nil {
+		return nil, k.loadErr
+	}
+	var found *interaction
+	for i, in := range k.interactions {
+		if !k.matches(&in.Request, req, body) {
+			continue
+		}
+		found = in
+		if !k.used[i] {
+			k.used[i] = true
+			break
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("httpclient: no interaction recorded in %s for %s %s", k.path, req.Method, req.URL)
+	}
+	return newResponse(req, found.Response.StatusCode, found.Response.Header.Clone(), found.Response.bytes()), nil
+}
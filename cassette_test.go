@@ -0,0 +1,166 @@
+package httpclient
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func cassetteServer() *httptest.Server {
+	var n int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Set-Cookie", "session=secret-cookie")
+			w.Write([]byte(`{"id":1,"name":"Ada"}`))
+		case "/binary":
+			w.Write([]byte{0, 1, 2, 0xfe, 0xff})
+		case "/counter":
+			w.Write([]byte(strconv.Itoa(int(atomic.AddInt32(&n, 1)))))
+		case "/echo":
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Write(bytes.ToUpper(body))
+		}
+	}))
+}
+
+func TestRecorder(t *testing.T) {
+	srv := cassetteServer()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	type results struct {
+		user struct {
+			ID   int
+			Name string
+		}
+		binary   []byte
+		counters []string
+		echo     []byte
+	}
+	run := func(c *httpClient) (r results, err error) {
+		if err = c.JSON(srv.URL+"/user", &r.user, WithRequestHeader("Authorization", "Bearer secret-token")); err != nil {
+			return
+		}
+		if r.binary, err = c.Bytes(srv.URL + "/binary"); err != nil {
+			return
+		}
+		for i := 0; i < 3; i++ {
+			var s string
+			if s, err = c.String(srv.URL + "/counter"); err != nil {
+				return
+			}
+			r.counters = append(r.counters, s)
+		}
+		resp, err := c.Post(srv.URL+"/echo", "text/plain", strings.NewReader("hello"))
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		r.echo, err = ioutil.ReadAll(resp.Body)
+		return
+	}
+
+	recorded, err := run(New(WithRecorder(path, ModeRecord)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	replayed, err := run(New(WithRecorder(path, ModeReplay)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.user != recorded.user || recorded.user.Name != "Ada" {
+		t.Errorf("JSON: recorded %+v, replayed %+v", recorded.user, replayed.user)
+	}
+	if !bytes.Equal(replayed.binary, recorded.binary) || len(recorded.binary) != 5 {
+		t.Errorf("Bytes: recorded %v, replayed %v", recorded.binary, replayed.binary)
+	}
+	if strings.Join(replayed.counters, ",") != "1,2,3" || strings.Join(recorded.counters, ",") != "1,2,3" {
+		t.Errorf("repeated requests: recorded %v, replayed %v", recorded.counters, replayed.counters)
+	}
+	if string(replayed.echo) != "HELLO" {
+		t.Errorf("POST: replayed %q", replayed.echo)
+	}
+
+	// Once the recorded interactions are used up, the last one is served
+	// again.
+	if s, err := New(WithRecorder(path, ModeReplay)).String(srv.URL + "/counter"); err != nil || s != "1" {
+		t.Errorf("got %q, %v", s, err)
+	}
+
+	p, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(p, []byte("secret-")) {
+		t.Errorf("cassette leaks a secret:\n%s", p)
+	}
+}
+
+func TestRecorderUnmatched(t *testing.T) {
+	srv := cassetteServer()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if _, err := New(WithRecorder(path, ModeRecord)).Bytes(srv.URL + "/user"); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	c := New(WithRecorder(path, ModeReplay))
+	for _, url := range []string{srv.URL + "/binary", srv.URL + "/user?page=2"} {
+		if _, err := c.Bytes(url); err == nil || !strings.Contains(err.Error(), "no interaction recorded") {
+			t.Errorf("%s: got %v", url, err)
+		}
+	}
+	if _, err := c.Delete(srv.URL + "/user"); err == nil {
+		t.Error("DELETE matched a GET")
+	}
+	c = New(WithRecorder(path, ModeReplay), WithRecorderMatcher(CassetteMatcher{IgnoreQuery: true, IgnoreMethod: true}))
+	if _, err := c.Bytes(srv.URL + "/user?page=2"); err != nil {
+		t.Errorf("IgnoreQuery: %v", err)
+	}
+	if _, err := New(WithRecorder(filepath.Join(t.TempDir(), "missing.json"), ModeReplay)).Bytes(srv.URL); err == nil {
+		t.Error("replayed from a missing cassette")
+	}
+}
+
+func TestRecorderMatchBodyAndHeaders(t *testing.T) {
+	srv := cassetteServer()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	c := New(WithRecorder(path, ModeRecord))
+	for _, body := range []string{"a", "b"} {
+		for _, lang := range []string{"en", "fr"} {
+			resp, err := c.Post(srv.URL+"/echo", "text/plain", strings.NewReader(body+lang), WithRequestHeader("Accept-Language", lang))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+		}
+	}
+	srv.Close()
+
+	c = New(WithRecorder(path, ModeReplay), WithRecorderMatcher(CassetteMatcher{Headers: []string{"Accept-Language"}, Body: true}))
+	for _, body := range []string{"b", "a"} {
+		for _, lang := range []string{"fr", "en"} {
+			resp, err := c.Post(srv.URL+"/echo", "text/plain", strings.NewReader(body+lang), WithRequestHeader("Accept-Language", lang))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if want := strings.ToUpper(body + lang); string(got) != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		}
+	}
+	if _, err := c.Post(srv.URL+"/echo", "text/plain", strings.NewReader("c")); err == nil {
+		t.Error("an unrecorded body matched")
+	}
+}
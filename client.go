@@ -1,13 +1,20 @@
 package httpclient
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
+	neturl "net/url"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Error is the custom error type returns from HTTP requests.
@@ -15,6 +22,19 @@ type Error struct {
 	Message    string
 	StatusCode int
 	URL        string
+
+	// Err is the cause of an error without a response, e.g. the context
+	// error of a cancelled batch, or the error reading the body of a
+	// response.
+	Err error
+
+	// Attempts is the number of times the request was sent, more than one
+	// if it was retried.
+	Attempts int
+
+	// Body holds the start of the body of a response whose status was not
+	// accepted, e.g. the error payload of an API.
+	Body []byte
 }
 
 // Error returns the error message.
@@ -22,6 +42,68 @@ func (e *Error) Error() string {
 	return e.Message
 }
 
+// ErrNotFound matches, with errors.Is, the errors of requests answered with
+// a 404 status.
+var ErrNotFound = errors.New("httpclient: not found")
+
+// Unwrap returns the cause of the error, if any.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether e matches target, e.g. ErrNotFound.
+func (e *Error) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// FileError is the failure of a single download of a batch.
+type FileError struct {
+	// Index is the position of URL in the batch.
+	Index int
+
+	// URL is the URL of the download, redacted.
+	URL string
+
+	// StatusCode is the status of the response, zero if there was none.
+	StatusCode int
+	Err        error
+}
+
+// Error returns the error message of the download.
+func (e *FileError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the download.
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError is returned by Files and Download when some of the downloads
+// fail.
+type BatchError struct {
+	// Errors holds the failed downloads, ordered by index.
+	Errors []*FileError
+}
+
+// Error returns the first error message, and how many more there are.
+func (e *BatchError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e.Errors[0].Error(), len(e.Errors)-1)
+}
+
+// Unwrap returns the errors of the failed downloads, so that errors.Is and
+// errors.As look into them.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
 // File represents a file.
 type File struct {
 	// File name with no directory.
@@ -29,65 +111,659 @@ type File struct {
 
 	// Contents of the file.
 	Data []byte
+
+	// Timings of the download, when the client records them.
+	Timings *Timings
+
+	// ContentType and LastModified are those of the response headers;
+	// LastModified is zero without the header.
+	ContentType  string
+	LastModified time.Time
+
+	// Spill holds the contents instead of Data when they were larger than
+	// the threshold of WithSpillToDisk. It must be closed.
+	Spill io.ReadSeekCloser
 }
 
 // A Client is an HTTP client.
 // It wraps net/http's client and add some methods for making HTTP request easier.
 type httpClient struct {
 	client *http.Client
+	// rt is the base transport, which chain wraps with the transports of
+	// the options; nil means http.DefaultTransport.
+	rt http.RoundTripper
+
+	logger       Logger
+	logLevel     string
+	debug        *debugger
+	debugSecrets bool
+	curl         io.Writer
+	redact       *redactor
+	maxBody      int64
+	timings      bool
+	metrics      Metrics
+	inFlight     InFlightMetrics
+	tracer       Tracer
+	stats        *stats
+	hosts        *hostStats
+	base         *neturl.URL
+	baseErr      error
+	restrict     bool
+
+	slowThreshold time.Duration
+	slow          func(SlowRequestInfo)
+
+	har             *HARRecorder
+	cassette        *cassette
+	cassetteMatcher CassetteMatcher
+	signer          Signer
+	apiKey          *apiKey
+	auth            *bearer
+	local           bool
+	robots          *robotsPolicy
+	sem             *semaphore
+	encodings       []string
+	concurrency     int
+	retry           RetryPolicy
+	accept          func(code int) bool
+	configErr       error
+	cache           Cache
+	creds           map[string]Credential
+	digest          *digestAuth
+	hooks           *hooks
+	headers         *defaultHeaders
+}
+
+// An Option configures a client created by New.
+type Option func(*httpClient)
+
+// A RequestOption configures a single call to one of the client's methods.
+type RequestOption func(*requestOptions)
+
+type requestOptions struct {
+	ctx         context.Context
+	curl        func(cmd string)
+	curlSecrets bool
+	report      *BatchReport
+	maxPolls    int
+	pollHook    func(PollAttempt)
+	maxPages    int
+	query       neturl.Values
+	pathParams  map[string]string
+	header      http.Header
+	strict      bool
+	err         error
+
+	longPollTimeout  time.Duration
+	longPollCursor   string
+	fullBodyFallback bool
+	tee              io.Writer
+	teeFactory       func(index int, url string) io.Writer
+	teeBestEffort    bool
+	spillThreshold   int64
+	spillDir         string
+	integrity        *integrity
+	priority         int
+	timeout          time.Duration
+	progress         ProgressFunc
+	concurrency      int
+	partial          bool
+	accept           []int
+	uploadField      string
+}
+
+// fail records the first error of an option, which makes the request fail.
+func (o *requestOptions) fail(err error) {
+	if o.err == nil {
+		o.err = err
+	}
+}
+
+func newRequestOptions(opts []RequestOption) *requestOptions {
+	o := &requestOptions{ctx: context.Background()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
 }
 
 // New returns new client.
-func New() *httpClient {
-	return &httpClient{client: &http.Client{}}
+func New(opts ...Option) *httpClient {
+	c := &httpClient{
+		client:   &http.Client{},
+		logLevel: LevelInfo,
+		metrics:  noMetrics{},
+		redact:   newRedactor(),
+		stats:    &stats{},
+		hosts:    &hostStats{},
+		headers:  &defaultHeaders{},
+		hooks:    &hooks{},
+	}
+	c.configure(opts)
+	return c
+}
+
+// Clone returns a copy of the client with opts applied on top of its
+// configuration, e.g. to derive a client for another base URL. The copy
+// shares the connections of the original but has its own statistics.
+func (c *httpClient) Clone(opts ...Option) *httpClient {
+	n := *c
+	hc := *c.client
+	n.client = &hc
+	n.redact = c.redact.clone()
+	n.stats = &stats{}
+	n.hosts = &hostStats{limit: c.hosts.limit}
+	n.headers = c.headers.clone()
+	n.hooks = c.hooks.clone()
+	if c.auth != nil {
+		n.auth = c.auth.clone()
+	}
+	n.configure(opts)
+	return &n
+}
+
+// configure applies opts to c and installs the transports they call for.
+func (c *httpClient) configure(opts []Option) {
+	cassette := c.cassette
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.debug != nil {
+		c.debug.redact = c.redact
+		if c.debugSecrets {
+			c.debug.redact = noRedaction
+		}
+	}
+	if c.cassette != nil && c.cassette != cassette {
+		c.cassette.matcher = c.cassetteMatcher
+		c.cassette.redact = c.redact
+		c.cassette.load()
+	}
+	c.chain()
+}
+
+// chain builds the transport of the underlying http.Client from the base
+// transport and the settings of c, so that replacing the base transport,
+// e.g. in a Clone, keeps them. From the innermost out, it renders curl
+// commands and debug dumps, serves local schemes, enforces robots.txt,
+// replays cassettes, records HAR entries, signs requests, answers Digest
+// challenges, makes requests conditional, adds credentials and drops the
+// sensitive headers of redirects to other hosts: a request goes through
+// the same steps in reverse, so it is signed with its credentials and
+// conditional headers, and rendered as it is sent.
+func (c *httpClient) chain() {
+	rt := http.RoundTripper(&wireTransport{next: c.transport(), curl: c.curl, debug: c.debug, redact: c.redact})
+	if c.local {
+		rt = &localTransport{next: rt}
+	}
+	if c.robots != nil {
+		rt = &robotsTransport{next: rt, robotsPolicy: c.robots}
+	}
+	if c.cassette != nil {
+		rt = &cassetteTransport{next: rt, cassette: c.cassette}
+	}
+	if c.har != nil {
+		rt = &harTransport{next: rt, recorder: c.har, redact: c.redact}
+	}
+	if c.signer != nil {
+		rt = &signTransport{next: rt, signer: c.signer}
+	}
+	if c.digest != nil {
+		rt = &digestTransport{next: rt, digestAuth: c.digest}
+	}
+	if c.cache != nil {
+		rt = &cacheTransport{next: rt, cache: c.cache, identity: c.cacheIdentity}
+	}
+	if c.creds != nil {
+		rt = &credTransport{next: rt, hosts: c.creds}
+	}
+	c.client.Transport = &hopTransport{next: rt, redact: c.redact}
+}
+
+// transport returns the base transport of c, the one the transports of
+// the options wrap.
+func (c *httpClient) transport() http.RoundTripper {
+	if c.rt != nil {
+		return c.rt
+	}
+	return http.DefaultTransport
 }
 
 func (c *httpClient) err(resp *http.Response, message string) error {
+	return c.fail(resp, message, nil)
+}
+
+// bodyErr returns the *Error of a failure reading the body of resp, with
+// err as its cause, so that errors.Is finds e.g. a context error.
+func (c *httpClient) bodyErr(resp *http.Response, err error) error {
+	return c.fail(resp, err.Error(), err)
+}
+
+func (c *httpClient) fail(resp *http.Response, message string, cause error) error {
+	u := c.redact.url(resp.Request.URL).String()
+	kind := ErrorKindBody
+	var body []byte
 	if message == "" {
-		message = fmt.Sprintf("Get %s -> %d", resp.Request.URL.String(), resp.StatusCode)
+		m := resp.Request.Method
+		message = fmt.Sprintf("%s %s -> %d", m[:1]+strings.ToLower(m[1:]), u, resp.StatusCode)
+		if loc := resp.Header.Get("Location"); loc != "" && resp.StatusCode/100 == 3 {
+			message += " (redirect to " + c.redact.urlString(loc) + " not followed)"
+		}
+		kind = ErrorKindStatus
+		body = c.errorBody(resp)
 	}
-	return &Error{
+	c.metrics.IncError(resp.Request.URL.Host, kind)
+	if c.logs(LevelError) {
+		c.log(LevelError, "request error", map[string]interface{}{
+			"method": resp.Request.Method,
+			"url":    u,
+			"status": resp.StatusCode,
+			"error":  message,
+		})
+	}
+	n := attempts(resp.Request)
+	if n > 1 {
+		message += fmt.Sprintf(" (%d attempts)", n)
+	}
+	err := &Error{
 		Message:    message,
 		StatusCode: resp.StatusCode,
-		URL:        resp.Request.URL.String(),
+		URL:        u,
+		Err:        cause,
+		Attempts:   n,
+		Body:       body,
+	}
+	c.hooks.failed(u, err)
+	return err
+}
+
+// An exchange is a single request made by the client, from the moment it is
+// sent until the body of its response is closed.
+type exchange struct {
+	req     *http.Request
+	resp    *http.Response
+	start   time.Time
+	id      int64
+	timings *timingsRecorder
+	span    Span
+	attempt int
+	body    *sentBody
+
+	slowTimer *time.Timer
+}
+
+func (x *exchange) host() string {
+	return x.req.URL.Host
+}
+
+func (x *exchange) snapshotTimings() *Timings {
+	if x.timings == nil {
+		return nil
+	}
+	return x.timings.snapshot()
+}
+
+// do sends req through the underlying http.Client. The body of the returned
+// response reports the completed exchange when it is closed.
+func (c *httpClient) do(req *http.Request, o *requestOptions) (*http.Response, error) {
+	release := func() {}
+	if o.timeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), o.timeout)
+		req, release = req.WithContext(ctx), cancel
+	}
+	if sem := c.sem; sem != nil {
+		if err := sem.acquire(req.Context(), o.priority); err != nil {
+			release()
+			return nil, err
+		}
+		cancel := release
+		release = func() {
+			sem.release()
+			cancel()
+		}
+	}
+	var attempt int
+	if c.retry.MaxAttempts > 1 {
+		req = req.WithContext(context.WithValue(req.Context(), attemptsKey{}, &attempt))
+	}
+	var hit bool
+	if c.cache != nil {
+		req = req.WithContext(context.WithValue(req.Context(), cacheHitKey{}, &hit))
+	}
+	if c.signer != nil {
+		req = withBodyHash(req)
+	}
+	var x *exchange
+	var resp *http.Response
+	var err error
+	for {
+		attempt++
+		x = c.begin(req, o)
+		x.attempt = attempt
+		resp, err = c.client.Do(x.req)
+		if ue, ok := err.(*neturl.Error); ok {
+			ue.URL = c.redact.urlString(ue.URL)
+		}
+		wait, retry := c.retry.wait(req, attempt, resp, err)
+		if !retry {
+			break
+		}
+		c.retried(x, resp, err, wait)
+		if err = sleep(req.Context(), wait); err == nil && req.GetBody != nil {
+			r := *req
+			r.Body, err = req.GetBody()
+			req = &r
+		}
+		if err != nil {
+			release()
+			return nil, err
+		}
+	}
+	if err != nil {
+		c.failed(x, err)
+		release()
+		u := c.redact.url(req.URL).String()
+		if attempt > 1 {
+			err = &Error{Message: fmt.Sprintf("%v (%d attempts)", err, attempt), URL: u, Err: err, Attempts: attempt}
+		}
+		c.hooks.failed(u, err)
+		return nil, err
+	}
+	x.resp = resp
+	c.hooks.received(resp, time.Since(x.start))
+	if hit {
+		c.stats.cacheHit()
+	}
+	body := &trackedBody{ReadCloser: resp.Body, done: func(n int64) {
+		c.completed(x, n)
+		release()
+	}}
+	if x.timings != nil {
+		body.eof = func() {
+			x.timings.set(func(t *Timings) { t.BodyReadComplete = time.Now() })
+		}
+	}
+	resp.Body = body
+	if err := c.decompress(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if c.debug != nil {
+		c.debug.response(x.id, resp, time.Since(x.start))
+	}
+	if o.integrity != nil {
+		resp.Body = &integrityBody{ReadCloser: resp.Body, in: o.integrity, h: o.integrity.newHash(), url: c.redact.urlString(req.URL.String())}
+	}
+	if o.tee != nil {
+		resp.Body = &teeBody{ReadCloser: resp.Body, c: c, w: o.tee, url: c.redact.urlString(req.URL.String()), bestEffort: o.teeBestEffort}
+	}
+	return resp, nil
+}
+
+func (c *httpClient) begin(req *http.Request, o *requestOptions) *exchange {
+	x := &exchange{req: req}
+	ctx := req.Context()
+	if c.curl != nil || c.debug != nil || o.curl != nil {
+		w := &wire{curl: o.curl, curlSecrets: o.curlSecrets}
+		if c.debug != nil {
+			x.id = c.debug.next()
+			w.id = x.id
+		}
+		ctx = context.WithValue(ctx, wireKey{}, w)
+	}
+	x.req = req.WithContext(httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.stats.gotConn(info.Reused)
+			c.hosts.gotConn(x.host(), info.Reused)
+		},
+	}))
+	if c.tracer != nil {
+		x.req, x.span = c.tracer.Start(x.req)
+	}
+	if c.timings {
+		x.req, x.timings = withTimings(x.req)
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		x.body = &sentBody{ReadCloser: req.Body}
+		x.req.Body = x.body
+	}
+	c.hooks.sending(x.req)
+	x.start = time.Now()
+	c.watchSlow(x)
+	c.stats.started(req.Method)
+	if c.inFlight != nil {
+		c.inFlight.IncInFlight(x.host())
+	}
+	if c.logs(LevelDebug) {
+		c.log(LevelDebug, "request start", map[string]interface{}{
+			"method": req.Method,
+			"url":    c.redact.url(req.URL).String(),
+		})
+	}
+	return x
+}
+
+// failed reports an exchange that did not get a response.
+func (c *httpClient) failed(x *exchange, err error) {
+	elapsed := time.Since(x.start)
+	c.reportSlow(x, elapsed, 0, err)
+	c.stats.failed()
+	c.hosts.failed(x.host(), elapsed)
+	if c.inFlight != nil {
+		c.inFlight.DecInFlight(x.host())
+	}
+	if x.span != nil {
+		x.span.End(SpanResult{Err: err, Attempt: x.attempt})
+	}
+	c.metrics.IncError(x.host(), ErrorKindNetwork)
+	if c.debug != nil {
+		c.debug.failure(x.id, err, elapsed)
+	}
+	if c.logs(LevelError) {
+		c.log(LevelError, "request failed", map[string]interface{}{
+			"method":   x.req.Method,
+			"url":      c.redact.url(x.req.URL).String(),
+			"duration": elapsed,
+			"error":    err.Error(),
+		})
+	}
+}
+
+// completed reports an exchange whose response body has been closed after n
+// bytes were read from it.
+func (c *httpClient) completed(x *exchange, n int64) {
+	elapsed := time.Since(x.start)
+	if x.timings != nil {
+		x.timings.set(func(t *Timings) {
+			if t.BodyReadComplete.IsZero() {
+				t.BodyReadComplete = time.Now()
+			}
+		})
+	}
+	var out int64
+	if x.body != nil {
+		out = atomic.LoadInt64(&x.body.n)
+	}
+	c.reportSlow(x, elapsed, x.resp.StatusCode, nil)
+	c.stats.completed(x.resp.StatusCode, n, out)
+	c.hosts.completed(x.host(), elapsed, x.resp.StatusCode, n, out)
+	if c.inFlight != nil {
+		c.inFlight.DecInFlight(x.host())
+	}
+	if x.span != nil {
+		x.span.End(SpanResult{StatusCode: x.resp.StatusCode, Attempt: x.attempt})
+	}
+	c.metrics.ObserveRequest(x.req.Method, x.host(), x.resp.StatusCode, elapsed, n, out)
+	if c.logs(LevelInfo) {
+		c.log(LevelInfo, "request complete", map[string]interface{}{
+			"method":   x.req.Method,
+			"url":      c.redact.url(x.req.URL).String(),
+			"status":   x.resp.StatusCode,
+			"duration": elapsed,
+			"bytes":    n,
+		})
 	}
 }
 
+// sentBody counts the bytes the transport reads from a request body, which
+// it may do while the response is being read.
+type sentBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *sentBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(&b.n, int64(n))
+	return n, err
+}
+
+// trackedBody counts the bytes read from a response body. It calls eof, if
+// set, once the body has been read to the end and done exactly once, when
+// the body is closed.
+type trackedBody struct {
+	io.ReadCloser
+	n        int64
+	eofOnce  sync.Once
+	doneOnce sync.Once
+	eof      func()
+	done     func(n int64)
+}
+
+func (b *trackedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if err == io.EOF && b.eof != nil {
+		b.eofOnce.Do(b.eof)
+	}
+	return n, err
+}
+
+func (b *trackedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.doneOnce.Do(func() { b.done(b.n) })
+	return err
+}
+
 // Get issues a GET to the specified URL. It returns an http.Response for further processing.
-func (c *httpClient) Get(url string) (*http.Response, error) {
-	return c.client.Get(url)
+func (c *httpClient) Get(url string, opts ...RequestOption) (*http.Response, error) {
+	return c.send("GET", url, nil, newRequestOptions(opts))
 }
 
-// Bytes fetches the specified url and returns the response body as bytes.
-func (c *httpClient) Bytes(url string) ([]byte, error) {
-	resp, err := c.Get(url)
+// send makes a request with the method, URL and body.
+func (c *httpClient) send(method, url string, body io.Reader, o *requestOptions) (*http.Response, error) {
+	req, err := c.newRequest(method, url, body, o)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return nil, c.err(resp, "")
+	return c.do(req, o)
+}
+
+// newRequest builds a request with the method, URL and body, applying the
+// base URL and the path and query parameters of o.
+func (c *httpClient) newRequest(method, url string, body io.Reader, o *requestOptions) (*http.Request, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	if c.configErr != nil {
+		return nil, c.configErr
+	}
+	url, err := o.expandPath(url)
+	if err != nil {
+		return nil, err
+	}
+	u, err := c.resolve(url)
+	if err != nil {
+		return nil, err
+	}
+	if u, err = o.withQuery(u); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(o.ctx, method, u, body)
+	if err != nil {
+		return nil, err
 	}
-	p, err := ioutil.ReadAll(resp.Body)
+	c.headers.apply(req.Header, o)
+	if c.encodings != nil && req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", c.acceptEncoding())
+	}
+	if c.apiKey != nil {
+		c.apiKey.apply(req)
+	}
+	if c.auth != nil {
+		if err := c.auth.apply(req); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// Bytes fetches the specified url and returns the response body as bytes.
+func (c *httpClient) Bytes(url string, opts ...RequestOption) ([]byte, error) {
+	p, _, err := c.BytesResponse(url, opts...)
 	return p, err
 }
 
+// BytesResponse is Bytes that also returns the Response, whose Body is the
+// result. The Response is returned with the *Error of a status that is not
+// accepted, too.
+func (c *httpClient) BytesResponse(url string, opts ...RequestOption) ([]byte, *Response, error) {
+	o := newRequestOptions(opts)
+	resp, err := c.send("GET", url, nil, o)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	r := responseOf(resp)
+	if !c.accepted(resp, o) {
+		return nil, r, c.err(resp, "")
+	}
+	p, err := c.readAll(resp)
+	if err != nil {
+		return nil, r, err
+	}
+	r.Body, r.Timings = p, TimingsOf(resp)
+	return p, r, nil
+}
+
 // String fetches the specified URL and returns the response body as a string.
-func (c *httpClient) String(url string) (string, error) {
-	bytes, err := c.Bytes(url)
+func (c *httpClient) String(url string, opts ...RequestOption) (string, error) {
+	buf, err := c.buffer(url, opts)
 	if err != nil {
 		return "", err
 	}
-	return string(bytes), nil
+	s := buf.String()
+	putBuffer(buf)
+	return s, nil
+}
+
+// buffer fetches url into a pooled buffer, which the caller must put back.
+func (c *httpClient) buffer(url string, opts []RequestOption) (*bytes.Buffer, error) {
+	o := newRequestOptions(opts)
+	resp, err := c.send("GET", url, nil, o)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if !c.accepted(resp, o) {
+		return nil, c.err(resp, "")
+	}
+	buf := getBuffer()
+	if err := c.readBody(resp, buf); err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+	return buf, nil
 }
 
 // Reader issues a GET request to a specified URL and returns an reader from the response body.
-func (c *httpClient) Reader(url string) (io.ReadCloser, error) {
-	resp, err := c.Get(url)
+func (c *httpClient) Reader(url string, opts ...RequestOption) (io.ReadCloser, error) {
+	o := newRequestOptions(opts)
+	resp, err := c.send("GET", url, nil, o)
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != 200 {
+	if !c.accepted(resp, o) {
 		err = c.err(resp, "")
 		resp.Body.Close()
 		return nil, err
@@ -96,119 +772,225 @@ func (c *httpClient) Reader(url string) (io.ReadCloser, error) {
 }
 
 // JSON issues a GET request to a specified URL and unmarshal json data from the response body.
-func (c *httpClient) JSON(url string, v interface{}) error {
-	resp, err := c.Get(url)
+func (c *httpClient) JSON(url string, v interface{}, opts ...RequestOption) error {
+	_, err := c.JSONResponse(url, v, opts...)
+	return err
+}
+
+// JSONResponse is JSON that also returns the Response, with no Body. The
+// Response is returned with the *Error of a status that is not accepted,
+// too.
+func (c *httpClient) JSONResponse(url string, v interface{}, opts ...RequestOption) (*Response, error) {
+	o := newRequestOptions(opts)
+	resp, err := c.send("GET", url, nil, o)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return c.err(resp, "")
+	r := responseOf(resp)
+	if !c.accepted(resp, o) {
+		return r, c.err(resp, "")
 	}
-	err = json.NewDecoder(resp.Body).Decode(v)
+	dec := json.NewDecoder(resp.Body)
+	if o.strict {
+		dec.DisallowUnknownFields()
+	}
+	err = dec.Decode(v)
 	if _, ok := err.(*json.SyntaxError); ok {
-		err = c.err(resp, "JSON syntax error at "+url)
+		err = c.err(resp, "JSON syntax error at "+c.redact.urlString(url))
 	}
-	return err
+	if err == nil {
+		err = o.verifyRest(resp.Body)
+	}
+	r.Timings = TimingsOf(resp)
+	return r, c.bodyFailed(resp, err)
 }
 
 // XML issues a GET request to a specified URL and unmarshal XML data from the response body.
-func (c *httpClient) XML(url string, v interface{}) error {
-	resp, err := c.Get(url)
+// A body that is not well-formed XML fails with an *Error.
+func (c *httpClient) XML(url string, v interface{}, opts ...RequestOption) error {
+	_, err := c.XMLResponse(url, v, opts...)
+	return err
+}
+
+// XMLResponse is XML that also returns the Response, with no Body. The
+// Response is returned with the *Error of a status that is not accepted,
+// too.
+func (c *httpClient) XMLResponse(url string, v interface{}, opts ...RequestOption) (*Response, error) {
+	o := newRequestOptions(opts)
+	resp, err := c.send("GET", url, nil, o)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return c.err(resp, "")
+	r := responseOf(resp)
+	if !c.accepted(resp, o) {
+		return r, c.err(resp, "")
 	}
 	err = xml.NewDecoder(resp.Body).Decode(v)
-	return err
+	if _, ok := err.(*xml.SyntaxError); ok || err == io.EOF {
+		msg := "XML syntax error at " + c.redact.urlString(url)
+		if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.Contains(ct, "xml") {
+			msg += ": response is " + ct + ", not XML"
+		}
+		err = c.err(resp, msg)
+	}
+	if err == nil {
+		err = o.verifyRest(resp.Body)
+	}
+	r.Timings = TimingsOf(resp)
+	return r, c.bodyFailed(resp, err)
 }
 
 // Files downloads multiple files concurrency.
-func (c *httpClient) Files(urls []string, files *[]File) error {
-	l := len(urls)
-	fs := make([]File, l)
-	ch := make(chan error, l)
-	var wg sync.WaitGroup
-	wg.Add(l)
-	for i, url := range urls {
-		go func(i int) {
-			defer wg.Done()
-			resp, err := c.Get(url)
-			if err != nil {
-				ch <- err
-				return
-			}
-			defer resp.Body.Close()
-			if resp.StatusCode != 200 {
-				var err error
-				err = c.err(resp, "")
-				ch <- err
-				return
-			}
-			fs[i].Data, err = ioutil.ReadAll(resp.Body)
-			if err != nil {
-				ch <- c.err(resp, err.Error())
-				return
+//
+// All the downloads run at once, unless SetConcurrency or WithConcurrency
+// limit how many run at a time. Connections beyond the transport's
+// MaxIdleConnsPerHost (2 for http.DefaultTransport) are closed rather than
+// kept for reuse once their download is over, so repeated batches against
+// the same host keep dialing new connections; Stats and HostStats report
+// how many connections were new or reused.
+//
+// Each download writes only to its own slot of the result, so files are in
+// the order of urls. If any download fails, Files returns a *BatchError
+// whose errors are ordered by the index of their URL, whatever order the
+// downloads finished in, or, if the context given WithContext is done, an
+// *Error wrapping the context error. files is then left alone, unless
+// WithPartialResults is given.
+func (c *httpClient) Files(urls []string, files *[]File, opts ...RequestOption) error {
+	o := newRequestOptions(opts)
+	started := time.Now()
+	fs := make([]File, len(urls))
+	b := c.batch(urls, o, func(i int, url string) error {
+		opts := opts
+		if o.teeFactory != nil {
+			opts = append(opts[:len(opts):len(opts)], WithTee(o.teeFactory(i, url)))
+		}
+		return c.file(url, &fs[i], opts)
+	})
+	if o.report != nil {
+		o.report.fill(urls, fs, b.errs, b.durations, b.skipped, time.Since(started))
+	}
+	err := b.err(urls, o)
+	if err != nil && !o.partial {
+		for _, f := range fs {
+			if f.Spill != nil {
+				f.Spill.Close()
 			}
-			ch <- nil
-		}(i)
+		}
+		return err
 	}
-	wg.Wait()
-	for _ = range fs {
-		if err := <-ch; err != nil {
-			return err
+	for i, e := range b.errs {
+		if e != nil {
+			fs[i] = File{}
 		}
 	}
 	*files = fs
+	return err
+}
+
+// file downloads url into f.
+func (c *httpClient) file(url string, f *File, opts []RequestOption) error {
+	o := newRequestOptions(opts)
+	resp, err := c.send("GET", url, nil, o)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if !c.accepted(resp, o) {
+		return c.err(resp, "")
+	}
+	if o.spillThreshold > 0 {
+		f.Data, f.Spill, err = c.readSpill(resp, o)
+	} else {
+		f.Data, err = c.readAll(resp)
+	}
+	switch err.(type) {
+	case nil:
+	case *Error, *IntegrityError:
+		return err
+	default:
+		return c.bodyErr(resp, err)
+	}
+	f.Timings = TimingsOf(resp)
+	f.ContentType = resp.Header.Get("Content-Type")
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		f.LastModified = t
+	}
 	return nil
 }
 
 // Download downloads multiple files concurrency.
-func (c *httpClient) Download(urls []string, files *[]File) error {
-	return c.Files(urls, files)
+func (c *httpClient) Download(urls []string, files *[]File, opts ...RequestOption) error {
+	return c.Files(urls, files, opts...)
 }
 
-var client = New()
+var (
+	clientMu sync.RWMutex
+	client   = New()
+)
+
+// defaultClient returns the client of the package-level functions.
+func defaultClient() *httpClient {
+	clientMu.RLock()
+	defer clientMu.RUnlock()
+	return client
+}
 
 // Get issues a GET to the specified URL. It returns an http.Response for further processing.
-func Get(url string) (*http.Response, error) {
-	return client.Get(url)
+func Get(url string, opts ...RequestOption) (*http.Response, error) {
+	return defaultClient().Get(url, opts...)
 }
 
 // Bytes fetches the specified url and returns the response body as bytes.
-func Bytes(url string) ([]byte, error) {
-	return client.Bytes(url)
+func Bytes(url string, opts ...RequestOption) ([]byte, error) {
+	return defaultClient().Bytes(url, opts...)
 }
 
 // String fetches the specified URL and returns the response body as a string.
-func String(url string) (string, error) {
-	return client.String(url)
+func String(url string, opts ...RequestOption) (string, error) {
+	return defaultClient().String(url, opts...)
 }
 
 // Reader issues a GET request to a specified URL and returns an reader from the response body.
-func Reader(url string) (io.ReadCloser, error) {
-	return client.Reader(url)
+func Reader(url string, opts ...RequestOption) (io.ReadCloser, error) {
+	return defaultClient().Reader(url, opts...)
 }
 
 // JSON issues a GET request to a specified URL and unmarshal json data from the response body.
-func JSON(url string, v interface{}) error {
-	return client.JSON(url, v)
+func JSON(url string, v interface{}, opts ...RequestOption) error {
+	return defaultClient().JSON(url, v, opts...)
+}
+
+// BytesResponse fetches url with the default client and returns the body
+// and the Response.
+func BytesResponse(url string, opts ...RequestOption) ([]byte, *Response, error) {
+	return defaultClient().BytesResponse(url, opts...)
+}
+
+// JSONResponse fetches url with the default client, unmarshals its JSON
+// body into v and returns the Response.
+func JSONResponse(url string, v interface{}, opts ...RequestOption) (*Response, error) {
+	return defaultClient().JSONResponse(url, v, opts...)
+}
+
+// XMLResponse fetches url with the default client, unmarshals its XML body
+// into v and returns the Response.
+func XMLResponse(url string, v interface{}, opts ...RequestOption) (*Response, error) {
+	return defaultClient().XMLResponse(url, v, opts...)
 }
 
 // XML issues a GET request to a specified URL and unmarshal xml data from the response body.
-func XML(url string, v interface{}) error {
-	return client.JSON(url, v)
+func XML(url string, v interface{}, opts ...RequestOption) error {
+	return defaultClient().XML(url, v, opts...)
 }
 
 // Files downloads multiple files concurrency.
-func Files(urls []string, files *[]File) error {
-	return client.Files(urls, files)
+func Files(urls []string, files *[]File, opts ...RequestOption) error {
+	return defaultClient().Files(urls, files, opts...)
 }
 
 // Download downloads multiple files concurrency.
-func Download(urls []string, files *[]File) error {
-	return client.Files(urls, files)
+func Download(urls []string, files *[]File, opts ...RequestOption) error {
+	return defaultClient().Files(urls, files, opts...)
 }
@@ -0,0 +1,202 @@
+package httpclient
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedEncoding is the Err of the *Error returned for a response
+// with a Content-Encoding the client cannot decode, see WithCompression.
+var ErrUnsupportedEncoding = errors.New("httpclient: unsupported Content-Encoding")
+
+// A Decoder returns a reader of the content encoded in r, for a
+// Content-Encoding registered with RegisterDecoder.
+type Decoder func(r io.Reader) (io.ReadCloser, error)
+
+var decoders = struct {
+	sync.RWMutex
+	m map[string]Decoder
+}{m: map[string]Decoder{
+	"gzip":    func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	"deflate": inflate,
+}}
+
+// inflate decodes a deflate body. RFC 9110 has it in the zlib format, but
+// some servers send raw deflate data, which is accepted too.
+func inflate(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	p, err := br.Peek(2)
+	if err == io.EOF {
+		return ioutil.NopCloser(br), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p[0]&0x0f == 8 && (uint16(p[0])<<8|uint16(p[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// RegisterDecoder makes d decode the responses with the Content-Encoding
+// name. gzip and deflate are built in; other
+// encodings can be added without this package depending on them, e.g.
+// with github.com/andybalholm/brotli and github.com/klauspost/compress:
+//
+//	httpclient.RegisterDecoder("br", func(r io.Reader) (io.ReadCloser, error) {
+//		return io.NopCloser(brotli.NewReader(r)), nil
+//	})
+//	httpclient.RegisterDecoder("zstd", func(r io.Reader) (io.ReadCloser, error) {
+//		d, err := zstd.NewReader(r)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return d.IOReadCloser(), nil
+//	})
+func RegisterDecoder(name string, d Decoder) {
+	decoders.Lock()
+	decoders.m[strings.ToLower(name)] = d
+	decoders.Unlock()
+}
+
+func decoder(name string) Decoder {
+	decoders.RLock()
+	defer decoders.RUnlock()
+	return decoders.m[name]
+}
+
+// WithCompression makes the client ask for responses compressed with the
+// given encodings, e.g. "gzip" and "deflate", in order of preference, and
+// decompress them itself rather than leave it to the transport. Only the
+// encodings registered with RegisterDecoder are asked for. The maximum body
+// size applies to the decompressed body. A response with an encoding the
+// client cannot decode fails with an *Error naming it, whose Err is
+// ErrUnsupportedEncoding.
+//
+// Without it, the responses to requests that set Accept-Encoding
+// themselves are still decompressed when their encoding is registered, and
+// passed through untouched otherwise.
+func WithCompression(encodings ...string) Option {
+	return func(c *httpClient) {
+		c.encodings = nil
+		for _, e := range encodings {
+			c.encodings = append(c.encodings, strings.ToLower(e))
+		}
+	}
+}
+
+// acceptEncoding returns the Accept-Encoding header for the encodings of c
+// that can be decoded.
+func (c *httpClient) acceptEncoding() string {
+	var names []string
+	for _, e := range c.encodings {
+		if decoder(e) != nil {
+			names = append(names, e)
+		}
+	}
+	if names == nil {
+		return "identity"
+	}
+	return strings.Join(names, ", ")
+}
+
+// decompress replaces the body of resp with its decoded content. A body
+// that fails to decode fails with an *Error naming the URL.
+func (c *httpClient) decompress(resp *http.Response) error {
+	var encodings []string
+	for _, v := range resp.Header.Values("Content-Encoding") {
+		for _, e := range strings.Split(v, ",") {
+			if e = strings.ToLower(strings.TrimSpace(e)); e != "" && e != "identity" {
+				encodings = append(encodings, e)
+			}
+		}
+	}
+	if len(encodings) == 0 {
+		return nil
+	}
+	if resp.Request != nil && resp.Request.Method == "HEAD" || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	ds := make([]Decoder, len(encodings))
+	for i, e := range encodings {
+		if ds[i] = decoder(e); ds[i] == nil {
+			if c.encodings == nil {
+				return nil
+			}
+			msg := fmt.Sprintf("%s: unsupported Content-Encoding %q", c.redact.url(resp.Request.URL), e)
+			err := c.err(resp, msg).(*Error)
+			err.Err = ErrUnsupportedEncoding
+			return err
+		}
+	}
+
+	body := resp.Body
+	for i := len(encodings) - 1; i >= 0; i-- {
+		e := encodings[i]
+		r, err := ds[i](body)
+		if err != nil {
+			return c.decodeErr(resp, e, err)
+		}
+		body = &decodedBody{ReadCloser: r, next: body, fail: func(err error) error {
+			return c.decodeErr(resp, e, err)
+		}}
+	}
+	resp.Body = body
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	return nil
+}
+
+// decodeErr returns the *Error of a body of resp that failed to decode.
+func (c *httpClient) decodeErr(resp *http.Response, encoding string, err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	msg := fmt.Sprintf("%s: corrupt %s body: %v", c.redact.url(resp.Request.URL), encoding, err)
+	e := c.err(resp, msg).(*Error)
+	e.Err = err
+	return e
+}
+
+// decodedBody closes the decoder and the body it reads. fail turns the
+// first error of the decoder into the error Read returns from then on, so
+// that it is reported once.
+type decodedBody struct {
+	io.ReadCloser
+	next io.ReadCloser
+	fail func(error) error
+	err  error
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		if _, ok := err.(*Error); !ok {
+			err = b.fail(err)
+		}
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *decodedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if nerr := b.next.Close(); err == nil {
+		err = nerr
+	}
+	return err
+}
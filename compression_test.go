@@ -0,0 +1,257 @@
+package httpclient
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type compressed struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Tags  []string `json:"tags"`
+}
+
+const compressedJSON = `{"name":"widget","count":42,"tags":["a","b","c"]}`
+
+func init() {
+	RegisterDecoder("x-base64", func(r io.Reader) (io.ReadCloser, error) {
+		return ioutil.NopCloser(base64.NewDecoder(base64.StdEncoding, r)), nil
+	})
+}
+
+func encode(t *testing.T, encoding string, p []byte) []byte {
+	var b bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&b)
+	case "deflate":
+		w = zlib.NewWriter(&b)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&b, flate.DefaultCompression)
+	case "x-base64":
+		w = base64.NewEncoder(base64.StdEncoding, &b)
+	default:
+		t.Fatalf("unknown encoding %s", encoding)
+	}
+	w.Write(p)
+	w.Close()
+	return b.Bytes()
+}
+
+// encodedServer serves compressedJSON with the Content-Encoding of the
+// path, e.g. /gzip or /gzip,x-base64, encoded in order.
+func encodedServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := []byte(compressedJSON)
+		header := strings.TrimPrefix(r.URL.Path, "/")
+		for _, e := range strings.Split(header, ",") {
+			p = encode(t, e, p)
+		}
+		w.Header().Set("Content-Encoding", strings.Replace(header, "raw-deflate", "deflate", -1))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(p)
+	}))
+}
+
+func TestCompression(t *testing.T) {
+	srv := encodedServer(t)
+	defer srv.Close()
+	c := New(WithCompression("gzip", "deflate", "x-base64"))
+	var want compressed
+	if err := c.JSON(srv.URL+"/x-base64", &want); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []string{"gzip", "deflate", "raw-deflate", "gzip,x-base64"} {
+		var got compressed
+		if err := c.JSON(srv.URL+"/"+e, &got); err != nil {
+			t.Errorf("%s: %v", e, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", e, got, want)
+		}
+	}
+}
+
+func TestCompressionAcceptEncoding(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("Accept-Encoding"))
+	}))
+	defer srv.Close()
+	got, err := New(WithCompression("gzip", "x-unknown", "x-base64")).String(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "gzip, x-base64" {
+		t.Errorf("got Accept-Encoding %q", got)
+	}
+}
+
+func TestCompressionUnsupported(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "x-unknown")
+		io.WriteString(w, "data")
+	}))
+	defer srv.Close()
+	_, err := New(WithCompression("gzip")).Bytes(srv.URL)
+	var e *Error
+	if !errors.As(err, &e) || !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Fatalf("got %v, want an *Error with ErrUnsupportedEncoding", err)
+	}
+	if !strings.Contains(e.Error(), "x-unknown") {
+		t.Errorf("error %q does not name the encoding", e)
+	}
+}
+
+func TestCompressionCorruptReportedOnce(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		p := encode(t, "gzip", bytes.Repeat([]byte("x"), 1<<16))
+		w.Write(p[:len(p)/2])
+	}))
+	defer srv.Close()
+	c := New(WithCompression("gzip"))
+	var errs int32
+	c.OnError(func(string, error) { atomic.AddInt32(&errs, 1) })
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var first error
+	for i := 0; i < 3; i++ {
+		_, err := io.Copy(ioutil.Discard, resp.Body)
+		if err == nil {
+			t.Fatal("got no error")
+		}
+		if first == nil {
+			first = err
+		} else if err != first {
+			t.Errorf("read %d: got %v, want the first error again", i+1, err)
+		}
+	}
+	var e *Error
+	if !errors.As(first, &e) {
+		t.Errorf("got %T, want *Error", first)
+	}
+	if errs != 1 {
+		t.Errorf("OnError called %d times, want 1", errs)
+	}
+}
+
+func TestCompressionDeflate(t *testing.T) {
+	zlibBody := encode(t, "deflate", []byte(compressedJSON))
+	rawBody := encode(t, "raw-deflate", []byte(compressedJSON))
+	badSum := append([]byte(nil), zlibBody...)
+	badSum[len(badSum)-1] ^= 0xff
+	bodies := map[string][]byte{
+		"/zlib":          zlibBody,
+		"/raw":           rawBody,
+		"/empty":         nil,
+		"/zlib-short":    zlibBody[:len(zlibBody)/2],
+		"/raw-short":     rawBody[:len(rawBody)/2],
+		"/zlib-checksum": badSum,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "deflate")
+		w.Write(bodies[r.URL.Path])
+	}))
+	defer srv.Close()
+	c := New(WithCompression("deflate"))
+
+	tests := []struct {
+		path string
+		want string // the body, or the error
+	}{
+		{"/zlib", compressedJSON},
+		{"/raw", compressedJSON},
+		{"/empty", ""},
+		{"/zlib-short", "corrupt deflate body: unexpected EOF"},
+		{"/raw-short", "corrupt deflate body: unexpected EOF"},
+		{"/zlib-checksum", "corrupt deflate body: zlib: invalid checksum"},
+	}
+	for _, tt := range tests {
+		got, err := c.String(srv.URL + tt.path)
+		if err != nil {
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Errorf("%s: got %T, want an *Error", tt.path, err)
+			}
+			got = err.Error()
+		}
+		if !strings.HasSuffix(got, tt.want) || err == nil && got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCompressionReaderClose(t *testing.T) {
+	gone := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() { gone <- r.URL.Path }()
+		var body io.Writer = w
+		var zw *gzip.Writer
+		if r.URL.Path == "/gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			zw = gzip.NewWriter(w)
+			defer zw.Close()
+			body = zw
+		}
+		// An endless body, until the client goes away.
+		chunk := bytes.Repeat([]byte("x"), 4096)
+		for {
+			if _, err := body.Write(chunk); err != nil {
+				return
+			}
+			if zw != nil {
+				zw.Flush()
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			default:
+			}
+		}
+	}))
+	defer srv.Close()
+	c := New(WithCompression("gzip"))
+
+	for _, path := range []string{"/gzip", "/plain"} {
+		r, err := c.Reader(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := make([]byte, 10000)
+		if _, err := io.ReadFull(r, p); err != nil || p[9999] != 'x' {
+			t.Errorf("%s: read %q, %v", path, p[9990:], err)
+		}
+		if err := r.Close(); err != nil {
+			t.Errorf("%s: close: %v", path, err)
+		}
+		// Closing the reader closes the connection, and the server sees
+		// the client go.
+		select {
+		case got := <-gone:
+			if got != path {
+				t.Errorf("%s: got %s closed", path, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("%s: the body was not closed", path)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WithRequestTimeout bounds the time a request may take, from sending it
+// to closing the response body, to d. For Files, it bounds each download.
+func WithRequestTimeout(d time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.timeout = d
+	}
+}
+
+// withContext returns opts with ctx, which overrides any WithContext in
+// opts.
+func withContext(ctx context.Context, opts []RequestOption) []RequestOption {
+	return append(opts[:len(opts):len(opts)], WithContext(ctx))
+}
+
+// GetContext is Get with ctx.
+func (c *httpClient) GetContext(ctx context.Context, url string, opts ...RequestOption) (*http.Response, error) {
+	return c.Get(url, withContext(ctx, opts)...)
+}
+
+// BytesContext is Bytes with ctx.
+func (c *httpClient) BytesContext(ctx context.Context, url string, opts ...RequestOption) ([]byte, error) {
+	return c.Bytes(url, withContext(ctx, opts)...)
+}
+
+// StringContext is String with ctx.
+func (c *httpClient) StringContext(ctx context.Context, url string, opts ...RequestOption) (string, error) {
+	return c.String(url, withContext(ctx, opts)...)
+}
+
+// ReaderContext is Reader with ctx. Cancelling ctx also aborts reading the
+// body.
+func (c *httpClient) ReaderContext(ctx context.Context, url string, opts ...RequestOption) (io.ReadCloser, error) {
+	return c.Reader(url, withContext(ctx, opts)...)
+}
+
+// JSONContext is JSON with ctx.
+func (c *httpClient) JSONContext(ctx context.Context, url string, v interface{}, opts ...RequestOption) error {
+	return c.JSON(url, v, withContext(ctx, opts)...)
+}
+
+// XMLContext is XML with ctx.
+func (c *httpClient) XMLContext(ctx context.Context, url string, v interface{}, opts ...RequestOption) error {
+	return c.XML(url, v, withContext(ctx, opts)...)
+}
+
+// FilesContext is Files with ctx. Cancelling ctx aborts the downloads in
+// progress and skips those not started; FilesContext then returns an
+// *Error wrapping ctx.Err().
+func (c *httpClient) FilesContext(ctx context.Context, urls []string, files *[]File, opts ...RequestOption) error {
+	return c.Files(urls, files, withContext(ctx, opts)...)
+}
+
+// GetContext is Get with ctx, with the default client.
+func GetContext(ctx context.Context, url string, opts ...RequestOption) (*http.Response, error) {
+	return defaultClient().GetContext(ctx, url, opts...)
+}
+
+// BytesContext is Bytes with ctx, with the default client.
+func BytesContext(ctx context.Context, url string, opts ...RequestOption) ([]byte, error) {
+	return defaultClient().BytesContext(ctx, url, opts...)
+}
+
+// StringContext is String with ctx, with the default client.
+func StringContext(ctx context.Context, url string, opts ...RequestOption) (string, error) {
+	return defaultClient().StringContext(ctx, url, opts...)
+}
+
+// ReaderContext is Reader with ctx, with the default client.
+func ReaderContext(ctx context.Context, url string, opts ...RequestOption) (io.ReadCloser, error) {
+	return defaultClient().ReaderContext(ctx, url, opts...)
+}
+
+// JSONContext is JSON with ctx, with the default client.
+func JSONContext(ctx context.Context, url string, v interface{}, opts ...RequestOption) error {
+	return defaultClient().JSONContext(ctx, url, v, opts...)
+}
+
+// XMLContext is XML with ctx, with the default client.
+func XMLContext(ctx context.Context, url string, v interface{}, opts ...RequestOption) error {
+	return defaultClient().XMLContext(ctx, url, v, opts...)
+}
+
+// FilesContext is Files with ctx, with the default client.
+func FilesContext(ctx context.Context, urls []string, files *[]File, opts ...RequestOption) error {
+	return defaultClient().FilesContext(ctx, urls, files, opts...)
+}
@@ -0,0 +1,122 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stallServer answers /fast at once, and at /slow sends the headers and
+// the start of the body, then waits for the client to go.
+func stallServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fast" {
+			io.WriteString(w, `{"n":1}`)
+			return
+		}
+		io.WriteString(w, `{"n":`)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+}
+
+func TestContextCancel(t *testing.T) {
+	srv := stallServer()
+	defer srv.Close()
+	c := New()
+	url := srv.URL + "/slow"
+
+	tests := []struct {
+		name string
+		call func(ctx context.Context) error
+	}{
+		{"GetContext", func(ctx context.Context) error {
+			resp, err := c.GetContext(ctx, url)
+			if err == nil {
+				_, err = ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+			}
+			return err
+		}},
+		{"BytesContext", func(ctx context.Context) error { _, err := c.BytesContext(ctx, url); return err }},
+		{"StringContext", func(ctx context.Context) error { _, err := c.StringContext(ctx, url); return err }},
+		{"ReaderContext", func(ctx context.Context) error {
+			r, err := c.ReaderContext(ctx, url)
+			if err == nil {
+				_, err = ioutil.ReadAll(r)
+				r.Close()
+			}
+			return err
+		}},
+		{"JSONContext", func(ctx context.Context) error { var v interface{}; return c.JSONContext(ctx, url, &v) }},
+		{"XMLContext", func(ctx context.Context) error { var v interface{}; return c.XMLContext(ctx, url, &v) }},
+		{"FilesContext", func(ctx context.Context) error {
+			var files []File
+			return c.FilesContext(ctx, []string{srv.URL + "/fast", url, url}, &files)
+		}},
+	}
+	for _, tt := range tests {
+		// Cancelled halfway through the body.
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		if err := tt.call(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: got %v, want context.Canceled", tt.name, err)
+		}
+
+		// Past its deadline.
+		ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+		if err := tt.call(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s deadline: got %v, want context.DeadlineExceeded", tt.name, err)
+		}
+		cancel()
+	}
+
+	// A cancelled FilesContext fails with an *Error.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var files []File
+	err := c.FilesContext(ctx, []string{srv.URL + "/fast"}, &files)
+	var herr *Error
+	if !errors.As(err, &herr) || herr.Err != context.Canceled || files != nil {
+		t.Errorf("FilesContext: got %v, %d files, want an *Error", err, len(files))
+	}
+}
+
+func TestWithRequestTimeout(t *testing.T) {
+	srv := stallServer()
+	defer srv.Close()
+	c := New()
+
+	// The timeout covers the body, too.
+	if _, err := c.String(srv.URL+"/slow", WithRequestTimeout(50*time.Millisecond)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("String: got %v, want context.DeadlineExceeded", err)
+	}
+	r, err := c.Reader(srv.URL+"/slow", WithRequestTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(r); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Reader: got %v, want context.DeadlineExceeded", err)
+	}
+	r.Close()
+	if s, err := c.String(srv.URL+"/fast", WithRequestTimeout(time.Second)); err != nil || s != `{"n":1}` {
+		t.Errorf("fast: got %q, %v", s, err)
+	}
+
+	// For Files, it bounds each download, not the batch.
+	var files []File
+	err = c.Files([]string{srv.URL + "/fast", srv.URL + "/slow", srv.URL + "/fast"}, &files, WithRequestTimeout(50*time.Millisecond))
+	var berr *BatchError
+	if !errors.As(err, &berr) || len(berr.Errors) != 1 || berr.Errors[0].Index != 1 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Files: got %v, want the slow download to time out", err)
+	}
+	if !strings.Contains(berr.Errors[0].URL, "/slow") {
+		t.Errorf("Files: got %s, want the slow URL", berr.Errors[0].URL)
+	}
+}
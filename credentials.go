@@ -0,0 +1,130 @@
+package httpclient
+
+import (
+	"encoding/base64"
+	"net/http"
+	neturl "net/url"
+	"strings"
+)
+
+// A Credential authenticates the requests made to a host, see
+// SetCredentials.
+type Credential struct {
+	header http.Header
+	query  map[string]string
+}
+
+// BasicCredential returns a Credential for HTTP Basic authentication.
+func BasicCredential(user, password string) Credential {
+	auth := base64.StdEncoding.EncodeToString([]byte(user + ":" + password))
+	return HeaderCredential(http.Header{"Authorization": {"Basic " + auth}})
+}
+
+// BearerCredential returns a Credential sending token in an
+// "Authorization: Bearer" header.
+func BearerCredential(token string) Credential {
+	return HeaderCredential(http.Header{"Authorization": {"Bearer " + token}})
+}
+
+// APIKeyCredential returns a Credential sending key in the header or query
+// parameter name.
+func APIKeyCredential(key string, in APIKeyLocation, name string) Credential {
+	if in == APIKeyQuery {
+		return Credential{query: map[string]string{name: key}}
+	}
+	return HeaderCredential(http.Header{http.CanonicalHeaderKey(name): {key}})
+}
+
+// HeaderCredential returns a Credential sending the headers in h.
+func HeaderCredential(h http.Header) Credential {
+	c := Credential{header: make(http.Header, len(h))}
+	for k, vs := range h {
+		c.header[http.CanonicalHeaderKey(k)] = append([]string(nil), vs...)
+	}
+	return c
+}
+
+// SetCredentials makes c authenticate the requests to host with cred. The
+// host is matched exactly, with or without a port, or, if it starts with
+// "*.", by suffix: "*.example.com" matches api.example.com but not
+// example.com itself. An exact match wins over a suffix one, and a longer
+// suffix over a shorter one.
+//
+// The credential is added to each request as it is sent, after redirects
+// are followed, so it never reaches another host, and before the request
+// is signed, so that the signature covers it. A header or query parameter
+// that the request sets itself is left alone. The header and parameter
+// names are added to the redaction rules. Clones keep the credentials. It
+// must be called before c is used.
+func (c *httpClient) SetCredentials(host string, cred Credential) {
+	hosts := make(map[string]Credential, len(c.creds)+1)
+	for h, cr := range c.creds {
+		hosts[h] = cr
+	}
+	hosts[strings.ToLower(host)] = cred
+	c.creds = hosts
+	c.chain()
+
+	if c.redact.off {
+		return
+	}
+	var headers, params []string
+	for k := range cred.header {
+		headers = append(headers, k)
+	}
+	for k := range cred.query {
+		params = append(params, k)
+	}
+	c.redact.add(headers, params, nil)
+}
+
+// SetCredentials sets the credentials of the default client for host.
+func SetCredentials(host string, cred Credential) {
+	defaultClient().SetCredentials(host, cred)
+}
+
+// credTransport adds the credentials of the host to every request.
+type credTransport struct {
+	next  http.RoundTripper
+	hosts map[string]Credential
+}
+
+func (t *credTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cred, ok := t.lookup(req.URL)
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for k, vs := range cred.header {
+		if _, ok := req.Header[k]; !ok {
+			req.Header[k] = vs
+		}
+	}
+	if len(cred.query) > 0 {
+		q := req.URL.Query()
+		for k, v := range cred.query {
+			if _, ok := q[k]; !ok {
+				q.Set(k, v)
+			}
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+	return t.next.RoundTrip(req)
+}
+
+func (t *credTransport) lookup(u *neturl.URL) (Credential, bool) {
+	if cred, ok := t.hosts[strings.ToLower(u.Host)]; ok {
+		return cred, true
+	}
+	host := strings.ToLower(u.Hostname())
+	if cred, ok := t.hosts[host]; ok {
+		return cred, true
+	}
+	for i := strings.IndexByte(host, '.'); i >= 0; i = strings.IndexByte(host, '.') {
+		host = host[i+1:]
+		if cred, ok := t.hosts["*."+host]; ok {
+			return cred, true
+		}
+	}
+	return Credential{}, false
+}
@@ -0,0 +1,114 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetCredentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization") + "|" + r.URL.Query().Get("key")))
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	tests := []struct {
+		name string
+		host string
+		cred Credential
+		url  string
+		want string
+	}{
+		{"basic", host, BasicCredential("u", "p"), srv.URL, "Basic dTpw|"},
+		{"bearer by hostname", "127.0.0.1", BearerCredential("t"), srv.URL, "Bearer t|"},
+		{"query", host, APIKeyCredential("k", APIKeyQuery, "key"), srv.URL, "|k"},
+		{"query set by the request", host, APIKeyCredential("k", APIKeyQuery, "key"), srv.URL + "?key=mine", "|mine"},
+		{"other host", "example.com", BearerCredential("t"), srv.URL, "|"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			c.SetCredentials(tt.host, tt.cred)
+			got, err := c.String(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetCredentialsRedirect(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer other.Close()
+	srv := httptest.NewServer(http.RedirectHandler(other.URL+"/landing", http.StatusFound))
+	defer srv.Close()
+
+	c := New()
+	c.SetCredentials(strings.TrimPrefix(srv.URL, "http://"), BearerCredential("secret"))
+	got, err := c.String(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "" {
+		t.Errorf("redirected request got %q, want no credentials", got)
+	}
+}
+
+func TestCredentialLookup(t *testing.T) {
+	tr := &credTransport{hosts: map[string]Credential{
+		"api.example.com":  BearerCredential("exact"),
+		"*.example.com":    BearerCredential("short"),
+		"*.eu.example.com": BearerCredential("long"),
+		"example.org:8080": BearerCredential("port"),
+	}}
+	tests := []struct {
+		url, want string
+	}{
+		{"https://api.example.com/", "Bearer exact"},
+		{"https://www.example.com/", "Bearer short"},
+		{"https://a.eu.example.com/", "Bearer long"},
+		{"https://example.org:8080/", "Bearer port"},
+		{"https://example.com/", ""},
+		{"https://example.org/", ""},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.url, nil)
+		cred, _ := tr.lookup(req.URL)
+		if got := cred.header.Get("Authorization"); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+type headerSigner struct{ seen string }
+
+func (s *headerSigner) Sign(req *http.Request, body []byte) error {
+	s.seen = req.Header.Get("Authorization")
+	return nil
+}
+
+func TestSetCredentialsSignedAndCloned(t *testing.T) {
+	h := HandlerTransport(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	s := &headerSigner{}
+	c := New(WithSigner(s))
+	c.SetCredentials("example.com", BearerCredential("t"))
+	got, err := c.Clone(WithTransport(h)).String("http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Bearer t" {
+		t.Errorf("got %q, want the credential", got)
+	}
+	if s.seen != "Bearer t" {
+		t.Errorf("signer saw %q, want the credential", s.seen)
+	}
+}
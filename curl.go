@@ -0,0 +1,87 @@
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// WithCurlCommand calls fn with a curl command line equivalent to the
+// outgoing request, as it is sent, with the headers that credentials and
+// signers add. Secrets are redacted; see WithCurlSecrets.
+func WithCurlCommand(fn func(cmd string)) RequestOption {
+	return func(o *requestOptions) {
+		o.curl = fn
+	}
+}
+
+// WithCurlSecrets includes secrets in the command passed to the
+// WithCurlCommand callback instead of redacting them.
+func WithCurlSecrets() RequestOption {
+	return func(o *requestOptions) {
+		o.curlSecrets = true
+	}
+}
+
+// WithCurlDebug writes a curl command line equivalent to every request the
+// client makes to w, one per line. Secrets are redacted; see WithRedaction.
+func WithCurlDebug(w io.Writer) Option {
+	return func(c *httpClient) {
+		c.curl = w
+	}
+}
+
+// curlCommand renders req as a shell-escaped curl command. Headers are
+// sorted by name so that the output is stable. Textual bodies are passed
+// with --data-raw; multipart and binary bodies are replaced by a comment.
+func curlCommand(req *http.Request, redact *redactor) string {
+	var b strings.Builder
+	b.WriteString("curl")
+	if req.Method != "GET" {
+		b.WriteString(" -X " + shellQuote(req.Method))
+	}
+	b.WriteString(" " + shellQuote(redact.url(req.URL).String()))
+
+	header := redact.header(req.Header)
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range header[k] {
+			b.WriteString(" -H " + shellQuote(k+": "+v))
+		}
+	}
+
+	if req.GetBody == nil || req.ContentLength == 0 {
+		return b.String()
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return b.String() + " # body unavailable"
+	}
+	p, err := ioutil.ReadAll(body)
+	body.Close()
+	ct := req.Header.Get("Content-Type")
+	switch {
+	case err != nil:
+		b.WriteString(" # body unavailable")
+	case strings.HasPrefix(strings.ToLower(ct), "multipart/"):
+		fmt.Fprintf(&b, " # multipart body (%d bytes) omitted", len(p))
+	case !isText(ct, p):
+		fmt.Fprintf(&b, " # binary body (%d bytes) omitted", len(p))
+	default:
+		b.WriteString(" --data-raw " + shellQuote(string(redact.body(ct, p))))
+	}
+	return b.String()
+}
+
+// shellQuote quotes s for a POSIX shell. Everything between single quotes is
+// literal, newlines included, so only single quotes themselves need care.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,140 @@
+package httpclient
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCurlCommand(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	body := "{\"msg\": \"it's \\\"quoted\\\"\\nand\nsplit\"}"
+	var cmds []string
+	for i := 0; i < 10; i++ {
+		resp, err := New().Post(srv.URL+"/items?q=a+b", "application/json", strings.NewReader(body),
+			WithHeaders(map[string]string{"X-B": "two", "X-A": "it's", "X-C": "three", "Authorization": "Bearer secret"}),
+			WithCurlCommand(func(cmd string) { cmds = append(cmds, cmd) }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	for _, cmd := range cmds[1:] {
+		if cmd != cmds[0] {
+			t.Fatalf("commands differ:\n%s\n%s", cmds[0], cmd)
+		}
+	}
+	want := "curl -X 'POST' '" + srv.URL + "/items?q=a+b'" +
+		" -H 'Authorization: [REDACTED]' -H 'Content-Type: application/json'" +
+		` -H 'X-A: it'\''s' -H 'X-B: two' -H 'X-C: three'` +
+		` --data-raw '{"msg": "it'\''s \"quoted\"\nand` + "\n" + `split"}'`
+	if cmds[0] != want {
+		t.Errorf("got\n%s\nwant\n%s", cmds[0], want)
+	}
+
+	// The shell must see the exact header values and body.
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no shell")
+	}
+	out, err := exec.Command(sh, "-c", `set -- `+strings.TrimPrefix(cmds[0], "curl ")+`; for a; do printf '%s\0' "$a"; done`).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	wantArgs := []string{"-X", "POST", srv.URL + "/items?q=a+b",
+		"-H", "Authorization: [REDACTED]", "-H", "Content-Type: application/json",
+		"-H", "X-A: it's", "-H", "X-B: two", "-H", "X-C: three", "--data-raw", body}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("shell saw %q, want %q", args, wantArgs)
+	}
+}
+
+func TestCurlCommandBodies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	tests := []struct {
+		contentType string
+		body        []byte
+		want        string
+	}{
+		{"multipart/form-data; boundary=x", []byte("--x\r\n\r\nfield\r\n--x--\r\n"), " # multipart body (21 bytes) omitted"},
+		{"application/octet-stream", []byte{0, 1, 2, 0xff}, " # binary body (4 bytes) omitted"},
+		{"text/plain", []byte("hello"), " --data-raw 'hello'"},
+	}
+	for _, tt := range tests {
+		var cmd string
+		resp, err := New().Post(srv.URL, tt.contentType, bytes.NewReader(tt.body), WithCurlCommand(func(s string) { cmd = s }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if !strings.HasSuffix(cmd, tt.want) {
+			t.Errorf("%s: got %s, want suffix %q", tt.contentType, cmd, tt.want)
+		}
+	}
+}
+
+func TestCurlSecrets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var debug bytes.Buffer
+	c := New(WithCurlDebug(&debug))
+	var cmd string
+	resp, err := c.Get(srv.URL+"/?api_key=k1", WithRequestHeader("Authorization", "Bearer t1"),
+		WithCurlCommand(func(s string) { cmd = s }), WithCurlSecrets())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if !strings.Contains(cmd, "api_key=k1") || !strings.Contains(cmd, "Bearer t1") {
+		t.Errorf("WithCurlSecrets redacted %s", cmd)
+	}
+	if s := debug.String(); strings.Contains(s, "k1") || strings.Contains(s, "t1") || !strings.HasSuffix(s, "\n") {
+		t.Errorf("WithCurlDebug wrote %q", s)
+	}
+}
+
+// markSigner signs a request by setting a header.
+type markSigner struct{}
+
+func (markSigner) Sign(req *http.Request, bodyHash []byte) error {
+	req.Header.Set("X-Signature", "signed")
+	return nil
+}
+
+func TestCurlFinalRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var curl, dump bytes.Buffer
+	c := New(WithCurlDebug(&curl), WithDebug(&dump), WithSigner(markSigner{}))
+	c.SetCredentials(strings.TrimPrefix(srv.URL, "http://"), HeaderCredential(http.Header{"X-Token": {"s3cret"}}))
+	var cmd string
+	if _, err := c.String(srv.URL+"/a", WithCurlCommand(func(s string) { cmd = s }), WithCurlSecrets()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The headers of the credentials and the signer are rendered, and the
+	// credentials redacted, except with WithCurlSecrets.
+	if !strings.Contains(cmd, "-H 'X-Token: s3cret'") || !strings.Contains(cmd, "-H 'X-Signature: signed'") {
+		t.Errorf("WithCurlCommand: got %s", cmd)
+	}
+	if s := curl.String(); !strings.Contains(s, "-H 'X-Token: [REDACTED]'") || !strings.Contains(s, "-H 'X-Signature: signed'") || strings.Contains(s, "s3cret") {
+		t.Errorf("WithCurlDebug: got %s", s)
+	}
+	if s := dump.String(); !strings.Contains(s, "X-Token: [REDACTED]\r\n") || !strings.Contains(s, "X-Signature: signed\r\n") || strings.Contains(s, "s3cret") {
+		t.Errorf("WithDebug: got %s", s)
+	}
+	if n := strings.Count(curl.String(), "\n"); n != 1 {
+		t.Errorf("WithCurlDebug: got %d commands, want 1", n)
+	}
+}
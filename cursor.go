@@ -0,0 +1,149 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+)
+
+// ErrStopPaging can be returned by the callback of PaginateCursor to end
+// the iteration without an error.
+var ErrStopPaging = errors.New("httpclient: stop paging")
+
+// ErrCursorLoop is returned by PaginateCursor when the API returns a cursor
+// it has returned before.
+var ErrCursorLoop = errors.New("httpclient: cursor loop")
+
+// cursorMemory is how many of the last cursors PaginateCursor remembers to
+// detect loops.
+const cursorMemory = 64
+
+// CursorConfig describes a cursor-paginated API for PaginateCursor. Paths
+// are dotted field names in the JSON body, e.g. "meta.next_cursor".
+type CursorConfig struct {
+	// ItemsPath is the path of the items of a page, e.g. "items". An empty
+	// path passes the whole body.
+	ItemsPath string
+
+	// CursorPath is the path of the cursor of the next page, e.g.
+	// "next_cursor". The cursor may be a string or a number.
+	CursorPath string
+
+	// Param is the query parameter the cursor is sent in, e.g. "cursor".
+	Param string
+}
+
+// PaginateCursor fetches url and calls each with the items of the response,
+// then fetches the next page with the cursor found in the response, until
+// the cursor is empty, null or absent, or each returns an error. If each
+// returns ErrStopPaging, PaginateCursor returns nil; other errors are
+// returned as is. WithMaxPages limits the number of pages.
+func (c *httpClient) PaginateCursor(url string, cfg CursorConfig, each func(items json.RawMessage) error, opts ...RequestOption) error {
+	if cfg.CursorPath == "" || cfg.Param == "" {
+		return errors.New("httpclient: cursor config needs a cursor path and a parameter")
+	}
+	o := newRequestOptions(opts)
+	seen := make(map[string]bool)
+	var recent []string
+	for pages := 0; ; pages++ {
+		if o.maxPages > 0 && pages >= o.maxPages {
+			return ErrMaxPages
+		}
+		resp, err := c.send("GET", url, nil, o)
+		if err != nil {
+			return err
+		}
+		p, err := c.readJSON(resp, o)
+		if err != nil {
+			return err
+		}
+		items, err := jsonLookup(p, cfg.ItemsPath)
+		if err != nil {
+			return fmt.Errorf("httpclient: items: %v", err)
+		}
+		raw, err := jsonLookup(p, cfg.CursorPath)
+		if err != nil {
+			return fmt.Errorf("httpclient: cursor: %v", err)
+		}
+		if err := each(items); err != nil {
+			if err == ErrStopPaging {
+				return nil
+			}
+			return err
+		}
+
+		cursor := cursorString(raw)
+		if cursor == "" {
+			return nil
+		}
+		if seen[cursor] {
+			return fmt.Errorf("%w: %q", ErrCursorLoop, cursor)
+		}
+		seen[cursor] = true
+		if recent = append(recent, cursor); len(recent) > cursorMemory {
+			delete(seen, recent[0])
+			recent = recent[1:]
+		}
+		if o.query == nil {
+			o.query = make(neturl.Values)
+		}
+		o.query.Set(cfg.Param, cursor)
+	}
+}
+
+// PaginateCursor follows the pages of url with the default client.
+func PaginateCursor(url string, cfg CursorConfig, each func(items json.RawMessage) error, opts ...RequestOption) error {
+	return defaultClient().PaginateCursor(url, cfg, each, opts...)
+}
+
+// readJSON reads the body of an accepted response, closing it, and checks
+// that it is JSON.
+func (c *httpClient) readJSON(resp *http.Response, o *requestOptions) ([]byte, error) {
+	defer resp.Body.Close()
+	if !c.accepted(resp, o) {
+		return nil, c.err(resp, "")
+	}
+	p, err := c.readAll(resp)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(p) {
+		return nil, c.err(resp, "JSON syntax error at "+c.redact.url(resp.Request.URL).String())
+	}
+	return p, nil
+}
+
+// jsonLookup returns the value at the dotted path in the JSON document p,
+// or nil if there is none.
+func jsonLookup(p json.RawMessage, path string) (json.RawMessage, error) {
+	if path == "" {
+		return p, nil
+	}
+	for _, name := range strings.Split(path, ".") {
+		if len(p) == 0 || string(p) == "null" {
+			return nil, nil
+		}
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(p, &m); err != nil {
+			return nil, fmt.Errorf("%s is not an object", path)
+		}
+		p = m[name]
+	}
+	return p, nil
+}
+
+// cursorString returns the cursor in raw, a JSON string or number.
+func cursorString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n.String()
+	}
+	return ""
+}
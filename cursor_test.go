@@ -0,0 +1,188 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// cursorServer serves the pages of a cursor API at /items: the body of the
+// page for a cursor, "" being the first one, and the cursors asked for.
+func cursorServer(pages map[string]string) (*httptest.Server, *[]string) {
+	var cursors []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.URL.Query().Get("cursor")
+		cursors = append(cursors, cursor)
+		page, ok := pages[cursor]
+		if !ok || r.URL.Query().Get("limit") != "2" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, page)
+	}))
+	return srv, &cursors
+}
+
+func TestPaginateCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     CursorConfig
+		pages   map[string]string
+		items   string
+		cursors string
+	}{
+		{
+			"strings",
+			CursorConfig{ItemsPath: "items", CursorPath: "next_cursor", Param: "cursor"},
+			map[string]string{
+				"":    `{"items": [1, 2], "next_cursor": "abc"}`,
+				"abc": `{"items": [3, 4], "next_cursor": "def"}`,
+				"def": `{"items": [5], "next_cursor": ""}`,
+			},
+			"[1,2] [3,4] [5]",
+			"[ abc def]",
+		},
+		{
+			"nested null",
+			CursorConfig{ItemsPath: "data.items", CursorPath: "meta.next", Param: "cursor"},
+			map[string]string{
+				"":  `{"data": {"items": ["a"]}, "meta": {"next": 7}}`,
+				"7": `{"data": {"items": ["b"]}, "meta": {"next": null}}`,
+			},
+			`["a"] ["b"]`,
+			"[ 7]",
+		},
+		{
+			"absent",
+			CursorConfig{ItemsPath: "items", CursorPath: "meta.next", Param: "cursor"},
+			map[string]string{
+				"":  `{"items": [], "meta": {"next": "x"}}`,
+				"x": `{"items": [9]}`,
+			},
+			"[] [9]",
+			"[ x]",
+		},
+		{
+			"whole body",
+			CursorConfig{CursorPath: "next", Param: "cursor"},
+			map[string]string{"": `{"next": ""}`},
+			`{"next":""}`,
+			"[]",
+		},
+	}
+	for _, tt := range tests {
+		srv, cursors := cursorServer(tt.pages)
+		var items []string
+		err := PaginateCursor(srv.URL+"/items", tt.cfg, func(p json.RawMessage) error {
+			items = append(items, strings.ReplaceAll(string(p), " ", ""))
+			return nil
+		}, WithQueryParam("limit", "2"))
+		srv.Close()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := strings.Join(items, " "); got != tt.items {
+			t.Errorf("%s: got items %s, want %s", tt.name, got, tt.items)
+		}
+		if got := fmt.Sprint(*cursors); got != tt.cursors {
+			t.Errorf("%s: got cursors %s, want %s", tt.name, got, tt.cursors)
+		}
+	}
+}
+
+func TestPaginateCursorStop(t *testing.T) {
+	srv, cursors := cursorServer(map[string]string{
+		"":  `{"items": [1], "next": "b"}`,
+		"b": `{"items": [2], "next": "c"}`,
+		"c": `{"items": [3], "next": "d"}`,
+		"d": `{"items": [4]}`,
+	})
+	defer srv.Close()
+	cfg := CursorConfig{ItemsPath: "items", CursorPath: "next", Param: "cursor"}
+	c := New(WithBaseURL(srv.URL))
+
+	pages := 0
+	err := c.PaginateCursor("/items", cfg, func(json.RawMessage) error {
+		if pages++; pages == 2 {
+			return ErrStopPaging
+		}
+		return nil
+	}, WithQueryParam("limit", "2"))
+	if err != nil || pages != 2 {
+		t.Errorf("stop: got %d pages, %v", pages, err)
+	}
+
+	boom := errors.New("boom")
+	err = c.PaginateCursor("/items", cfg, func(json.RawMessage) error { return boom }, WithQueryParam("limit", "2"))
+	if err != boom {
+		t.Errorf("error: got %v", err)
+	}
+
+	pages = 0
+	err = c.PaginateCursor("/items", cfg, func(json.RawMessage) error {
+		pages++
+		return nil
+	}, WithQueryParam("limit", "2"), WithMaxPages(3))
+	if !errors.Is(err, ErrMaxPages) || pages != 3 {
+		t.Errorf("max pages: got %d pages, %v", pages, err)
+	}
+
+	*cursors = nil
+	err = c.PaginateCursor("/items", cfg, func(json.RawMessage) error { return nil })
+	var herr *Error
+	if !errors.As(err, &herr) || herr.StatusCode != 404 || len(*cursors) != 1 {
+		t.Errorf("404: got %v after %d requests", err, len(*cursors))
+	}
+}
+
+func TestPaginateCursorLoop(t *testing.T) {
+	srv, cursors := cursorServer(map[string]string{
+		"":  `{"items": [1], "next": "a"}`,
+		"a": `{"items": [2], "next": "b"}`,
+		"b": `{"items": [3], "next": "c"}`,
+		"c": `{"items": [4], "next": "a"}`,
+	})
+	defer srv.Close()
+
+	pages := 0
+	err := PaginateCursor(srv.URL+"/items", CursorConfig{ItemsPath: "items", CursorPath: "next", Param: "cursor"},
+		func(json.RawMessage) error {
+			pages++
+			return nil
+		}, WithQueryParam("limit", "2"))
+	if !errors.Is(err, ErrCursorLoop) || !strings.Contains(err.Error(), `"a"`) {
+		t.Errorf("got %v, want a cursor loop on a", err)
+	}
+	if pages != 4 || fmt.Sprint(*cursors) != "[ a b c]" {
+		t.Errorf("got %d pages, cursors %v", pages, *cursors)
+	}
+}
+
+func TestPaginateCursorErrors(t *testing.T) {
+	srv, _ := cursorServer(map[string]string{
+		"":  `{"items": [1], "next": "a"}`,
+		"a": `{"items": [2], "next": {"id": "b"}}`,
+	})
+	defer srv.Close()
+	each := func(json.RawMessage) error { return nil }
+
+	// A cursor that is neither a string nor a number ends the iteration.
+	err := PaginateCursor(srv.URL+"/items", CursorConfig{ItemsPath: "items", CursorPath: "next", Param: "cursor"}, each, WithQueryParam("limit", "2"))
+	if err != nil {
+		t.Errorf("object cursor: got %v", err)
+	}
+	err = PaginateCursor(srv.URL+"/items", CursorConfig{ItemsPath: "items.list", CursorPath: "next", Param: "cursor"}, each, WithQueryParam("limit", "2"))
+	if err == nil || !strings.Contains(err.Error(), "items.list is not an object") {
+		t.Errorf("items path: got %v", err)
+	}
+	err = PaginateCursor(srv.URL+"/items", CursorConfig{ItemsPath: "items"}, each)
+	if err == nil || !strings.Contains(err.Error(), "cursor config") {
+		t.Errorf("config: got %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+package httpclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// debugBodyLimit is the number of body bytes included in a debug dump.
+	debugBodyLimit = 64 << 10
+
+	// debugRedactLimit is the size of the largest body redacted, as a
+	// whole, before it is truncated to debugBodyLimit.
+	debugRedactLimit = 1 << 20
+)
+
+// WithDebug writes a numbered dump of every request and response to w.
+// Bodies are included up to 64KB, binary bodies are elided and secrets are
+// redacted; see WithRedaction. A body larger than 1MB that the redaction
+// rules apply to, such as a JSON body when body paths are set, is elided
+// instead, since it cannot be redacted whole. A response is dumped once
+// its body has been read or closed, with the part of the body the caller
+// read.
+func WithDebug(w io.Writer) Option {
+	return func(c *httpClient) {
+		c.debug = &debugger{w: w}
+	}
+}
+
+// WithDebugSecrets includes secrets in the dumps of WithDebug instead of
+// redacting them. The other diagnostics are still redacted; see
+// WithUnsafeNoRedaction to turn redaction off everywhere.
+func WithDebugSecrets() Option {
+	return func(c *httpClient) {
+		c.debugSecrets = true
+	}
+}
+
+// A debugger writes request and response dumps. Dumps of concurrent requests
+// may interleave, but each one is written in a single piece.
+type debugger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	seq    int64
+	redact *redactor
+}
+
+func (d *debugger) next() int64 {
+	return atomic.AddInt64(&d.seq, 1)
+}
+
+func (d *debugger) write(p []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.w.Write(p)
+}
+
+// wireKey is the context key of the *wire of a request.
+type wireKey struct{}
+
+// A wire holds what is rendered of a request as it is sent: the number of
+// its debug dump, if any, and its WithCurlCommand callback.
+type wire struct {
+	id          int64
+	curl        func(string)
+	curlSecrets bool
+}
+
+// wireTransport renders the curl commands and debug dumps of the requests
+// sent with next. It is the innermost transport, so that they show the
+// headers the other transports add, such as credentials and signatures.
+type wireTransport struct {
+	next   http.RoundTripper
+	curl   io.Writer
+	debug  *debugger
+	redact *redactor
+}
+
+func (t *wireTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if w, ok := req.Context().Value(wireKey{}).(*wire); ok {
+		if t.curl != nil {
+			fmt.Fprintln(t.curl, curlCommand(req, t.redact))
+		}
+		if w.curl != nil {
+			redact := t.redact
+			if w.curlSecrets {
+				redact = noRedaction
+			}
+			w.curl(curlCommand(req, redact))
+		}
+		if t.debug != nil && w.id != 0 {
+			t.debug.request(w.id, req)
+		}
+	}
+	return t.next.RoundTrip(req)
+}
+
+func (d *debugger) request(id int64, req *http.Request) {
+	r := *req
+	r.URL = d.redact.url(req.URL)
+	r.Header = d.redact.header(req.Header)
+	dump, err := httputil.DumpRequestOut(&r, false)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "---- request #%d ----\n", id)
+	if err != nil {
+		fmt.Fprintf(&buf, "[dump failed: %v]\n", err)
+	} else {
+		buf.Write(dump)
+	}
+	if req.GetBody != nil && req.ContentLength != 0 {
+		if body, err := req.GetBody(); err == nil {
+			p, _ := io.ReadAll(io.LimitReader(body, debugRedactLimit+1))
+			body.Close()
+			writeDebugBody(&buf, req.Header.Get("Content-Type"), p, d.redact)
+		}
+	}
+	d.write(buf.Bytes())
+}
+
+// response dumps resp once its body has been read to the end or closed,
+// keeping up to debugRedactLimit bytes of the body as the caller reads it.
+func (d *debugger) response(id int64, resp *http.Response, elapsed time.Duration) {
+	r := *resp
+	r.Header = d.redact.header(resp.Header)
+	r.Body = nil
+	dump, err := httputil.DumpResponse(&r, false)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "---- response #%d (%s) ----\n", id, elapsed)
+	if err != nil {
+		fmt.Fprintf(&buf, "[dump failed: %v]\n", err)
+	} else {
+		buf.Write(dump)
+	}
+	resp.Body = &debugBody{ReadCloser: resp.Body, d: d, dump: buf, contentType: resp.Header.Get("Content-Type"), size: resp.ContentLength}
+}
+
+// debugBody keeps the first bytes read from a response body and writes the
+// dump of the response when the body is done with.
+type debugBody struct {
+	io.ReadCloser
+	d           *debugger
+	dump        bytes.Buffer
+	contentType string
+	size        int64
+	p           []byte
+	n           int64
+	done        bool
+}
+
+func (b *debugBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.done {
+		return n, err
+	}
+	b.n += int64(n)
+	if room := debugRedactLimit + 1 - len(b.p); room > 0 {
+		if room > n {
+			room = n
+		}
+		b.p = append(b.p, p[:room]...)
+	}
+	if err != nil {
+		b.flush(err)
+	}
+	return n, err
+}
+
+func (b *debugBody) Close() error {
+	if !b.done {
+		b.flush(nil)
+	}
+	return b.ReadCloser.Close()
+}
+
+// flush writes the dump, given the error that ended the body, or nil if it
+// was closed before its end.
+func (b *debugBody) flush(err error) {
+	b.done = true
+	writeDebugBody(&b.dump, b.contentType, b.p, b.d.redact)
+	switch {
+	case err == nil:
+		if b.size < 0 || b.n < b.size {
+			fmt.Fprintf(&b.dump, "[body closed after %d bytes]\n\n", b.n)
+		}
+	case err != io.EOF:
+		fmt.Fprintf(&b.dump, "[body read failed: %v]\n\n", err)
+	}
+	b.d.write(b.dump.Bytes())
+}
+
+func (d *debugger) failure(id int64, err error, elapsed time.Duration) {
+	d.write([]byte(fmt.Sprintf("---- response #%d (%s) ----\n[request failed: %v]\n\n", id, elapsed, err)))
+}
+
+// writeDebugBody writes the first bytes p of a body to buf, redacted, then
+// truncated to debugBodyLimit. p holds the whole body unless it is longer
+// than debugRedactLimit.
+func writeDebugBody(buf *bytes.Buffer, contentType string, p []byte, redact *redactor) {
+	switch {
+	case len(p) == 0:
+		return
+	case !isText(contentType, p):
+		fmt.Fprintf(buf, "[binary body elided]\n\n")
+		return
+	case len(p) > debugRedactLimit && redact.redactsBody(contentType):
+		fmt.Fprintf(buf, "[body of more than %d bytes elided, too large to redact]\n\n", debugRedactLimit)
+		return
+	}
+	p = redact.body(contentType, p)
+	if len(p) > debugBodyLimit {
+		buf.Write(p[:debugBodyLimit])
+		fmt.Fprintf(buf, "\n[body truncated after %d bytes]\n\n", debugBodyLimit)
+		return
+	}
+	buf.Write(p)
+	buf.WriteString("\n\n")
+}
+
+// replayBody is a response body whose first bytes have already been read
+// and are served again from memory.
+type replayBody struct {
+	io.Reader
+	io.Closer
+}
+
+// isText reports whether a body of the given content type, beginning with
+// sample, is human readable.
+func isText(contentType string, sample []byte) bool {
+	if contentType == "" {
+		contentType = http.DetectContentType(sample)
+	}
+	ct := strings.ToLower(contentType)
+	switch {
+	case strings.HasPrefix(ct, "text/"),
+		strings.Contains(ct, "json"),
+		strings.Contains(ct, "xml"),
+		strings.Contains(ct, "javascript"),
+		strings.Contains(ct, "x-www-form-urlencoded"):
+		return true
+	case strings.HasPrefix(ct, "multipart/"):
+		return false
+	}
+	if len(sample) > debugBodyLimit {
+		sample = sample[:debugBodyLimit]
+	}
+	return utf8.Valid(sample) && bytes.IndexByte(sample, 0) < 0
+}
@@ -0,0 +1,140 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestDebugJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=s3cr3t")
+		io.WriteString(w, `{"name":"app","token":"tok3n"}`)
+	}))
+	defer srv.Close()
+	var out syncBuffer
+	c := New(WithDebug(&out), WithRedaction(nil, nil, []string{"token"}))
+	var v struct{ Name string }
+	err := c.JSON(srv.URL+"/x?api_key=k3y", &v, WithRequestHeader("Authorization", "Bearer b34rer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump := out.String()
+	for _, want := range []string{
+		"---- request #1 ----",
+		"GET /x?api_key=[REDACTED]",
+		"---- response #1 (",
+		"200 OK",
+		`"name":"app"`,
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump lacks %q:\n%s", want, dump)
+		}
+	}
+	for _, secret := range []string{"k3y", "b34rer", "s3cr3t", "tok3n"} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("dump leaks %q:\n%s", secret, dump)
+		}
+	}
+}
+
+func TestDebugRequestBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	var out syncBuffer
+	c := New(WithDebug(&out))
+	resp, err := c.Post(srv.URL, "application/x-www-form-urlencoded", strings.NewReader("user=a&password=hunter2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	dump := out.String()
+	if !strings.Contains(dump, "user=a&password=[REDACTED]") {
+		t.Errorf("dump lacks the redacted body:\n%s", dump)
+	}
+}
+
+func TestDebugBinaryAndTruncated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bin" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte{0, 1, 2, 3})
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write(bytes.Repeat([]byte("a"), debugBodyLimit+100))
+	}))
+	defer srv.Close()
+	var out syncBuffer
+	c := New(WithDebug(&out))
+	if _, err := c.Bytes(srv.URL + "/bin"); err != nil {
+		t.Fatal(err)
+	}
+	body, err := c.Bytes(srv.URL + "/text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != debugBodyLimit+100 {
+		t.Errorf("got %d bytes, want the whole body", len(body))
+	}
+	dump := out.String()
+	for _, want := range []string{"[binary body elided]", "[body truncated after 65536 bytes]"} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump lacks %q", want)
+		}
+	}
+}
+
+func TestDebugResponseCapturedLazily(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "first chunk")
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+	var out syncBuffer
+	resp, err := New(WithDebug(&out)).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := make([]byte, 5)
+	if _, err := io.ReadFull(resp.Body, p); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "---- response") {
+		t.Error("response dumped before its body was done with")
+	}
+	resp.Body.Close()
+	dump := out.String()
+	if !strings.Contains(dump, "first\n") || !strings.Contains(dump, "[body closed after 5 bytes]") {
+		t.Errorf("got dump:\n%s", dump)
+	}
+}
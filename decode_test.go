@@ -0,0 +1,83 @@
+package httpclient
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	XMLName xml.Name `json:"-" xml:"item"`
+	Name    string   `json:"name" xml:"name"`
+	Count   int      `json:"count" xml:"count"`
+}
+
+func decodeServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"name":"widget","count":3}`)
+		case "/xml":
+			w.Header().Set("Content-Type", "application/xml")
+			io.WriteString(w, `<item><name>widget</name><count>3</count></item>`)
+		case "/bad-json":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"name":}`)
+		case "/bad-xml":
+			w.Header().Set("Content-Type", "application/xml")
+			io.WriteString(w, `<item><name>widget</item>`)
+		default:
+			w.WriteHeader(http.StatusTeapot)
+			io.WriteString(w, `{"error":"no"}`)
+		}
+	}))
+}
+
+func TestJSONAndXML(t *testing.T) {
+	srv := decodeServer()
+	defer srv.Close()
+	want := item{Name: "widget", Count: 3}
+
+	decoders := []struct {
+		name   string
+		decode func(url string, v interface{}, opts ...RequestOption) error
+		path   string
+		bad    string
+	}{
+		{"JSON", JSON, "/json", "/bad-json"},
+		{"XML", XML, "/xml", "/bad-xml"},
+		{"client JSON", New().JSON, "/json", "/bad-json"},
+		{"client XML", New().XML, "/xml", "/bad-xml"},
+	}
+	for _, d := range decoders {
+		t.Run(d.name, func(t *testing.T) {
+			var got item
+			if err := d.decode(srv.URL+d.path, &got); err != nil {
+				t.Fatal(err)
+			}
+			got.XMLName = xml.Name{}
+			if got != want {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+
+			err := d.decode(srv.URL+d.bad, &got)
+			var e *Error
+			if !errors.As(err, &e) || !strings.Contains(e.Message, "syntax error") {
+				t.Errorf("bad syntax: got %v, want a syntax *Error", err)
+			}
+
+			err = d.decode(srv.URL+"/teapot", &got)
+			if !errors.As(err, &e) || e.StatusCode != http.StatusTeapot {
+				t.Fatalf("non-200: got %v, want a 418 *Error", err)
+			}
+			if string(e.Body) != `{"error":"no"}` {
+				t.Errorf("non-200: got body %q", e.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package httpclient
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// SetDigestAuth makes c answer Digest authentication challenges (RFC 7616)
+// with user and password. A request that gets a 401 response with a Digest
+// challenge is sent again, once, with the credentials; the challenge is
+// then kept per host so that later requests authenticate up front. The MD5
+// and SHA-256 algorithms and their -sess variants are supported, with
+// qop=auth or without qop.
+//
+// A request whose body cannot be replayed, i.e. whose GetBody is nil, gets
+// the 401 response unless the host has been authenticated before. The
+// request is signed after it is authorized. Clones keep the credentials
+// and share the challenges. It must be called before c is used.
+func (c *httpClient) SetDigestAuth(user, password string) {
+	c.digest = &digestAuth{
+		user:       user,
+		password:   password,
+		challenges: make(map[string]*digestChallenge),
+	}
+	c.chain()
+}
+
+// SetDigestAuth sets the Digest credentials of the default client.
+func SetDigestAuth(user, password string) {
+	defaultClient().SetDigestAuth(user, password)
+}
+
+// digestAuth holds the credentials of SetDigestAuth and the challenges
+// answered so far.
+type digestAuth struct {
+	mu             sync.Mutex
+	user, password string
+	challenges     map[string]*digestChallenge
+}
+
+// digestTransport answers the Digest challenges of the requests sent with
+// next.
+type digestTransport struct {
+	next http.RoundTripper
+	*digestAuth
+}
+
+type digestChallenge struct {
+	realm, nonce, opaque, algorithm, qop string
+	stale                                bool
+	nc                                   int
+}
+
+func (t *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sent := ""
+	if ch := t.challenge(req.URL.Host); ch != nil {
+		req, sent = t.authorize(req, ch)
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	ch := parseDigestChallenge(resp.Header)
+	if ch == nil || ch.nonce == sent && !ch.stale {
+		return resp, nil
+	}
+	t.mu.Lock()
+	t.challenges[req.URL.Host] = ch
+	t.mu.Unlock()
+
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return resp, nil
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	req, _ = t.authorize(req, ch)
+	return t.next.RoundTrip(req)
+}
+
+func (t *digestTransport) challenge(host string) *digestChallenge {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.challenges[host]
+}
+
+// authorize returns a copy of req with the Authorization header answering
+// ch, and the nonce it used.
+func (t *digestTransport) authorize(req *http.Request, ch *digestChallenge) (*http.Request, string) {
+	t.mu.Lock()
+	ch.nc++
+	nc := fmt.Sprintf("%08x", ch.nc)
+	user, password := t.user, t.password
+	t.mu.Unlock()
+
+	var h func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(ch.algorithm), "-SESS") {
+	case "SHA-256":
+		h = sha256.New
+	default:
+		h = md5.New
+	}
+	digest := func(parts ...string) string {
+		d := h()
+		io.WriteString(d, strings.Join(parts, ":"))
+		return hex.EncodeToString(d.Sum(nil))
+	}
+
+	cnonce := newCnonce()
+	uri := req.URL.RequestURI()
+	ha1 := digest(user, ch.realm, password)
+	if strings.HasSuffix(strings.ToUpper(ch.algorithm), "-SESS") {
+		ha1 = digest(ha1, ch.nonce, cnonce)
+	}
+	ha2 := digest(req.Method, uri)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `Digest username=%q, realm=%q, nonce=%q, uri=%q`, user, ch.realm, ch.nonce, uri)
+	if ch.algorithm != "" {
+		fmt.Fprintf(&b, ", algorithm=%s", ch.algorithm)
+	}
+	if ch.qop != "" {
+		fmt.Fprintf(&b, `, qop=auth, nc=%s, cnonce=%q, response=%q`, nc, cnonce, digest(ha1, ch.nonce, nc, cnonce, "auth", ha2))
+	} else {
+		fmt.Fprintf(&b, `, response=%q`, digest(ha1, ch.nonce, ha2))
+	}
+	if ch.opaque != "" {
+		fmt.Fprintf(&b, ", opaque=%q", ch.opaque)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", b.String())
+	return req, ch.nonce
+}
+
+// parseDigestChallenge returns the first Digest challenge in h with a
+// supported algorithm and qop, or nil.
+func parseDigestChallenge(h http.Header) *digestChallenge {
+	for _, v := range h.Values("Www-Authenticate") {
+		if len(v) < 7 || !strings.EqualFold(v[:7], "Digest ") {
+			continue
+		}
+		ch := &digestChallenge{}
+		qop := ""
+		for s := v[7:]; s != ""; {
+			var name, value string
+			name, value, s = linkParam(s)
+			s = strings.TrimLeft(s, " \t,;")
+			switch strings.ToLower(name) {
+			case "realm":
+				ch.realm = value
+			case "nonce":
+				ch.nonce = value
+			case "opaque":
+				ch.opaque = value
+			case "algorithm":
+				ch.algorithm = value
+			case "qop":
+				qop = value
+			case "stale":
+				ch.stale = strings.EqualFold(value, "true")
+			}
+		}
+		switch strings.TrimSuffix(strings.ToUpper(ch.algorithm), "-SESS") {
+		case "", "MD5", "SHA-256":
+		default:
+			continue
+		}
+		if qop != "" {
+			for _, q := range strings.Split(qop, ",") {
+				if strings.TrimSpace(q) == "auth" {
+					ch.qop = "auth"
+				}
+			}
+			if ch.qop == "" {
+				continue
+			}
+		}
+		if ch.nonce != "" {
+			return ch
+		}
+	}
+	return nil
+}
+
+func newCnonce() string {
+	var p [16]byte
+	rand.Read(p[:])
+	return hex.EncodeToString(p[:])
+}
@@ -0,0 +1,182 @@
+package httpclient
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// digestHandler requires Digest authentication of user and password with
+// algorithm and qop, checking the response parameter against its own
+// implementation of RFC 7616. A request authorized with an older nonce
+// gets a stale=true challenge.
+type digestHandler struct {
+	user, password, algorithm, qop string
+
+	mu         sync.Mutex
+	nonce      string
+	challenges int
+}
+
+const digestRealm, digestOpaque = "test", "5ccc069c403ebaf9f0171e9517f40e41"
+
+func newDigestHandler(user, password, algorithm, qop string) *digestHandler {
+	return &digestHandler{user: user, password: password, algorithm: algorithm, qop: qop, nonce: "dcd98b7102dd2f0e8b11d0f600bfb0c093"}
+}
+
+// rotate changes the nonce, making the one in use stale.
+func (h *digestHandler) rotate() {
+	h.mu.Lock()
+	h.nonce += "x"
+	h.mu.Unlock()
+}
+
+func (h *digestHandler) challenge(w http.ResponseWriter, stale bool) {
+	h.challenges++
+	ch := fmt.Sprintf(`Digest realm=%q, nonce=%q, opaque=%q`, digestRealm, h.nonce, digestOpaque)
+	if h.algorithm != "" {
+		ch += ", algorithm=" + h.algorithm
+	}
+	if h.qop != "" {
+		ch += fmt.Sprintf(", qop=%q", h.qop)
+	}
+	if stale {
+		ch += ", stale=true"
+	}
+	w.Header().Set("WWW-Authenticate", ch)
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+func (h *digestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Digest ") {
+		h.challenge(w, false)
+		return
+	}
+	p := map[string]string{}
+	for s := auth[7:]; s != ""; {
+		var name, value string
+		name, value, s = linkParam(s)
+		s = strings.TrimLeft(s, " \t,;")
+		p[name] = value
+	}
+	newHash := md5.New
+	if strings.HasPrefix(strings.ToUpper(h.algorithm), "SHA-256") {
+		newHash = func() hash.Hash { return sha256.New() }
+	}
+	digest := func(parts ...string) string {
+		d := newHash()
+		io.WriteString(d, strings.Join(parts, ":"))
+		return hex.EncodeToString(d.Sum(nil))
+	}
+	nonce := p["nonce"]
+	ha1 := digest(h.user, digestRealm, h.password)
+	if strings.HasSuffix(strings.ToUpper(h.algorithm), "-SESS") {
+		ha1 = digest(ha1, nonce, p["cnonce"])
+	}
+	ha2 := digest(r.Method, r.URL.RequestURI())
+	want := digest(ha1, nonce, ha2)
+	if h.qop != "" {
+		want = digest(ha1, nonce, p["nc"], p["cnonce"], p["qop"], ha2)
+	}
+	if p["username"] != h.user || p["uri"] != r.URL.RequestURI() || p["opaque"] != digestOpaque || p["response"] != want {
+		h.challenge(w, false)
+		return
+	}
+	if nonce != h.nonce {
+		h.challenge(w, true)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	fmt.Fprintf(w, "ok %s", body)
+}
+
+func TestSetDigestAuth(t *testing.T) {
+	for _, tt := range []struct{ algorithm, qop string }{
+		{"", ""},
+		{"MD5", "auth"},
+		{"MD5-sess", "auth"},
+		{"SHA-256", "auth,auth-int"},
+		{"SHA-256-sess", "auth"},
+	} {
+		t.Run(tt.algorithm+"/"+tt.qop, func(t *testing.T) {
+			h := newDigestHandler("alice", "secret", tt.algorithm, tt.qop)
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+			c := New()
+			c.SetDigestAuth("alice", "secret")
+			for i := 0; i < 3; i++ {
+				got, err := c.String(srv.URL + "/dir/index.html?x=1")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != "ok " {
+					t.Errorf("got %q", got)
+				}
+			}
+			if h.challenges != 1 {
+				t.Errorf("got %d challenges, want 1", h.challenges)
+			}
+		})
+	}
+}
+
+func TestSetDigestAuthStale(t *testing.T) {
+	h := newDigestHandler("alice", "secret", "SHA-256", "auth")
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	c := New()
+	c.SetDigestAuth("alice", "secret")
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			h.rotate()
+		}
+		got, err := c.String(srv.URL)
+		if err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+		if got != "ok " {
+			t.Errorf("request %d: got %q", i+1, got)
+		}
+	}
+	if h.challenges != 2 {
+		t.Errorf("got %d challenges, want 2", h.challenges)
+	}
+}
+
+func TestSetDigestAuthWrongPassword(t *testing.T) {
+	srv := httptest.NewServer(newDigestHandler("alice", "secret", "MD5", "auth"))
+	defer srv.Close()
+	c := New()
+	c.SetDigestAuth("alice", "wrong")
+	if _, err := c.String(srv.URL); err == nil {
+		t.Fatal("got no error")
+	}
+}
+
+func TestSetDigestAuthPostAndClone(t *testing.T) {
+	srv := httptest.NewServer(newDigestHandler("alice", "secret", "MD5", "auth"))
+	defer srv.Close()
+	c := New()
+	c.SetDigestAuth("alice", "secret")
+	d := c.Clone(WithTransport(http.DefaultTransport))
+	resp, err := d.Post(srv.URL, "text/plain", strings.NewReader("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(got) != "ok body" {
+		t.Errorf("got %q, want %q", got, "ok body")
+	}
+}
@@ -0,0 +1,152 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// A ProgressFunc is told, as a download goes, how many bytes of url have
+// been written so far out of total, the Content-Length of the response, or
+// -1 if it is unknown.
+type ProgressFunc func(url string, written, total int64)
+
+// WithProgress calls fn after every write of DownloadTo.
+func WithProgress(fn ProgressFunc) RequestOption {
+	return func(o *requestOptions) {
+		o.progress = fn
+	}
+}
+
+// DownloadTo downloads urls concurrently into files in dir, streaming each
+// response body to disk instead of holding it in memory, and returns their
+// paths in the order of urls.
+//
+// A file is named after the Content-Disposition header of its response or,
+// without one, after the last element of the URL path. When a file of that
+// name exists, a suffix is added to the name, e.g. "report-1.pdf", so no
+// file is ever overwritten. The file of a failed download is removed and
+// its path left empty; DownloadTo then returns the paths of the others with
+// a *BatchError, as Files does.
+func (c *httpClient) DownloadTo(urls []string, dir string, opts ...RequestOption) ([]string, error) {
+	o := newRequestOptions(opts)
+	paths := make([]string, len(urls))
+	b := c.batch(urls, o, func(i int, url string) error {
+		p, err := c.downloadTo(url, dir, o)
+		paths[i] = p
+		return err
+	})
+	return paths, b.err(urls, o)
+}
+
+// downloadTo downloads url into a new file in dir and returns its path.
+func (c *httpClient) downloadTo(url, dir string, o *requestOptions) (string, error) {
+	resp, err := c.send("GET", url, nil, o)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if !c.accepted(resp, o) {
+		return "", c.err(resp, "")
+	}
+	if c.maxBody > 0 && resp.ContentLength > c.maxBody {
+		return "", c.tooLarge(resp)
+	}
+	f, err := createUnique(dir, downloadName(resp))
+	if err != nil {
+		return "", err
+	}
+	r := io.Reader(resp.Body)
+	if c.maxBody > 0 {
+		r = io.LimitReader(r, c.maxBody+1)
+	}
+	w := io.Writer(f)
+	if o.progress != nil {
+		w = &progressWriter{w: f, url: url, total: resp.ContentLength, fn: o.progress}
+	}
+	n, err := io.Copy(w, r)
+	if err == nil && c.maxBody > 0 && n > c.maxBody {
+		err = c.tooLarge(resp)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		switch err.(type) {
+		case *Error, *IntegrityError, *os.PathError:
+			return "", err
+		}
+		return "", c.bodyErr(resp, err)
+	}
+	return f.Name(), nil
+}
+
+// downloadName returns the name of the file of resp, from its
+// Content-Disposition header or its URL.
+func downloadName(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if name := safeName(params["filename"]); name != "" {
+			return name
+		}
+	}
+	if name := safeName(path.Base(resp.Request.URL.Path)); name != "" {
+		return name
+	}
+	return "download"
+}
+
+// safeName returns the last element of name, or "" if there is none that
+// can be used as a file name.
+func safeName(name string) string {
+	name = strings.TrimSpace(name[strings.LastIndexAny(name, `/\`)+1:])
+	switch name {
+	case "", ".", "..":
+		return ""
+	}
+	return name
+}
+
+// createUnique creates a new file in dir named name, or, if there is one,
+// name with the first free numeric suffix.
+func createUnique(dir, name string) (*os.File, error) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for i := 0; ; i++ {
+		p := filepath.Join(dir, name)
+		if i > 0 {
+			p = filepath.Join(dir, fmt.Sprintf("%s-%d%s", stem, i, ext))
+		}
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if !errors.Is(err, os.ErrExist) {
+			return f, err
+		}
+	}
+}
+
+// progressWriter reports the bytes written through it to fn.
+type progressWriter struct {
+	w       io.Writer
+	url     string
+	written int64
+	total   int64
+	fn      ProgressFunc
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	p.fn(p.url, p.written, p.total)
+	return n, err
+}
+
+// DownloadTo downloads urls into files in dir with the default client.
+func DownloadTo(urls []string, dir string, opts ...RequestOption) ([]string, error) {
+	return defaultClient().DownloadTo(urls, dir, opts...)
+}
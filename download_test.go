@@ -0,0 +1,217 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// downloadServer serves files named by their Content-Disposition or their
+// path. /broken breaks off its body, and /stall sends some of its body and
+// waits for the client to go.
+func downloadServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cd":
+			w.Header().Set("Content-Disposition", `attachment; filename="notes.txt"`)
+		case "/traversal":
+			w.Header().Set("Content-Disposition", `attachment; filename="../../etc/passwd"`)
+		case "/backslash":
+			w.Header().Set("Content-Disposition", `attachment; filename="..\\..\\evil.txt"`)
+		case "/dotdot":
+			w.Header().Set("Content-Disposition", `attachment; filename=".."`)
+		case "/broken":
+			w.Header().Set("Content-Length", "100")
+			io.WriteString(w, "only ten b")
+			return
+		case "/stall":
+			io.WriteString(w, strings.Repeat("x", 1000))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		case "/missing":
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, "contents of "+r.URL.Path)
+	}))
+}
+
+// dirFiles returns the names and contents of the files in dir.
+func dirFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	for _, name := range spilled(t, dir) {
+		p, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = string(p)
+	}
+	return files
+}
+
+func TestDownloadTo(t *testing.T) {
+	srv := downloadServer()
+	defer srv.Close()
+	root := t.TempDir()
+	dir := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path, name string
+	}{
+		{"/files/report.pdf", "report.pdf"},
+		{"/cd", "notes.txt"},
+		{"/traversal", "passwd"},
+		{"/backslash", "evil.txt"},
+		{"/dotdot", "dotdot"},
+		{"/", "download"},
+	}
+	var urls []string
+	for _, tt := range tests {
+		urls = append(urls, srv.URL+tt.path)
+	}
+	paths, err := DownloadTo(urls, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tt := range tests {
+		if want := filepath.Join(dir, tt.name); paths[i] != want {
+			t.Errorf("%s: got %s, want %s", tt.path, paths[i], want)
+		}
+	}
+	if got := dirFiles(t, dir)["passwd"]; got != "contents of /traversal" {
+		t.Errorf("got passwd %q", got)
+	}
+	// Nothing was written outside dir.
+	if names := spilled(t, root); len(names) != 1 || names[0] != "a" {
+		t.Errorf("got %v in the parent directories", names)
+	}
+	if names := spilled(t, filepath.Join(root, "a")); len(names) != 1 {
+		t.Errorf("got %v in the parent directories", names)
+	}
+}
+
+func TestDownloadToCollisions(t *testing.T) {
+	srv := downloadServer()
+	defer srv.Close()
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("mine"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	urls := []string{srv.URL + "/r/report.pdf", srv.URL + "/s/report.pdf", srv.URL + "/t/report.pdf", srv.URL + "/cd", srv.URL + "/noext", srv.URL + "/noext"}
+	paths, err := DownloadTo(urls, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, len(paths))
+	for i, p := range paths {
+		got[i] = filepath.Base(p)
+	}
+	sort.Strings(got[:3])
+	sort.Strings(got[4:])
+	if want := "report-1.pdf report-2.pdf report.pdf notes-1.txt noext noext-1"; strings.Join(got, " ") != want {
+		t.Errorf("got %s, want %s", strings.Join(got, " "), want)
+	}
+	files := dirFiles(t, dir)
+	if files["notes.txt"] != "mine" || files["notes-1.txt"] != "contents of /cd" || len(files) != 7 {
+		t.Errorf("got files %v", files)
+	}
+	// Each path holds the body of its own URL.
+	for i, p := range paths {
+		want := "contents of " + strings.TrimPrefix(urls[i], srv.URL)
+		if got := files[filepath.Base(p)]; got != want {
+			t.Errorf("%s: got %q, want %q", urls[i], got, want)
+		}
+	}
+}
+
+func TestDownloadToFailures(t *testing.T) {
+	srv := downloadServer()
+	defer srv.Close()
+	dir := t.TempDir()
+
+	urls := []string{srv.URL + "/ok", srv.URL + "/broken", srv.URL + "/missing"}
+	paths, err := DownloadTo(urls, dir)
+	var berr *BatchError
+	if !errors.As(err, &berr) || len(berr.Errors) != 2 {
+		t.Fatalf("got %v, want a *BatchError of 2", err)
+	}
+	if berr.Errors[0].Index != 1 || berr.Errors[1].StatusCode != http.StatusNotFound {
+		t.Errorf("got errors %v", berr)
+	}
+	if paths[0] != filepath.Join(dir, "ok") || paths[1] != "" || paths[2] != "" {
+		t.Errorf("got paths %q", paths)
+	}
+	// The partial file of /broken is gone.
+	if names := spilled(t, dir); len(names) != 1 || names[0] != "ok" {
+		t.Errorf("got files %v", names)
+	}
+
+	// So is the file of a download cancelled halfway.
+	ctx, cancel := context.WithCancel(context.Background())
+	var once sync.Once
+	paths, err = DownloadTo([]string{srv.URL + "/stall"}, dir, WithContext(ctx), WithProgress(func(url string, written, total int64) {
+		once.Do(cancel)
+	}))
+	if !errors.Is(err, context.Canceled) || paths[0] != "" {
+		t.Errorf("cancelled: got %q, %v", paths, err)
+	}
+	if names := spilled(t, dir); len(names) != 1 {
+		t.Errorf("cancelled: got files %v", names)
+	}
+}
+
+func TestDownloadToProgress(t *testing.T) {
+	body := strings.Repeat("0123456789", 10000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/known" {
+			w.Header().Set("Content-Length", "100000")
+		}
+		for i := 0; i < len(body); i += 10000 {
+			io.WriteString(w, body[i:i+10000])
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		path  string
+		total int64
+	}{{"/known", 100000}, {"/unknown", -1}} {
+		var (
+			mu    sync.Mutex
+			calls int
+			last  int64
+		)
+		url := srv.URL + tt.path
+		_, err := DownloadTo([]string{url}, t.TempDir(), WithProgress(func(u string, written, total int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			if u != url || total != tt.total || written <= last {
+				t.Errorf("%s: got %s, %d of %d after %d", tt.path, u, written, total, last)
+			}
+			last = written
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if calls < 2 || last != int64(len(body)) {
+			t.Errorf("%s: got %d calls, %d bytes written", tt.path, calls, last)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Client is the interface implemented by the clients of this package. Code
+// that depends on it rather than on the value returned by New can be tested
+// with a FakeClient.
+type Client interface {
+	Get(url string, opts ...RequestOption) (*http.Response, error)
+	Bytes(url string, opts ...RequestOption) ([]byte, error)
+	String(url string, opts ...RequestOption) (string, error)
+	Reader(url string, opts ...RequestOption) (io.ReadCloser, error)
+	JSON(url string, v interface{}, opts ...RequestOption) error
+	XML(url string, v interface{}, opts ...RequestOption) error
+	Files(urls []string, files *[]File, opts ...RequestOption) error
+	Download(urls []string, files *[]File, opts ...RequestOption) error
+}
+
+var (
+	_ Client = (*httpClient)(nil)
+	_ Client = (*FakeClient)(nil)
+)
+
+// A FakeClient is a Client that answers requests with programmed responses
+// instead of going to the network, and records them for later assertions.
+// Since the responses go through the same code as real ones, status checks
+// and decoding behave exactly as they would against a server.
+//
+//	fake := httpclient.NewFake()
+//	fake.On("GET", "http://api.example.com/users/42").ReturnJSON(user)
+//	fake.On("GET", "http://api.example.com/users/43").ReturnStatus(404)
+//
+// Requests without a programmed response fail. The embedded
+// RequestRecorder provides assertions about the requests received.
+type FakeClient struct {
+	*httpClient
+	*RequestRecorder
+
+	mu        sync.Mutex
+	responses map[string]*FakeResponse
+}
+
+// A RecordedCall is a request received by a FakeClient.
+type RecordedCall struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// NewFake returns a FakeClient with no programmed responses.
+func NewFake(opts ...Option) *FakeClient {
+	f := &FakeClient{responses: make(map[string]*FakeResponse)}
+	f.RequestRecorder = NewRequestRecorder(fakeTransport{f})
+	f.httpClient = NewWithTransport(f.RequestRecorder, opts...)
+	return f
+}
+
+// On programs the response to requests with the method and URL. By default
+// it is an empty 200 response.
+func (f *FakeClient) On(method, url string) *FakeResponse {
+	r := &FakeResponse{status: http.StatusOK, header: make(http.Header)}
+	f.mu.Lock()
+	f.responses[method+" "+url] = r
+	f.mu.Unlock()
+	return r
+}
+
+// Calls returns the requests received so far, in the order they were
+// received.
+func (f *FakeClient) Calls() []RecordedCall {
+	return f.Requests()
+}
+
+// A FakeResponse is the programmed response of a FakeClient.
+type FakeResponse struct {
+	mu     sync.Mutex
+	status int
+	header http.Header
+	body   []byte
+	err    error
+}
+
+// ReturnStatus sets the status code of the response.
+func (r *FakeResponse) ReturnStatus(code int) *FakeResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = code
+	return r
+}
+
+// ReturnHeader adds a header to the response.
+func (r *FakeResponse) ReturnHeader(key, value string) *FakeResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.header.Add(key, value)
+	return r
+}
+
+// ReturnBytes sets the body of the response.
+func (r *FakeResponse) ReturnBytes(body []byte) *FakeResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.body = body
+	return r
+}
+
+// ReturnString sets the body of the response.
+func (r *FakeResponse) ReturnString(body string) *FakeResponse {
+	return r.ReturnBytes([]byte(body))
+}
+
+// ReturnJSON sets the body of the response to v encoded as JSON. If v can
+// not be encoded, requests fail with the encoding error.
+func (r *FakeResponse) ReturnJSON(v interface{}) *FakeResponse {
+	p, err := json.Marshal(v)
+	if err != nil {
+		return r.ReturnError(err)
+	}
+	return r.ReturnHeader("Content-Type", "application/json").ReturnBytes(p)
+}
+
+// ReturnXML sets the body of the response to v encoded as XML. If v can not
+// be encoded, requests fail with the encoding error.
+func (r *FakeResponse) ReturnXML(v interface{}) *FakeResponse {
+	p, err := xml.Marshal(v)
+	if err != nil {
+		return r.ReturnError(err)
+	}
+	return r.ReturnHeader("Content-Type", "application/xml").ReturnBytes(p)
+}
+
+// ReturnError makes requests fail with err, as if the server could not be
+// reached.
+func (r *FakeResponse) ReturnError(err error) *FakeResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+	return r
+}
+
+func (r *FakeResponse) response(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	return newResponse(req, r.status, r.header.Clone(), r.body), nil
+}
+
+type fakeTransport struct {
+	f *FakeClient
+}
+
+func (t fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.f.mu.Lock()
+	r, ok := t.f.responses[req.Method+" "+req.URL.String()]
+	t.f.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("httpclient: no fake response for %s %s", req.Method, req.URL)
+	}
+	return r.response(req)
+}
@@ -0,0 +1,99 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// userName stands for code that depends on a Client.
+func userName(c Client, url string) (string, error) {
+	var u user
+	if err := c.JSON(url, &u, WithRequestHeader("Authorization", "Bearer t")); err != nil {
+		return "", err
+	}
+	return u.Name, nil
+}
+
+func TestFakeClientJSON(t *testing.T) {
+	fake := NewFake()
+	fake.On("GET", "http://api.example.com/users/42").ReturnJSON(user{ID: 42, Name: "Ada"})
+	fake.On("GET", "http://api.example.com/users/43").ReturnStatus(404)
+	fake.On("GET", "http://api.example.com/users/44").ReturnString("{oops}").ReturnHeader("Content-Type", "application/json")
+
+	name, err := userName(fake, "http://api.example.com/users/42")
+	if err != nil || name != "Ada" {
+		t.Fatalf("got %q, %v", name, err)
+	}
+	if _, err := userName(fake, "http://api.example.com/users/43"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+	var e *Error
+	if _, err := userName(fake, "http://api.example.com/users/44"); !errors.As(err, &e) || !strings.Contains(e.Message, "JSON") {
+		t.Errorf("got %v, want a decoding error", err)
+	}
+	if _, err := userName(fake, "http://api.example.com/users/45"); err == nil || !strings.Contains(err.Error(), "no fake response") {
+		t.Errorf("got %v for a request without a programmed response", err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 4 {
+		t.Fatalf("got %d calls, want 4", len(calls))
+	}
+	for i, c := range calls {
+		want := fmt.Sprintf("http://api.example.com/users/%d", 42+i)
+		if c.Method != "GET" || c.URL != want || c.Header.Get("Authorization") != "Bearer t" {
+			t.Errorf("call %d: got %s %s %v", i, c.Method, c.URL, c.Header)
+		}
+	}
+	fake.AssertRequested(t, "GET", "http://api.example.com/users/*")
+	fake.AssertNumberOfCalls(t, "*/users/42", 1)
+	fake.AssertHeader(t, "*/users/42", "Authorization", "Bearer ")
+}
+
+func TestFakeClientFiles(t *testing.T) {
+	fake := NewFake()
+	fake.On("GET", "http://cdn.example.com/a.txt").ReturnString("a").ReturnHeader("Content-Type", "text/plain")
+	fake.On("GET", "http://cdn.example.com/b.txt").ReturnStatus(503)
+	fake.On("GET", "http://cdn.example.com/c.txt").ReturnError(errors.New("connection reset"))
+
+	urls := []string{"http://cdn.example.com/a.txt", "http://cdn.example.com/b.txt", "http://cdn.example.com/c.txt"}
+	var files []File
+	err := fake.Files(urls, &files, WithPartialResults())
+	var be *BatchError
+	if !errors.As(err, &be) || len(be.Errors) != 2 {
+		t.Fatalf("got %v, want two failures", err)
+	}
+	if be.Errors[0].Index != 1 || be.Errors[0].StatusCode != 503 {
+		t.Errorf("got %+v for b.txt", be.Errors[0])
+	}
+	if be.Errors[1].Index != 2 || !strings.Contains(be.Errors[1].Error(), "connection reset") {
+		t.Errorf("got %+v for c.txt", be.Errors[1])
+	}
+	if len(files) != 3 || string(files[0].Data) != "a" || files[0].ContentType != "text/plain" {
+		t.Errorf("got files %+v", files)
+	}
+	fake.AssertNumberOfCalls(t, "http://cdn.example.com/*", 3)
+}
+
+func TestFakeClientPost(t *testing.T) {
+	fake := NewFake()
+	fake.On("POST", "http://api.example.com/users").ReturnStatus(201).ReturnJSON(user{ID: 1, Name: "Ada"})
+	var got user
+	if err := fake.PostJSON("http://api.example.com/users", user{Name: "Ada"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 1 {
+		t.Errorf("got %+v", got)
+	}
+	if calls := fake.Calls(); len(calls) != 1 || string(calls[0].Body) != `{"id":0,"name":"Ada"}` {
+		t.Errorf("got calls %+v", calls)
+	}
+	fake.AssertJSONBody(t, "*/users", `{"name":"Ada"}`)
+}
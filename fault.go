@@ -0,0 +1,199 @@
+package httpclient
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// A Fault is a failure injected by a FaultTransport.
+type Fault struct {
+	kind   faultKind
+	status int
+	n      int64
+	delay  time.Duration
+}
+
+type faultKind int
+
+const (
+	faultNone faultKind = iota
+	faultRefuse
+	faultTimeout
+	faultTruncate
+	faultStatus
+	faultMalformedJSON
+	faultSlowFirstByte
+)
+
+// FaultNone lets the request through untouched. It is useful in scripts,
+// e.g. to fail every other request.
+func FaultNone() Fault {
+	return Fault{kind: faultNone}
+}
+
+// FaultRefuse fails the request as if the connection had been refused.
+func FaultRefuse() Fault {
+	return Fault{kind: faultRefuse}
+}
+
+// FaultTimeout holds the request until its context is done, which makes it
+// run into the client's timeout or the caller's deadline.
+func FaultTimeout() Fault {
+	return Fault{kind: faultTimeout}
+}
+
+// FaultTruncate cuts the response body off after n bytes with
+// io.ErrUnexpectedEOF.
+func FaultTruncate(n int64) Fault {
+	return Fault{kind: faultTruncate, n: n}
+}
+
+// FaultStatus answers the request with an empty response with the status
+// code, without sending it.
+func FaultStatus(code int) Fault {
+	return Fault{kind: faultStatus, status: code}
+}
+
+// FaultMalformedJSON answers the request with a 200 response whose JSON
+// body is cut in the middle, without sending it.
+func FaultMalformedJSON() Fault {
+	return Fault{kind: faultMalformedJSON}
+}
+
+// FaultSlowFirstByte delays the response by d.
+func FaultSlowFirstByte(d time.Duration) Fault {
+	return Fault{kind: faultSlowFirstByte, delay: d}
+}
+
+// A FaultTransport wraps a RoundTripper and injects faults into the
+// requests whose URL matches a pattern, either following a script or at
+// random. It composes with any transport, stubs and real servers alike:
+//
+//	faults := httpclient.NewFaultTransport(nil).
+//		Script("https://api.example.com/*", httpclient.FaultStatus(503), httpclient.FaultRefuse())
+//
+// fails the first two requests to api.example.com and lets the next ones
+// through. URL patterns are globs as in StubTransport.Handle; the first
+// rule with a fault left to inject wins.
+type FaultTransport struct {
+	next http.RoundTripper
+
+	mu    sync.Mutex
+	rules []*faultRule
+	rand  *rand.Rand
+}
+
+type faultRule struct {
+	re          *regexp.Regexp
+	script      []Fault
+	probability float64
+	fault       Fault
+}
+
+// NewFaultTransport returns a FaultTransport that sends requests with next,
+// or with http.DefaultTransport if next is nil.
+func NewFaultTransport(next http.RoundTripper) *FaultTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &FaultTransport{next: next, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Script injects faults, one per request, into the requests to URLs
+// matching pattern. Once the script is exhausted, requests go through.
+func (t *FaultTransport) Script(pattern string, faults ...Fault) *FaultTransport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rules = append(t.rules, &faultRule{re: globRegexp(pattern), script: faults})
+	return t
+}
+
+// Randomly injects f into requests to URLs matching pattern with the given
+// probability, between 0 and 1.
+func (t *FaultTransport) Randomly(pattern string, probability float64, f Fault) *FaultTransport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rules = append(t.rules, &faultRule{re: globRegexp(pattern), probability: probability, fault: f})
+	return t
+}
+
+// Seed makes the random faults reproducible.
+func (t *FaultTransport) Seed(seed int64) *FaultTransport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rand = rand.New(rand.NewSource(seed))
+	return t
+}
+
+func (t *FaultTransport) pick(u string) Fault {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, r := range t.rules {
+		if !r.re.MatchString(u) {
+			continue
+		}
+		if len(r.script) > 0 {
+			f := r.script[0]
+			r.script = r.script[1:]
+			return f
+		}
+		if r.probability > 0 && t.rand.Float64() < r.probability {
+			return r.fault
+		}
+	}
+	return FaultNone()
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f := t.pick(req.URL.String())
+	switch f.kind {
+	case faultRefuse:
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	case faultTimeout:
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	case faultStatus:
+		return newResponse(req, f.status, nil, nil), nil
+	case faultMalformedJSON:
+		h := make(http.Header)
+		h.Set("Content-Type", "application/json")
+		return newResponse(req, http.StatusOK, h, []byte(`{"id": 42, "name": "trunc`)), nil
+	case faultSlowFirstByte:
+		if err := sleep(req.Context(), f.delay); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || f.kind != faultTruncate {
+		return resp, err
+	}
+	resp.Body = &truncatedBody{ReadCloser: resp.Body, left: f.n}
+	return resp, nil
+}
+
+// truncatedBody fails with io.ErrUnexpectedEOF once left bytes have been
+// read, unless the real body ends first.
+type truncatedBody struct {
+	io.ReadCloser
+	left int64
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.left <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if int64(len(p)) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.left -= int64(n)
+	return n, err
+}
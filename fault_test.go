@@ -0,0 +1,119 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFaultFailTwiceThenSucceed(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		maxAttempts int
+		ok          bool
+		calls       int
+	}{
+		{"enough attempts", 3, true, 1},
+		{"too few attempts", 2, false, 0},
+	} {
+		stub := NewStub().Handle("GET", "*", Stub(200, "ok"))
+		faults := NewFaultTransport(stub).Script("https://api.example.com/*", FaultStatus(503), FaultRefuse())
+		logs := &recordLogger{}
+		c := NewWithTransport(faults, WithRetry(tt.maxAttempts, time.Millisecond), WithLogger(logs))
+
+		s, err := c.String("https://api.example.com/data")
+		if tt.ok {
+			if err != nil || s != "ok" {
+				t.Errorf("%s: got %q, %v", tt.name, s, err)
+			}
+		} else {
+			var e *Error
+			if !errors.As(err, &e) || e.Attempts != 2 || !errors.Is(err, syscall.ECONNREFUSED) {
+				t.Errorf("%s: got %v, want the refused connection after 2 attempts", tt.name, err)
+			}
+		}
+		// The faults never reach the inner transport.
+		if got := stub.Calls("GET", "*"); got != tt.calls {
+			t.Errorf("%s: the stub got %d requests, want %d", tt.name, got, tt.calls)
+		}
+		retries := 0
+		for _, e := range logs.entries {
+			if e.msg == "request retry" {
+				retries++
+			}
+		}
+		if retries != tt.maxAttempts-1 {
+			t.Errorf("%s: got %d retries, want %d", tt.name, retries, tt.maxAttempts-1)
+		}
+	}
+}
+
+func TestFaults(t *testing.T) {
+	stub := NewStub().Handle("GET", "*", Stub(200, `{"id": 42}`).WithHeader("Content-Type", "application/json"))
+	faults := NewFaultTransport(stub).
+		Script("*/timeout", FaultTimeout()).
+		Script("*/truncate", FaultTruncate(4)).
+		Script("*/malformed", FaultMalformedJSON()).
+		Script("*/slow", FaultSlowFirstByte(30*time.Millisecond)).
+		Script("*/teapot", FaultStatus(418), FaultNone(), FaultStatus(418))
+	c := NewWithTransport(faults)
+	var v struct{ ID int }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.Bytes("https://api.example.com/timeout", WithContext(ctx)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("timeout: got %v", err)
+	}
+	if _, err := c.Bytes("https://api.example.com/truncate"); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("truncate: got %v", err)
+	}
+	if err := c.JSON("https://api.example.com/malformed", &v); err == nil {
+		t.Error("malformed: got no error")
+	}
+	start := time.Now()
+	if err := c.JSON("https://api.example.com/slow", &v); err != nil || v.ID != 42 {
+		t.Errorf("slow: got %v, %v", v, err)
+	}
+	if d := time.Since(start); d < 30*time.Millisecond {
+		t.Errorf("slow: answered after %v", d)
+	}
+	for i, want := range []int{418, 200, 418, 200} {
+		_, r, err := c.BytesResponse("https://api.example.com/teapot", WithAcceptStatus(418))
+		if err != nil || r.StatusCode != want {
+			t.Errorf("teapot %d: got %v, %v, want %d", i, r, err, want)
+		}
+	}
+	// Exhausted scripts let requests through.
+	if err := c.JSON("https://api.example.com/malformed", &v); err != nil {
+		t.Errorf("after the script: %v", err)
+	}
+}
+
+func TestFaultRandomly(t *testing.T) {
+	run := func(seed int64) []int {
+		stub := NewStub().Handle("GET", "*", Stub(200, ""))
+		c := NewWithTransport(NewFaultTransport(stub).Seed(seed).Randomly("*", 0.3, FaultStatus(500)))
+		var statuses []int
+		for i := 0; i < 200; i++ {
+			_, r, _ := c.BytesResponse("https://api.example.com/", WithAcceptStatus(500))
+			statuses = append(statuses, r.StatusCode)
+		}
+		return statuses
+	}
+	a, b := run(1), run(1)
+	failed := 0
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("request %d: got %d and %d with the same seed", i, a[i], b[i])
+		}
+		if a[i] == 500 {
+			failed++
+		}
+	}
+	if failed < 30 || failed > 90 {
+		t.Errorf("got %d faults out of 200 at a probability of 0.3", failed)
+	}
+}
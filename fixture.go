@@ -0,0 +1,121 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FixtureResponse returns a stub response with the contents of the file at
+// path as its body, for use with StubTransport. The Content-Type is
+// inferred from the file extension. An optional sidecar file, path with
+// ".headers" appended, holds "Name: value" lines of extra headers; a
+// "Status" line sets the status code, which is 200 by default.
+//
+// The test fails immediately if the fixture can not be read.
+func FixtureResponse(t TestingT, path string) *StubResponse {
+	t.Helper()
+	r, err := loadFixture(path)
+	if err != nil {
+		t.Fatalf("httpclient: fixture: %v", err)
+	}
+	return r
+}
+
+// StubFromDir returns a RoundTripper that answers requests with fixtures
+// from the directory tree at dir, mapping request paths to files: GET
+// /users/42 is answered with dir/users/42, or, failing that, the first file
+// dir/users/42.* (such as dir/users/42.json) in lexical order. A request
+// for a directory looks for an index file in it. Fixtures are read as by
+// FixtureResponse.
+//
+// Requests without a fixture fail with an error naming the files looked
+// for.
+func StubFromDir(dir string) http.RoundTripper {
+	return dirTransport{dir}
+}
+
+type dirTransport struct {
+	dir string
+}
+
+func (t dirTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p := path.Clean("/" + req.URL.Path)
+	if strings.HasSuffix(req.URL.Path, "/") {
+		p = path.Join(p, "index")
+	}
+	name := filepath.Join(t.dir, filepath.FromSlash(p))
+	if fi, err := os.Stat(name); err != nil || fi.IsDir() {
+		matches, _ := filepath.Glob(name + ".*")
+		name = ""
+		for _, m := range matches {
+			if !strings.HasSuffix(m, ".headers") {
+				name = m
+				break
+			}
+		}
+	}
+	if name == "" {
+		tried := filepath.Join(t.dir, filepath.FromSlash(p))
+		return nil, fmt.Errorf("httpclient: no fixture for %s %s (looked for %s and %s.*)", req.Method, req.URL.Path, tried, tried)
+	}
+	r, err := loadFixture(name)
+	if err != nil {
+		return nil, err
+	}
+	return r.response(req)
+}
+
+// loadFixture reads the fixture at name and its sidecar headers file.
+func loadFixture(name string) (*StubResponse, error) {
+	body, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	r := StubBytes(http.StatusOK, body)
+	if ct := mime.TypeByExtension(filepath.Ext(name)); ct != "" {
+		r.header.Set("Content-Type", ct)
+	} else {
+		r.header.Set("Content-Type", http.DetectContentType(body))
+	}
+	headers, err := ioutil.ReadFile(name + ".headers")
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := bufio.NewScanner(bytes.NewReader(headers))
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		i := strings.IndexByte(text, ':')
+		if i < 0 {
+			return nil, fmt.Errorf("%s.headers:%d: expected \"Name: value\"", name, line)
+		}
+		k, v := strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+		if strings.EqualFold(k, "Status") {
+			code, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s.headers:%d: invalid status %q", name, line, v)
+			}
+			r.status = code
+			continue
+		}
+		if strings.EqualFold(k, "Content-Type") {
+			r.header.Del(k)
+		}
+		r.header.Add(k, v)
+	}
+	return r, s.Err()
+}
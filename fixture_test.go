@@ -0,0 +1,109 @@
+package httpclient
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFixtures creates the files, keyed by slash separated path, under a
+// new directory and returns it.
+func writeFixtures(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestStubFromDir(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	dir := writeFixtures(t, map[string]string{
+		"users/42.json":         `{"id":42,"name":"Ada"}`,
+		"users/43.json":         `{"id":43}`,
+		"users/43.json.headers": "# created\nStatus: 201\nX-Rate-Limit: 10\nContent-Type: application/vnd.api+json\n",
+		"images/logo.png":       png,
+		"images/raw":            png,
+		"docs/index.html":       "<h1>docs</h1>",
+		"broken.json":           "{}",
+		"broken.json.headers":   "no colon here\n",
+	})
+	c := NewWithTransport(StubFromDir(dir))
+	base := "https://api.example.com"
+
+	var u struct {
+		ID   int
+		Name string
+	}
+	_, r, err := c.BytesResponse(base + "/users/42")
+	if err != nil || r.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("users/42: got %v, %v", r, err)
+	}
+	if err := c.JSON(base+"/users/42", &u); err != nil || u.Name != "Ada" {
+		t.Errorf("users/42: got %+v, %v", u, err)
+	}
+
+	p, r, err := c.BytesResponse(base+"/users/43", WithAcceptStatus(201))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.StatusCode != 201 || r.Header.Get("X-Rate-Limit") != "10" || string(p) != `{"id":43}` {
+		t.Errorf("users/43: got %d %v %q", r.StatusCode, r.Header, p)
+	}
+	if got := r.Header.Values("Content-Type"); len(got) != 1 || got[0] != "application/vnd.api+json" {
+		t.Errorf("users/43: got Content-Type %q, want the sidecar one only", got)
+	}
+
+	for _, name := range []string{"logo.png", "raw"} {
+		p, r, err := c.BytesResponse(base + "/images/" + name)
+		if err != nil || !bytes.Equal(p, []byte(png)) || r.Header.Get("Content-Type") != "image/png" {
+			t.Errorf("%s: got %q, %v, %v", name, p, r.Header, err)
+		}
+	}
+	if s, err := c.String(base + "/docs/"); err != nil || s != "<h1>docs</h1>" {
+		t.Errorf("docs/: got %q, %v", s, err)
+	}
+
+	_, err = c.Bytes(base + "/users/44")
+	want := "no fixture for GET /users/44 (looked for " + filepath.Join(dir, "users", "44")
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("users/44: got %v, want %q", err, want)
+	}
+	// Paths cannot escape the directory.
+	if _, err := c.Bytes(base + "/../" + filepath.Base(dir) + "/users/42"); err == nil {
+		t.Error("a path outside the directory was served")
+	}
+	if _, err := c.Bytes(base + "/broken"); err == nil || !strings.Contains(err.Error(), `broken.json.headers:1: expected "Name: value"`) {
+		t.Errorf("broken: got %v", err)
+	}
+}
+
+func TestFixtureResponse(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{"user.json": `{"id":1}`})
+
+	ft := &fakeT{}
+	r := FixtureResponse(ft, filepath.Join(dir, "user.json"))
+	if len(ft.errors) != 0 {
+		t.Fatalf("got failures %q", ft.errors)
+	}
+	c := NewWithTransport(NewStub().Handle("GET", "*", r))
+	var v struct{ ID int }
+	if err := c.JSON("https://api.example.com/user", &v); err != nil || v.ID != 1 {
+		t.Errorf("got %+v, %v", v, err)
+	}
+
+	missing := filepath.Join(dir, "missing.json")
+	FixtureResponse(ft, missing)
+	if len(ft.errors) != 1 || !strings.HasPrefix(ft.errors[0], "httpclient: fixture: open "+missing) {
+		t.Errorf("got failures %q", ft.errors)
+	}
+}
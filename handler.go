@@ -0,0 +1,104 @@
+package httpclient
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// NewWithTransport returns a client that sends its requests with rt.
+func NewWithTransport(rt http.RoundTripper, opts ...Option) *httpClient {
+	return New(append([]Option{WithTransport(rt)}, opts...)...)
+}
+
+// NewForHandler returns a client whose requests are served by h, called
+// directly in the calling goroutine: there is no listener and no network
+// connection, which makes it convenient for fast unit tests.
+func NewForHandler(h http.Handler, opts ...Option) *httpClient {
+	return NewWithTransport(HandlerTransport(h), opts...)
+}
+
+// HandlerTransport returns a RoundTripper that serves requests with h
+// instead of sending them over the network.
+func HandlerTransport(h http.Handler) http.RoundTripper {
+	return handlerTransport{h}
+}
+
+type handlerTransport struct {
+	h http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.RequestURI = req.URL.RequestURI()
+	r.RemoteAddr = "192.0.2.1:1234"
+	if r.Host == "" {
+		r.Host = req.URL.Host
+	}
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+	w := &handlerResponse{header: make(http.Header)}
+	t.h.ServeHTTP(w, r)
+	if err := req.Context().Err(); err != nil {
+		// A cancelled request gets no response, even if h wrote one.
+		return nil, err
+	}
+	w.WriteHeader(http.StatusOK)
+	header := w.sent
+	length := int64(w.body.Len())
+	if req.Method == "HEAD" {
+		// As a server would, keep the length the handler announced.
+		length = -1
+		if n, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil {
+			length = n
+		}
+	} else {
+		header.Set("Content-Length", strconv.Itoa(w.body.Len()))
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", w.status, http.StatusText(w.status)),
+		StatusCode:    w.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(&w.body),
+		ContentLength: length,
+		Request:       req,
+	}, nil
+}
+
+// handlerResponse is the http.ResponseWriter handed to handlers by
+// handlerTransport. Like a real server, it takes a snapshot of the header
+// when the status is written.
+type handlerResponse struct {
+	header http.Header
+	sent   http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *handlerResponse) Header() http.Header {
+	return w.header
+}
+
+func (w *handlerResponse) WriteHeader(status int) {
+	if w.sent != nil {
+		return
+	}
+	w.status = status
+	w.sent = w.header.Clone()
+}
+
+func (w *handlerResponse) Write(p []byte) (int, error) {
+	if w.sent == nil {
+		if w.header.Get("Content-Type") == "" {
+			w.header.Set("Content-Type", http.DetectContentType(p))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.body.Write(p)
+}
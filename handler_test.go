@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// apiHandler serves /users/<name> as JSON and anything under /files/ as
+// text. It fails requests that come from a network peer.
+func apiHandler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"name": strings.TrimPrefix(r.URL.Path, "/users/")})
+	})
+	mux.HandleFunc("/files/", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "contents of "+r.URL.Path)
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/files/new", http.StatusMovedPermanently)
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.RemoteAddr != "192.0.2.1:1234" {
+			t.Errorf("request from %s", r.RemoteAddr)
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// The .invalid domain never resolves, so requests that succeed did not
+// touch the network.
+const handlerBase = "http://api.invalid"
+
+func TestNewForHandlerJSON(t *testing.T) {
+	c := NewForHandler(apiHandler(t))
+	var v struct{ Name string }
+	if err := c.JSON(handlerBase+"/users/ada", &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "ada" {
+		t.Errorf("got %q", v.Name)
+	}
+	if err := c.JSON(handlerBase+"/nowhere", &v); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+	s, err := c.String(handlerBase + "/old")
+	if err != nil || s != "contents of /files/new" {
+		t.Errorf("redirect: got %q, %v", s, err)
+	}
+}
+
+func TestNewForHandlerFiles(t *testing.T) {
+	c := NewForHandler(apiHandler(t))
+	urls := []string{handlerBase + "/files/a", handlerBase + "/files/b", handlerBase + "/files/c"}
+	var files []File
+	if err := c.Files(urls, &files, WithConcurrency(3)); err != nil {
+		t.Fatal(err)
+	}
+	for i, f := range files {
+		if want := "contents of " + strings.TrimPrefix(urls[i], handlerBase); string(f.Data) != want {
+			t.Errorf("%s: got %q", urls[i], f.Data)
+		}
+		if f.ContentType != "text/plain; charset=utf-8" {
+			t.Errorf("%s: got content type %q", urls[i], f.ContentType)
+		}
+	}
+}
+
+func TestHandlerTransport(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Before", "1")
+		w.WriteHeader(http.StatusAccepted)
+		// Like a real server, headers set after the status are dropped.
+		w.Header().Set("X-After", "1")
+		if r.Method != "HEAD" {
+			io.WriteString(w, "body")
+		}
+	})
+	c := NewWithTransport(HandlerTransport(h))
+
+	resp, err := c.Get(handlerBase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != 202 || string(body) != "body" || resp.ContentLength != 4 {
+		t.Errorf("got %d %q, length %d", resp.StatusCode, body, resp.ContentLength)
+	}
+	if resp.Header.Get("X-Before") != "1" || resp.Header.Get("X-After") != "" {
+		t.Errorf("got header %v", resp.Header)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Get(handlerBase, WithContext(ctx)); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,331 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// harBodyLimit is the number of body bytes kept in a HAR entry.
+const harBodyLimit = 1 << 20
+
+// A HARRecorder records requests and responses in the HTTP Archive 1.2
+// format. Every round trip is an entry of its own, so redirect chains show
+// up as a sequence of entries. Bodies are kept up to 1MB and secrets are
+// redacted; see WithRedaction.
+type HARRecorder struct {
+	mu      sync.Mutex
+	entries []harEntry
+	path    string
+}
+
+// NewHARRecorder returns an empty in-memory recorder. Use it with WithHAR
+// and get the archive with Export.
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{}
+}
+
+// WithHAR records every request made by the client into r.
+func WithHAR(r *HARRecorder) Option {
+	return func(c *httpClient) {
+		c.har = r
+	}
+}
+
+// WithHARRecorder records every request made by the client and writes them
+// to the file at path when the client is flushed or closed.
+func WithHARRecorder(path string) Option {
+	return func(c *httpClient) {
+		c.har = &HARRecorder{path: path}
+	}
+}
+
+// Export returns the recorded entries as a HAR document, ordered by the
+// time the requests started.
+func (r *HARRecorder) Export() ([]byte, error) {
+	r.mu.Lock()
+	entries := make([]harEntry, len(r.entries))
+	copy(entries, r.entries)
+	r.mu.Unlock()
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].started.Before(entries[j].started)
+	})
+	return json.MarshalIndent(harDocument{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "github.com/tamnd/httpclient", Version: "1.0"},
+		Entries: entries,
+	}}, "", "  ")
+}
+
+// Flush writes the archive to the file given to WithHARRecorder. It does
+// nothing for in-memory recorders.
+func (r *HARRecorder) Flush() error {
+	if r.path == "" {
+		return nil
+	}
+	p, err := r.Export()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, p, 0644)
+}
+
+func (r *HARRecorder) add(e harEntry) {
+	r.mu.Lock()
+	r.entries = append(r.entries, e)
+	r.mu.Unlock()
+}
+
+// Flush writes out recordings kept by the client, such as the HAR file.
+func (c *httpClient) Flush() error {
+	if c.har != nil {
+		return c.har.Flush()
+	}
+	return nil
+}
+
+// Close flushes the client. The client must not be used afterwards.
+func (c *httpClient) Close() error {
+	return c.Flush()
+}
+
+// harTransport records every round trip into a HARRecorder.
+type harTransport struct {
+	next     http.RoundTripper
+	recorder *HARRecorder
+	redact   *redactor
+}
+
+func (t *harTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	e := harEntry{started: time.Now()}
+	e.StartedDateTime = e.started.Format("2006-01-02T15:04:05.000Z07:00")
+	e.Request = t.request(req)
+	resp, err := t.next.RoundTrip(req)
+	wait := time.Since(e.started)
+	e.Timings = harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: millis(wait)}
+	if err != nil {
+		e.Response = harResponse{
+			Cookies:     []harNameValue{},
+			Headers:     []harNameValue{},
+			Content:     harContent{Comment: err.Error()},
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+		e.Time = millis(wait)
+		t.recorder.add(e)
+		return nil, err
+	}
+	e.Response = harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode))),
+		HTTPVersion: resp.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(t.redact.header(resp.Header)),
+		RedirectURL: t.redact.urlString(resp.Header.Get("Location")),
+		HeadersSize: -1,
+	}
+	mimeType := resp.Header.Get("Content-Type")
+	resp.Body = &harBody{ReadCloser: resp.Body, done: func(p []byte, n int64) {
+		e.Response.BodySize = n
+		e.Response.Content = harBodyContent(mimeType, p, n, t.redact)
+		total := time.Since(e.started)
+		e.Timings.Receive = millis(total - wait)
+		e.Time = millis(total)
+		t.recorder.add(e)
+	}}
+	return resp, nil
+}
+
+func (t *harTransport) request(req *http.Request) harRequest {
+	r := harRequest{
+		Method:      req.Method,
+		URL:         t.redact.url(req.URL).String(),
+		HTTPVersion: req.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(t.redact.header(req.Header)),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	if r.HTTPVersion == "" {
+		r.HTTPVersion = "HTTP/1.1"
+	}
+	for k, vs := range t.redact.url(req.URL).Query() {
+		for _, v := range vs {
+			r.QueryString = append(r.QueryString, harNameValue{Name: k, Value: v})
+		}
+	}
+	sort.Slice(r.QueryString, func(i, j int) bool { return r.QueryString[i].Name < r.QueryString[j].Name })
+	if req.Body == nil || req.Body == http.NoBody {
+		r.BodySize = 0
+		return r
+	}
+	if req.GetBody == nil {
+		return r
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return r
+	}
+	p, _ := ioutil.ReadAll(io.LimitReader(body, harBodyLimit))
+	body.Close()
+	r.BodySize = req.ContentLength
+	mimeType := req.Header.Get("Content-Type")
+	r.PostData = &harPostData{MimeType: mimeType, Params: []harNameValue{}}
+	if isText(mimeType, p) {
+		r.PostData.Text = string(t.redact.body(mimeType, p))
+	}
+	return r
+}
+
+// harBody captures up to harBodyLimit bytes of a response body and hands
+// them to done when the body is closed.
+type harBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	n    int64
+	once sync.Once
+	done func(p []byte, n int64)
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if room := harBodyLimit - b.buf.Len(); room > 0 {
+		if n < room {
+			room = n
+		}
+		b.buf.Write(p[:room])
+	}
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.buf.Bytes(), b.n) })
+	return err
+}
+
+func harBodyContent(mimeType string, p []byte, n int64, redact *redactor) harContent {
+	c := harContent{Size: n, MimeType: mimeType}
+	switch {
+	case len(p) == 0:
+	case isText(mimeType, p):
+		c.Text = string(redact.body(mimeType, p))
+	default:
+		c.Text = base64.StdEncoding.EncodeToString(p)
+		c.Encoding = "base64"
+	}
+	if int64(len(p)) < n {
+		c.Comment = "body truncated"
+	}
+	return c
+}
+
+func harHeaders(h http.Header) []harNameValue {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	headers := []harNameValue{}
+	for _, k := range keys {
+		for _, v := range h[k] {
+			headers = append(headers, harNameValue{Name: k, Value: v})
+		}
+	}
+	return headers
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// The HAR 1.2 document, see http://www.softwareishard.com/blog/har-12-spec/.
+
+type harDocument struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	started time.Time
+
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string         `json:"mimeType"`
+	Params   []harNameValue `json:"params"`
+	Text     string         `json:"text"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
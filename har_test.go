@@ -0,0 +1,182 @@
+package httpclient
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// harFile is the part of a HAR 1.2 file the tests check, decoded
+// independently of the types the recorder writes.
+type harFile struct {
+	Log struct {
+		Version string
+		Creator struct{ Name, Version string }
+		Entries []struct {
+			StartedDateTime string
+			Time            *float64
+			Request         struct {
+				Method, URL, HTTPVersion string
+				Headers, QueryString     []struct{ Name, Value string }
+				Cookies                  []interface{}
+				PostData                 *struct{ MimeType, Text string }
+				HeadersSize, BodySize    *int64
+			}
+			Response struct {
+				Status                  int
+				StatusText, HTTPVersion string
+				RedirectURL             string `json:"redirectURL"`
+				Headers                 []struct{ Name, Value string }
+				Cookies                 []interface{}
+				Content                 struct {
+					Size                     int64
+					MimeType, Text, Encoding string
+				}
+				HeadersSize, BodySize *int64
+			}
+			Cache   *struct{}
+			Timings *struct{ Send, Wait, Receive float64 }
+		}
+	}
+}
+
+func harServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusFound)
+		case "/binary":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte{0, 1, 2, 0xff})
+		case "/echo":
+			w.Header().Set("Content-Type", "application/json")
+			io.Copy(w, r.Body)
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, "page "+r.URL.Path)
+		}
+	}))
+}
+
+func TestHAR(t *testing.T) {
+	srv := harServer()
+	defer srv.Close()
+
+	rec := NewHARRecorder()
+	c := New(WithHAR(rec), WithRedaction(nil, nil, []string{"password"}))
+	if _, err := c.Bytes(srv.URL+"/old?api_key=secret", WithRequestHeader("Authorization", "Bearer secret")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.PostJSON(srv.URL+"/echo", map[string]string{"password": "secret", "name": "n"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Bytes(srv.URL + "/binary"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := rec.Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(p), "secret") {
+		t.Errorf("secret in HAR:\n%s", p)
+	}
+	var har harFile
+	if err := json.Unmarshal(p, &har); err != nil {
+		t.Fatal(err)
+	}
+	if har.Log.Version != "1.2" || har.Log.Creator.Name == "" {
+		t.Errorf("got version %q, creator %q", har.Log.Version, har.Log.Creator.Name)
+	}
+	entries := har.Log.Entries
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries, want 4", len(entries))
+	}
+	want := []struct {
+		method, path string
+		status       int
+	}{
+		{"GET", "/old", 302},
+		{"GET", "/new", 200},
+		{"POST", "/echo", 200},
+		{"GET", "/binary", 200},
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Request.Method != w.method || !strings.HasPrefix(e.Request.URL, srv.URL+w.path) || e.Response.Status != w.status {
+			t.Errorf("entry %d: got %s %s %d, want %s %s %d", i, e.Request.Method, e.Request.URL, e.Response.Status, w.method, w.path, w.status)
+		}
+		if e.StartedDateTime == "" || e.Time == nil || e.Cache == nil || e.Timings == nil ||
+			e.Request.HeadersSize == nil || e.Request.BodySize == nil || e.Request.Cookies == nil ||
+			e.Response.HeadersSize == nil || e.Response.BodySize == nil || e.Response.Cookies == nil {
+			t.Errorf("entry %d: required field missing", i)
+		}
+	}
+	if got := entries[0].Response.RedirectURL; got != "/new" {
+		t.Errorf("got redirectURL %q", got)
+	}
+	if q := entries[0].Request.QueryString; len(q) != 1 || q[0].Name != "api_key" {
+		t.Errorf("got query string %v", q)
+	}
+	if got := entries[1].Response.Content; got.Text != "page /new" || got.Size != 9 || got.MimeType != "text/plain" {
+		t.Errorf("got content %+v", got)
+	}
+	post := entries[2]
+	if post.Request.PostData == nil || !strings.Contains(post.Request.PostData.Text, `"name":"n"`) {
+		t.Errorf("got post data %+v", post.Request.PostData)
+	}
+	if got := entries[3].Response.Content; got.Encoding != "base64" || got.Text != base64.StdEncoding.EncodeToString([]byte{0, 1, 2, 0xff}) {
+		t.Errorf("got binary content %+v", got)
+	}
+}
+
+func TestHARFiles(t *testing.T) {
+	srv := harServer()
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "session.har")
+	c := New(WithHARRecorder(path))
+	var urls []string
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		urls = append(urls, srv.URL+"/"+name)
+	}
+	var files []File
+	if err := c.Files(urls, &files, WithConcurrency(4)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var har harFile
+	if err := json.Unmarshal(p, &har); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range har.Log.Entries {
+		if e.Response.Content.Text != "page "+strings.TrimPrefix(e.Request.URL, srv.URL) {
+			t.Errorf("%s: got body %q", e.Request.URL, e.Response.Content.Text)
+		}
+		got = append(got, e.Request.URL)
+	}
+	sort.Strings(got)
+	if strings.Join(got, " ") != strings.Join(urls, " ") {
+		t.Errorf("got entries for %v, want %v", got, urls)
+	}
+	for i := 1; i < len(har.Log.Entries); i++ {
+		if har.Log.Entries[i].StartedDateTime < har.Log.Entries[i-1].StartedDateTime {
+			t.Error("entries not ordered by start time")
+		}
+	}
+}
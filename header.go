@@ -0,0 +1,146 @@
+package httpclient
+
+import (
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+)
+
+// WithRequestHeader sets a header of the request, replacing any value set
+// for it before. An empty value removes the client's default header of that
+// name from the request.
+func WithRequestHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		if o.header == nil {
+			o.header = make(http.Header)
+		}
+		o.header.Set(key, value)
+	}
+}
+
+// WithHeaders sets the headers of the request as WithRequestHeader does.
+func WithHeaders(headers map[string]string) RequestOption {
+	return func(o *requestOptions) {
+		for k, v := range headers {
+			WithRequestHeader(k, v)(o)
+		}
+	}
+}
+
+// defaultHeaders holds the headers set by SetHeader. It may be changed
+// while requests are in flight.
+type defaultHeaders struct {
+	mu     sync.RWMutex
+	header http.Header
+}
+
+func (d *defaultHeaders) clone() *defaultHeaders {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return &defaultHeaders{header: d.header.Clone()}
+}
+
+// apply adds the default headers to h, then the request headers of o. A
+// request header with an empty value removes the header.
+func (d *defaultHeaders) apply(h http.Header, o *requestOptions) {
+	d.mu.RLock()
+	for k, vs := range d.header {
+		h[k] = append([]string(nil), vs...)
+	}
+	d.mu.RUnlock()
+	for k, vs := range o.header {
+		if len(vs) == 1 && vs[0] == "" {
+			delete(h, k)
+			continue
+		}
+		h[k] = vs
+	}
+}
+
+// SetHeader makes c send the header key with value in every request, unless
+// the request sets it itself. An empty value removes the header. It is safe
+// to call while requests are in flight; they keep the headers they were
+// sent with.
+//
+// Redirects to another host, other than a subdomain of the host of the
+// request, are sent without the headers that the redaction rules cover,
+// such as X-Api-Key or those of SetAPIKey, as net/http does for
+// Authorization and Cookie. Other headers follow the redirect.
+func (c *httpClient) SetHeader(key, value string) {
+	c.headers.mu.Lock()
+	defer c.headers.mu.Unlock()
+	if value == "" {
+		c.headers.header.Del(key)
+		return
+	}
+	if c.headers.header == nil {
+		c.headers.header = make(http.Header)
+	}
+	c.headers.header.Set(key, value)
+}
+
+// SetHeaders calls SetHeader for each of headers.
+func (c *httpClient) SetHeaders(headers map[string]string) {
+	for k, v := range headers {
+		c.SetHeader(k, v)
+	}
+}
+
+// GetWithHeaders issues a GET request to url with headers added to, or
+// overriding, the client's default headers. An empty value removes a
+// default header from the request.
+func (c *httpClient) GetWithHeaders(url string, headers map[string]string, opts ...RequestOption) (*http.Response, error) {
+	return c.Get(url, append([]RequestOption{WithHeaders(headers)}, opts...)...)
+}
+
+// hopTransport removes the sensitive headers from the redirects of a
+// request that leave its host. net/http copies every header of the request
+// to its redirects but Authorization and Cookie, so the default headers and
+// the API key would reach any host a server redirects to. credTransport,
+// which comes after it, adds the credentials of the new host.
+type hopTransport struct {
+	next   http.RoundTripper
+	redact *redactor
+}
+
+func (t *hopTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	first := req
+	for first.Response != nil && first.Response.Request != nil {
+		first = first.Response.Request
+	}
+	if first == req || sameSite(first.URL, req.URL) {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for k := range req.Header {
+		if t.redact.headers[k] {
+			delete(req.Header, k)
+		}
+	}
+	return t.next.RoundTrip(req)
+}
+
+// sameSite reports whether the headers of a request to from may be sent to
+// to: whether to has the host of from, or is a subdomain of it. Ports are
+// ignored, as net/http ignores them.
+func sameSite(from, to *neturl.URL) bool {
+	f, d := strings.ToLower(from.Hostname()), strings.ToLower(to.Hostname())
+	return d == f || strings.HasSuffix(d, "."+f)
+}
+
+// SetHeader sets a default header of the default client.
+func SetHeader(key, value string) {
+	defaultClient().SetHeader(key, value)
+}
+
+// SetHeaders sets default headers of the default client.
+func SetHeaders(headers map[string]string) {
+	defaultClient().SetHeaders(headers)
+}
+
+// GetWithHeaders issues a GET request to url with headers, with the default
+// client.
+func GetWithHeaders(url string, headers map[string]string, opts ...RequestOption) (*http.Response, error) {
+	return defaultClient().GetWithHeaders(url, headers, opts...)
+}
@@ -0,0 +1,86 @@
+package httpclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// headerEcho answers with the headers of the request whose names start
+// with X-, as "Name=value" lines.
+var headerEcho = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	var lines []string
+	for k := range r.Header {
+		if strings.HasPrefix(k, "X-") {
+			lines = append(lines, k+"="+r.Header.Get(k))
+		}
+	}
+	sort.Strings(lines)
+	w.Write([]byte(strings.Join(lines, " ")))
+})
+
+func TestSetHeader(t *testing.T) {
+	c := NewForHandler(headerEcho)
+	c.SetHeaders(map[string]string{"X-Tenant": "acme", "X-Trace": "on", "X-Gone": "soon"})
+	c.SetHeader("X-Gone", "")
+
+	tests := []struct {
+		name    string
+		headers map[string]string // for GetWithHeaders
+		opts    []RequestOption
+		want    string
+	}{
+		{"defaults", nil, nil, "X-Tenant=acme X-Trace=on"},
+		{"override", nil, []RequestOption{WithRequestHeader("X-Tenant", "other")}, "X-Tenant=other X-Trace=on"},
+		{"delete", nil, []RequestOption{WithRequestHeader("X-Trace", "")}, "X-Tenant=acme"},
+		{"GetWithHeaders", map[string]string{"X-Tenant": "other", "X-Trace": "", "X-Extra": "1"}, nil, "X-Extra=1 X-Tenant=other"},
+		{"GetWithHeaders and options", map[string]string{"X-Extra": "1"}, []RequestOption{WithRequestHeader("X-Extra", "2")}, "X-Extra=2 X-Tenant=acme X-Trace=on"},
+	}
+	for _, tt := range tests {
+		got, err := c.String("http://api.test/", tt.opts...)
+		if tt.headers != nil {
+			var resp *http.Response
+			if resp, err = c.GetWithHeaders("http://api.test/", tt.headers, tt.opts...); err == nil {
+				var p []byte
+				p, err = ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				got = string(p)
+			}
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestHeadersOnRedirect(t *testing.T) {
+	c := NewForHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if to := r.URL.Query().Get("to"); to != "" {
+			http.Redirect(w, r, to, http.StatusFound)
+			return
+		}
+		headerEcho(w, r)
+	}))
+	c.SetHeaders(map[string]string{"X-Api-Key": "default", "X-Tenant": "acme"})
+	c.SetAPIKey("k3y", APIKeyHeader, "X-Key")
+
+	tests := []struct {
+		name, url string
+		want      string
+	}{
+		{"same host", "http://api.test/?to=/b", "X-Api-Key=default X-Key=k3y X-Tenant=acme"},
+		{"other port", "http://api.test/?to=http://api.test:8080/b", "X-Api-Key=default X-Key=k3y X-Tenant=acme"},
+		{"subdomain", "http://api.test/?to=http://v2.api.test/b", "X-Api-Key=default X-Key=k3y X-Tenant=acme"},
+		{"other host", "http://api.test/?to=http://evil.test/b", "X-Tenant=acme"},
+		{"parent domain", "http://v2.api.test/?to=http://api.test/b", "X-Tenant=acme"},
+		{"back again", "http://api.test/?to=http://evil.test/%3Fto=http://api.test/b", "X-Api-Key=default X-Key=k3y X-Tenant=acme"},
+	}
+	for _, tt := range tests {
+		got, err := c.String(tt.url)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// ErrMaxPolls is reported by UnhealthyError when the number of attempts
+// set with WithMaxPolls ran out.
+var ErrMaxPolls = errors.New("httpclient: maximum number of polls reached")
+
+// PollAttempt describes one attempt of a polling helper.
+type PollAttempt struct {
+	// Attempt counts from 1.
+	Attempt  int
+	Duration time.Duration
+
+	// Err is nil if the attempt succeeded.
+	Err error
+}
+
+// WithMaxPolls limits the number of attempts of a polling helper such as
+// WaitHealthy. By default there is no limit.
+func WithMaxPolls(n int) RequestOption {
+	return func(o *requestOptions) {
+		o.maxPolls = n
+	}
+}
+
+// WithPollHook calls fn after every attempt of a polling helper.
+func WithPollHook(fn func(PollAttempt)) RequestOption {
+	return func(o *requestOptions) {
+		o.pollHook = fn
+	}
+}
+
+// UnhealthyError is returned by WaitHealthy when the service did not
+// become healthy in time.
+type UnhealthyError struct {
+	URL      string
+	Attempts int
+
+	// Last is the error of the last attempt: an *Error for a response
+	// with a non-2xx status, or the network error.
+	Last error
+
+	// Reason is why the wait ended: the context error or ErrMaxPolls.
+	Reason error
+}
+
+// Error returns the error message.
+func (e *UnhealthyError) Error() string {
+	return fmt.Sprintf("%s not healthy after %d attempts: %v (%v)", e.URL, e.Attempts, e.Last, e.Reason)
+}
+
+// Unwrap returns the last error and the reason.
+func (e *UnhealthyError) Unwrap() []error {
+	return []error{e.Last, e.Reason}
+}
+
+// StatusCode returns the status of the last response, or 0 if the last
+// attempt did not get a response.
+func (e *UnhealthyError) StatusCode() int {
+	var err *Error
+	if errors.As(e.Last, &err) {
+		return err.StatusCode
+	}
+	return 0
+}
+
+// Ping checks that the service at url is healthy, i.e. that it answers a
+// HEAD request, or a GET one if it does not support HEAD, with a 2xx
+// status. At most 512 bytes of a GET response body are read.
+func (c *httpClient) Ping(url string, opts ...RequestOption) error {
+	o := newRequestOptions(opts)
+	resp, err := c.send("HEAD", url, nil, o)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = c.send("GET", url, nil, o)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return c.err(resp, "")
+	}
+	return nil
+}
+
+// WaitHealthy pings url until it is healthy, ctx is done or the attempts
+// set with WithMaxPolls run out. The wait between attempts starts at
+// interval and doubles after every failure, up to eight times interval.
+// If the service does not become healthy, WaitHealthy returns an
+// *UnhealthyError.
+func (c *httpClient) WaitHealthy(ctx context.Context, url string, interval time.Duration, opts ...RequestOption) error {
+	o := newRequestOptions(opts)
+	opts = withContext(ctx, opts)
+	wait := interval
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		err := c.Ping(url, opts...)
+		if o.pollHook != nil {
+			o.pollHook(PollAttempt{Attempt: attempt, Duration: time.Since(start), Err: err})
+		}
+		if err == nil {
+			return nil
+		}
+		if o.maxPolls > 0 && attempt >= o.maxPolls {
+			return &UnhealthyError{URL: c.redact.urlString(url), Attempts: attempt, Last: err, Reason: ErrMaxPolls}
+		}
+		if serr := sleep(ctx, wait); serr != nil {
+			return &UnhealthyError{URL: c.redact.urlString(url), Attempts: attempt, Last: err, Reason: serr}
+		}
+		if wait *= 2; wait > 8*interval {
+			wait = 8 * interval
+		}
+	}
+}
+
+// Ping checks that the service at url is healthy.
+func Ping(url string, opts ...RequestOption) error {
+	return defaultClient().Ping(url, opts...)
+}
+
+// WaitHealthy pings url until it is healthy or ctx is done.
+func WaitHealthy(ctx context.Context, url string, interval time.Duration, opts ...RequestOption) error {
+	return defaultClient().WaitHealthy(ctx, url, interval, opts...)
+}
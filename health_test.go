@@ -0,0 +1,127 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flippingServer answers 503 to the first n polls and 200 afterwards.
+func flippingServer(n int32) (*httptest.Server, *int32) {
+	var polls int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&polls, 1) <= n {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})), &polls
+}
+
+func TestWaitHealthy(t *testing.T) {
+	srv, polls := flippingServer(3)
+	defer srv.Close()
+
+	var attempts []PollAttempt
+	err := New().WaitHealthy(context.Background(), srv.URL, time.Millisecond, WithPollHook(func(a PollAttempt) {
+		attempts = append(attempts, a)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *polls != 4 || len(attempts) != 4 {
+		t.Fatalf("got %d polls and %d attempts, want 4", *polls, len(attempts))
+	}
+	for i, a := range attempts {
+		if a.Attempt != i+1 || a.Duration <= 0 {
+			t.Errorf("attempt %d: got %+v", i+1, a)
+		}
+		var e *Error
+		if i < 3 && (!errors.As(a.Err, &e) || e.StatusCode != 503) {
+			t.Errorf("attempt %d: got %v, want a 503", i+1, a.Err)
+		}
+		if i == 3 && a.Err != nil {
+			t.Errorf("attempt 4: got %v", a.Err)
+		}
+	}
+}
+
+func TestWaitHealthyMaxPolls(t *testing.T) {
+	srv, polls := flippingServer(100)
+	defer srv.Close()
+
+	start := time.Now()
+	err := New().WaitHealthy(context.Background(), srv.URL+"/?token=secret", 10*time.Millisecond, WithMaxPolls(5))
+	elapsed := time.Since(start)
+	var ue *UnhealthyError
+	if !errors.As(err, &ue) {
+		t.Fatalf("got %v, want an *UnhealthyError", err)
+	}
+	if ue.Attempts != 5 || *polls != 5 || !errors.Is(err, ErrMaxPolls) || ue.StatusCode() != 503 {
+		t.Errorf("got %d attempts, %d polls, reason %v, status %d", ue.Attempts, *polls, ue.Reason, ue.StatusCode())
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error leaks a secret: %v", err)
+	}
+	// The waits double: 10, 20, 40 and 80ms.
+	if elapsed < 150*time.Millisecond {
+		t.Errorf("took %v, want at least 150ms of backoff", elapsed)
+	}
+}
+
+func TestWaitHealthyNetworkError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := New().WaitHealthy(ctx, srv.URL, 5*time.Millisecond)
+	var ue *UnhealthyError
+	if !errors.As(err, &ue) {
+		t.Fatalf("got %v, want an *UnhealthyError", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Errorf("got %v, want the refused connection and the deadline", err)
+	}
+	if ue.StatusCode() != 0 || ue.Attempts < 2 {
+		t.Errorf("got status %d after %d attempts", ue.StatusCode(), ue.Attempts)
+	}
+}
+
+func TestPing(t *testing.T) {
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		switch {
+		case r.URL.Path == "/nohead" && r.Method == "HEAD":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/nohead":
+			io.WriteString(w, strings.Repeat("x", 1<<20))
+		case r.URL.Path == "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	c := New()
+	if err := c.Ping(srv.URL + "/empty"); err != nil {
+		t.Errorf("empty: %v", err)
+	}
+	if err := c.Ping(srv.URL + "/nohead"); err != nil {
+		t.Errorf("nohead: %v", err)
+	}
+	var e *Error
+	if err := c.Ping(srv.URL + "/down"); !errors.As(err, &e) || e.StatusCode != 500 {
+		t.Errorf("down: got %v", err)
+	}
+	if got := strings.Join(methods, " "); got != "HEAD HEAD GET HEAD" {
+		t.Errorf("got methods %s", got)
+	}
+}
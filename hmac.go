@@ -0,0 +1,125 @@
+package httpclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HMACConfig describes how WithHMACSigning signs requests. The zero value
+// signs
+//
+//	method + "\n" + path + "\n" + date + "\n" + hex(sha256(body))
+//
+// with HMAC-SHA256 and sets the X-Key-Id, Date and X-Signature headers,
+// the signature encoded in hex.
+type HMACConfig struct {
+	// KeyIDHeader, DateHeader and SignatureHeader name the headers that
+	// carry the key ID, the date and the signature. They default to
+	// X-Key-Id, Date and X-Signature; KeyIDHeader may be "-" to leave the
+	// key ID out.
+	KeyIDHeader     string
+	DateHeader      string
+	SignatureHeader string
+
+	// DateFormat is the layout of the date, http.TimeFormat by default.
+	// The date is in UTC.
+	DateFormat string
+
+	// Hash is the hash function of the HMAC, sha256.New by default. The
+	// body is always hashed with SHA-256.
+	Hash func() hash.Hash
+
+	// IncludeQuery signs the path with its query string.
+	IncludeQuery bool
+
+	// Headers are added to the signed string after the body hash, one
+	// "name:value" line each, the name in lower case.
+	Headers []string
+
+	// Base64 encodes the signature in standard base64 instead of hex.
+	Base64 bool
+
+	// Canonical, if set, returns the string to sign instead, given the
+	// request, the date and the hex SHA-256 hash of the body.
+	Canonical func(req *http.Request, date, bodyHash string) string
+}
+
+// WithHMACSigning signs every request made by the client, including each
+// request of Files, with an HMAC of secret as described by cfg. It is
+// WithSigner with NewHMACSigner. A request whose body has no GetBody, and
+// so cannot be hashed, fails with ErrUnsignedBody.
+func WithHMACSigning(keyID, secret string, cfg HMACConfig) Option {
+	return WithSigner(NewHMACSigner(keyID, secret, cfg))
+}
+
+// NewHMACSigner returns a Signer setting an HMAC of secret on requests as
+// described by cfg.
+func NewHMACSigner(keyID, secret string, cfg HMACConfig) Signer {
+	if cfg.KeyIDHeader == "" {
+		cfg.KeyIDHeader = "X-Key-Id"
+	}
+	if cfg.DateHeader == "" {
+		cfg.DateHeader = "Date"
+	}
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = "X-Signature"
+	}
+	if cfg.DateFormat == "" {
+		cfg.DateFormat = http.TimeFormat
+	}
+	if cfg.Hash == nil {
+		cfg.Hash = sha256.New
+	}
+	return &hmacSigner{keyID: keyID, secret: []byte(secret), cfg: cfg}
+}
+
+type hmacSigner struct {
+	keyID  string
+	secret []byte
+	cfg    HMACConfig
+}
+
+// Sign sets the signature headers of req.
+func (s *hmacSigner) Sign(req *http.Request, bodyHash []byte) error {
+	if bodyHash == nil {
+		return ErrUnsignedBody
+	}
+	date := time.Now().UTC().Format(s.cfg.DateFormat)
+	sum := hex.EncodeToString(bodyHash)
+
+	var msg string
+	if s.cfg.Canonical != nil {
+		msg = s.cfg.Canonical(req, date, sum)
+	} else {
+		path := req.URL.EscapedPath()
+		if s.cfg.IncludeQuery {
+			path = req.URL.RequestURI()
+		}
+		lines := []string{req.Method, path, date, sum}
+		for _, h := range s.cfg.Headers {
+			lines = append(lines, strings.ToLower(h)+":"+strings.TrimSpace(req.Header.Get(h)))
+		}
+		msg = strings.Join(lines, "\n")
+	}
+
+	mac := hmac.New(s.cfg.Hash, s.secret)
+	io.WriteString(mac, msg)
+	sig := hex.EncodeToString(mac.Sum(nil))
+	if s.cfg.Base64 {
+		sig = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	if s.cfg.KeyIDHeader != "-" {
+		req.Header.Set(s.cfg.KeyIDHeader, s.keyID)
+	}
+	req.Header.Set(s.cfg.DateHeader, date)
+	req.Header.Set(s.cfg.SignatureHeader, sig)
+	return nil
+}
@@ -0,0 +1,237 @@
+package httpclient
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gateway checks request signatures the way a server would, written apart
+// from hmacSigner.
+type gateway struct {
+	keyHeader, dateHeader, sigHeader string
+	withQuery                        bool
+	headers                          []string
+	hash                             func() hash.Hash
+	base64                           bool
+	dateLayout                       string
+	canonical                        func(r *http.Request, date, bodyHash string) string
+}
+
+func (g gateway) verify(r *http.Request, keys map[string]string) error {
+	keyHeader, dateHeader, sigHeader := "X-Key-Id", "Date", "X-Signature"
+	if g.keyHeader != "" {
+		keyHeader = g.keyHeader
+	}
+	if g.dateHeader != "" {
+		dateHeader = g.dateHeader
+	}
+	if g.sigHeader != "" {
+		sigHeader = g.sigHeader
+	}
+	secret, ok := keys[r.Header.Get(keyHeader)]
+	if keyHeader == "-" {
+		secret, ok = keys[""]
+	}
+	if !ok {
+		return fmt.Errorf("unknown key %q", r.Header.Get(keyHeader))
+	}
+
+	date := r.Header.Get(dateHeader)
+	layout := http.TimeFormat
+	if g.dateLayout != "" {
+		layout = g.dateLayout
+	}
+	t, err := time.Parse(layout, date)
+	if err != nil {
+		return fmt.Errorf("bad date %q: %v", date, err)
+	}
+	if d := time.Since(t); d > time.Minute || d < -time.Minute {
+		return fmt.Errorf("stale date %q", date)
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	sum := sha256.Sum256(body)
+	bodyHash := hex.EncodeToString(sum[:])
+
+	var msg string
+	if g.canonical != nil {
+		msg = g.canonical(r, date, bodyHash)
+	} else {
+		path := r.URL.EscapedPath()
+		if g.withQuery && r.URL.RawQuery != "" {
+			path += "?" + r.URL.RawQuery
+		}
+		msg = r.Method + "\n" + path + "\n" + date + "\n" + bodyHash
+		for _, h := range g.headers {
+			msg += "\n" + strings.ToLower(h) + ":" + r.Header.Get(h)
+		}
+	}
+
+	newHash := sha256.New
+	if g.hash != nil {
+		newHash = g.hash
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write([]byte(msg))
+	var got []byte
+	if g.base64 {
+		got, err = base64.StdEncoding.DecodeString(r.Header.Get(sigHeader))
+	} else {
+		got, err = hex.DecodeString(r.Header.Get(sigHeader))
+	}
+	if err != nil {
+		return fmt.Errorf("bad signature encoding: %v", err)
+	}
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// gatewayServer answers 200 with the request body to requests that
+// g.verify accepts, and 401 with the reason to the others.
+func gatewayServer(g gateway, keys map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := g.verify(r, keys); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+}
+
+func TestHMACSigning(t *testing.T) {
+	keys := map[string]string{"k1": "s3cret", "": "anonymous"}
+	tests := []struct {
+		name string
+		cfg  HMACConfig
+		g    gateway
+	}{
+		{"default", HMACConfig{}, gateway{}},
+		{"query", HMACConfig{IncludeQuery: true}, gateway{withQuery: true}},
+		{
+			"headers",
+			HMACConfig{Headers: []string{"Content-Type", "X-Request-Id"}},
+			gateway{headers: []string{"content-type", "x-request-id"}},
+		},
+		{"sha512 base64", HMACConfig{Hash: sha512.New, Base64: true}, gateway{hash: sha512.New, base64: true}},
+		{"sha1", HMACConfig{Hash: sha1.New}, gateway{hash: sha1.New}},
+		{
+			"header names",
+			HMACConfig{KeyIDHeader: "-", DateHeader: "X-Date", SignatureHeader: "Authorization", DateFormat: time.RFC3339},
+			gateway{keyHeader: "-", dateHeader: "X-Date", sigHeader: "Authorization", dateLayout: time.RFC3339},
+		},
+		{
+			"canonical",
+			HMACConfig{Canonical: func(r *http.Request, date, bodyHash string) string {
+				return strings.Join([]string{date, strings.ToLower(r.Method), r.URL.Host, bodyHash}, "|")
+			}},
+			gateway{canonical: func(r *http.Request, date, bodyHash string) string {
+				return strings.Join([]string{date, strings.ToLower(r.Method), r.Host, bodyHash}, "|")
+			}},
+		},
+	}
+	for _, tt := range tests {
+		srv := gatewayServer(tt.g, keys)
+		c := New(WithBaseURL(srv.URL), WithHMACSigning("k1", "s3cret", tt.cfg))
+		if tt.cfg.KeyIDHeader == "-" {
+			c = New(WithBaseURL(srv.URL), WithHMACSigning("k1", "anonymous", tt.cfg))
+		}
+		opts := []RequestOption{WithQueryParam("page", "2"), WithRequestHeader("X-Request-Id", "r-1")}
+
+		if s, err := c.String("/v1/items%2Fall", opts...); err != nil || s != "" {
+			t.Errorf("%s: GET: got %q, %v", tt.name, s, err)
+		}
+		resp, err := c.Post("/v1/items", "application/json", strings.NewReader(`{"name":"widget"}`), opts...)
+		if err != nil {
+			t.Errorf("%s: POST: %v", tt.name, err)
+		} else {
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != 200 || string(body) != `{"name":"widget"}` {
+				t.Errorf("%s: POST: got %d %s", tt.name, resp.StatusCode, body)
+			}
+		}
+		var out map[string]int
+		if err := c.PutJSON("/v1/items/1", map[string]int{"n": 1}, &out, opts...); err != nil || out["n"] != 1 {
+			t.Errorf("%s: PUT: got %v, %v", tt.name, out, err)
+		}
+		if resp, err := c.Delete("/v1/items/1", opts...); err != nil || resp.StatusCode != 200 {
+			t.Errorf("%s: DELETE: got %v, %v", tt.name, resp, err)
+		}
+
+		var files []File
+		urls := []string{srv.URL + "/a", srv.URL + "/b?x=1", srv.URL + "/c"}
+		if err := c.Files(urls, &files, opts...); err != nil || len(files) != 3 {
+			t.Errorf("%s: Files: got %d files, %v", tt.name, len(files), err)
+		}
+		srv.Close()
+	}
+}
+
+func TestHMACSigningRejected(t *testing.T) {
+	srv := gatewayServer(gateway{}, map[string]string{"k1": "s3cret"})
+	defer srv.Close()
+
+	// A wrong secret, an unknown key and a different canonical form are
+	// all turned down.
+	for _, c := range []*httpClient{
+		New(WithHMACSigning("k1", "wrong", HMACConfig{})),
+		New(WithHMACSigning("k2", "s3cret", HMACConfig{})),
+		New(WithHMACSigning("k1", "s3cret", HMACConfig{IncludeQuery: true})),
+	} {
+		_, err := c.String(srv.URL+"/v1/items?page=2", WithRequestHeader("X-Request-Id", "r-1"))
+		var herr *Error
+		if !errors.As(err, &herr) || herr.StatusCode != 401 {
+			t.Errorf("got %v, want a 401", err)
+		}
+	}
+
+	// A body that cannot be hashed is not sent.
+	c := New(WithHMACSigning("k1", "s3cret", HMACConfig{}))
+	_, err := c.Post(srv.URL, "text/plain", ioutil.NopCloser(strings.NewReader("once")))
+	if !errors.Is(err, ErrUnsignedBody) {
+		t.Errorf("got %v, want ErrUnsignedBody", err)
+	}
+}
+
+func TestHMACSignature(t *testing.T) {
+	// The default string to sign, with the SHA-256 of the empty body
+	// spelled out and the query left out.
+	req, _ := http.NewRequest("GET", "https://gw.test/v1/items?page=2", nil)
+	empty := sha256.Sum256(nil)
+	if err := NewHMACSigner("k1", "s3cret", HMACConfig{}).Sign(req, empty[:]); err != nil {
+		t.Fatal(err)
+	}
+	date := req.Header.Get("Date")
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	fmt.Fprintf(mac, "GET\n/v1/items\n%s\ne3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", date)
+	if got, want := req.Header.Get("X-Signature"), hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("got signature %s, want %s", got, want)
+	}
+	if req.Header.Get("X-Key-Id") != "k1" {
+		t.Errorf("got key ID %q", req.Header.Get("X-Key-Id"))
+	}
+	if _, err := time.Parse(http.TimeFormat, date); err != nil {
+		t.Errorf("got date %q: %v", date, err)
+	}
+}
@@ -0,0 +1,114 @@
+package httpclient
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// hooks holds the functions registered with OnRequest, OnResponse and
+// OnError. They may be registered while requests are in flight.
+type hooks struct {
+	mu       sync.RWMutex
+	request  []func(*http.Request)
+	response []func(*http.Response, time.Duration)
+	err      []func(url string, err error)
+}
+
+func (h *hooks) clone() *hooks {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return &hooks{
+		request:  append(h.request[:0:0], h.request...),
+		response: append(h.response[:0:0], h.response...),
+		err:      append(h.err[:0:0], h.err...),
+	}
+}
+
+// OnRequest registers fn to be called with every request the client sends,
+// including each retry and each download of Files, right before it is
+// sent. Hooks are called in the order they were registered, possibly from
+// several goroutines at once.
+func (c *httpClient) OnRequest(fn func(req *http.Request)) {
+	c.hooks.mu.Lock()
+	c.hooks.request = append(c.hooks.request, fn)
+	c.hooks.mu.Unlock()
+}
+
+// OnResponse registers fn to be called with every response the client
+// gets, and the time it took, as soon as its header has arrived. The
+// response fn gets is a copy without a body, which is left to the caller.
+// Hooks are called in the order they were registered, possibly from
+// several goroutines at once.
+func (c *httpClient) OnResponse(fn func(resp *http.Response, elapsed time.Duration)) {
+	c.hooks.mu.Lock()
+	c.hooks.response = append(c.hooks.response, fn)
+	c.hooks.mu.Unlock()
+}
+
+// OnError registers fn to be called with the redacted URL and the error of
+// every request that fails, be it without a response, with a status that
+// is not accepted or with a body that cannot be read or decoded. Hooks are
+// called in the order they were registered, possibly from several
+// goroutines at once.
+func (c *httpClient) OnError(fn func(url string, err error)) {
+	c.hooks.mu.Lock()
+	c.hooks.err = append(c.hooks.err, fn)
+	c.hooks.mu.Unlock()
+}
+
+func (h *hooks) sending(req *http.Request) {
+	h.mu.RLock()
+	fns := h.request
+	h.mu.RUnlock()
+	for _, fn := range fns {
+		fn(req)
+	}
+}
+
+func (h *hooks) received(resp *http.Response, elapsed time.Duration) {
+	h.mu.RLock()
+	fns := h.response
+	h.mu.RUnlock()
+	if len(fns) == 0 {
+		return
+	}
+	r := *resp
+	r.Body = http.NoBody
+	for _, fn := range fns {
+		fn(&r, elapsed)
+	}
+}
+
+func (h *hooks) failed(url string, err error) {
+	h.mu.RLock()
+	fns := h.err
+	h.mu.RUnlock()
+	for _, fn := range fns {
+		fn(url, err)
+	}
+}
+
+// bodyFailed reports err, the error reading or decoding the body of resp,
+// to the OnError hooks, and returns it. An *Error was reported when it was
+// made, and is not reported again.
+func (c *httpClient) bodyFailed(resp *http.Response, err error) error {
+	if _, ok := err.(*Error); err != nil && !ok {
+		c.hooks.failed(c.redact.url(resp.Request.URL).String(), err)
+	}
+	return err
+}
+
+// WithStdLogger logs the method, URL, status and duration of every
+// response, and every error, to l, one line each. URLs are redacted.
+func WithStdLogger(l *log.Logger) Option {
+	return func(c *httpClient) {
+		c.OnResponse(func(resp *http.Response, elapsed time.Duration) {
+			l.Printf("%s %s -> %d (%s)", resp.Request.Method, c.redact.url(resp.Request.URL), resp.StatusCode, elapsed)
+		})
+		c.OnError(func(url string, err error) {
+			l.Printf("%s: %v", url, err)
+		})
+	}
+}
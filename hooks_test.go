@@ -0,0 +1,281 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hookLog records the calls of hooks, in order.
+type hookLog struct {
+	mu     sync.Mutex
+	events []string
+	errs   []error
+}
+
+func (l *hookLog) add(event string) {
+	l.mu.Lock()
+	l.events = append(l.events, event)
+	l.mu.Unlock()
+}
+
+func (l *hookLog) take() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := strings.Join(l.events, " ")
+	l.events, l.errs = nil, nil
+	return s
+}
+
+// register registers n hooks of each kind on c, logging their calls.
+func (l *hookLog) register(c *httpClient, n int) {
+	for i := 1; i <= n; i++ {
+		i := i
+		c.OnRequest(func(req *http.Request) { l.add(fmt.Sprintf("request%d", i)) })
+		c.OnResponse(func(resp *http.Response, elapsed time.Duration) {
+			// The hook cannot consume the body of the caller.
+			p, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			l.add(fmt.Sprintf("response%d:%d:%d", i, resp.StatusCode, len(p)))
+		})
+		c.OnError(func(url string, err error) {
+			l.mu.Lock()
+			l.errs = append(l.errs, err)
+			l.mu.Unlock()
+			l.add(fmt.Sprintf("error%d", i))
+		})
+	}
+}
+
+func TestHooks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fail":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			io.WriteString(w, "hello")
+		}
+	}))
+	defer srv.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	var l hookLog
+	c := New()
+	l.register(c, 2)
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"success", srv.URL + "/ok", "request1 request2 response1:200:0 response2:200:0"},
+		{"status", srv.URL + "/fail", "request1 request2 response1:500:0 response2:500:0 error1 error2"},
+		{"no response", closed.URL, "request1 request2 error1 error2"},
+	}
+	for _, tt := range tests {
+		s, err := c.String(tt.url)
+		if got := l.take(); got != tt.want {
+			t.Errorf("%s: got hooks %s, want %s", tt.name, got, tt.want)
+		}
+		if tt.name == "success" && (err != nil || s != "hello") {
+			t.Errorf("%s: got %q, %v", tt.name, s, err)
+		}
+	}
+}
+
+func TestHooksDecodeErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/type":
+			io.WriteString(w, `{"n":"not a number"}`)
+		case "/syntax":
+			io.WriteString(w, `{"n":`)
+		case "/unknown":
+			io.WriteString(w, `{"n":1,"other":2}`)
+		case "/xml":
+			io.WriteString(w, `<v><n>not a number</n></v>`)
+		default:
+			io.WriteString(w, `{"n":1}`)
+		}
+	}))
+	defer srv.Close()
+	var l hookLog
+	c := New()
+	l.register(c, 1)
+
+	var v struct {
+		N int `json:"n" xml:"n"`
+	}
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"JSON type", func() error { return c.JSON(srv.URL+"/type", &v) }},
+		{"JSON syntax", func() error { return c.JSON(srv.URL+"/syntax", &v) }},
+		{"JSON strict", func() error { return c.JSON(srv.URL+"/unknown", &v, WithDisallowUnknownFields()) }},
+		{"XML type", func() error { return c.XML(srv.URL+"/xml", &v) }},
+		{"PostJSON type", func() error { return c.PostJSON(srv.URL+"/type", nil, &v) }},
+		{"PostJSON syntax", func() error { return c.PostJSON(srv.URL+"/syntax", nil, &v) }},
+	}
+	for _, tt := range tests {
+		err := tt.call()
+		l.mu.Lock()
+		errs := l.errs
+		l.mu.Unlock()
+		if got := l.take(); err == nil || got != "request1 response1:200:0 error1" || len(errs) != 1 || errs[0] != err {
+			t.Errorf("%s: got %v, hooks %s", tt.name, err, got)
+		}
+	}
+	// The error is reported as it is returned.
+	err := c.JSON(srv.URL+"/type", &v)
+	if _, ok := err.(*json.UnmarshalTypeError); !ok {
+		t.Errorf("JSON type: got %T, want a *json.UnmarshalTypeError", err)
+	}
+	l.take()
+	if err := c.JSON(srv.URL+"/ok", &v); err != nil || v.N != 1 {
+		t.Errorf("got %+v, %v", v, err)
+	}
+	if got := l.take(); strings.Contains(got, "error") {
+		t.Errorf("success: got hooks %s", got)
+	}
+}
+
+func TestHooksRegisteredInFlight(t *testing.T) {
+	arrived, release := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			arrived <- struct{}{}
+			<-release
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	c := New()
+
+	done := make(chan error)
+	go func() {
+		_, err := c.String(srv.URL + "/slow")
+		done <- err
+	}()
+	<-arrived
+
+	// Registering races with the request in flight, and with other
+	// requests, but the response is still to come and reaches the hook.
+	var l hookLog
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.String(srv.URL + "/fast")
+		}()
+		go func() {
+			defer wg.Done()
+			c.OnError(func(string, error) {})
+		}()
+	}
+	c.OnResponse(func(resp *http.Response, elapsed time.Duration) {
+		l.add(resp.Request.URL.Path)
+	})
+	wg.Wait()
+	l.take()
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := l.take(); got != "/slow" {
+		t.Errorf("got hooks %q, want the response of the request in flight", got)
+	}
+}
+
+func TestHooksFiles(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/7" {
+			http.NotFound(w, r)
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+		io.WriteString(w, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	var (
+		mu        sync.Mutex
+		requests  = make(map[string]int)
+		responses = make(map[string]int)
+		failed    []string
+	)
+	c := New()
+	c.OnRequest(func(req *http.Request) {
+		mu.Lock()
+		requests[req.URL.Path]++
+		mu.Unlock()
+	})
+	c.OnResponse(func(resp *http.Response, elapsed time.Duration) {
+		mu.Lock()
+		responses[resp.Request.URL.Path]++
+		mu.Unlock()
+	})
+	c.OnError(func(url string, err error) {
+		mu.Lock()
+		failed = append(failed, url)
+		mu.Unlock()
+	})
+
+	var urls []string
+	for i := 0; i < 20; i++ {
+		urls = append(urls, fmt.Sprintf("%s/%d", srv.URL, i))
+	}
+	var files []File
+	c.Files(urls, &files)
+	for i := 0; i < 20; i++ {
+		path := fmt.Sprintf("/%d", i)
+		if requests[path] != 1 || responses[path] != 1 {
+			t.Errorf("%s: got %d request and %d response hooks, want 1", path, requests[path], responses[path])
+		}
+	}
+	if len(failed) != 1 || failed[0] != srv.URL+"/7" {
+		t.Errorf("got error hooks for %v", failed)
+	}
+}
+
+func TestWithStdLogger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			http.Error(w, "boom", http.StatusBadGateway)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	var buf bytes.Buffer
+	c := New(WithStdLogger(log.New(&buf, "", 0)))
+
+	c.String(srv.URL + "/ok?api_key=secret")
+	c.String(srv.URL + "/fail")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines:\n%s", len(lines), buf.String())
+	}
+	for i, want := range []string{
+		"GET " + srv.URL + "/ok?api_key=",
+		"GET " + srv.URL + "/fail -> 502 (",
+		srv.URL + "/fail: Get " + srv.URL + "/fail -> 502",
+	} {
+		if !strings.HasPrefix(lines[i], want) {
+			t.Errorf("line %d: got %q, want %q", i, lines[i], want)
+		}
+	}
+	if !strings.Contains(lines[0], " -> 200 (") || strings.Contains(buf.String(), "secret") {
+		t.Errorf("got %q", lines[0])
+	}
+}
@@ -0,0 +1,189 @@
+package httpclient
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// defaultHostStatsLimit is the number of hosts whose statistics are kept.
+const defaultHostStatsLimit = 256
+
+// HostStat summarizes the requests made to a single host.
+type HostStat struct {
+	Requests int64
+
+	// Errors counts the requests that failed without a response or got a
+	// 5xx response.
+	Errors int64
+
+	BytesIn  int64
+	BytesOut int64
+
+	// NewConnections and ReusedConnections count the connections to the
+	// host, as in Stats.
+	NewConnections    int64
+	ReusedConnections int64
+
+	// P50 and P95 are latency quantiles, estimated from a histogram whose
+	// buckets are 40% apart.
+	P50 time.Duration
+	P95 time.Duration
+}
+
+// ErrorRate returns the fraction of requests that failed.
+func (s HostStat) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Requests)
+}
+
+// WithHostStatsLimit sets how many hosts HostStats keeps track of. When
+// the limit is reached, the least recently used host is forgotten. The
+// default is 256.
+func WithHostStatsLimit(n int) Option {
+	return func(c *httpClient) {
+		c.hosts.limit = n
+	}
+}
+
+// HostStats returns per host statistics of the requests made by the
+// client, keyed by host (including the port, if any).
+func (c *httpClient) HostStats() map[string]HostStat {
+	return c.hosts.snapshot()
+}
+
+// latencyBounds are the upper bounds of the latency histogram buckets,
+// from 1ms to about 12 minutes.
+var latencyBounds = func() []time.Duration {
+	bounds := make([]time.Duration, 40)
+	d := float64(time.Millisecond)
+	for i := range bounds {
+		bounds[i] = time.Duration(d)
+		d *= 1.4
+	}
+	return bounds
+}()
+
+type hostEntry struct {
+	host     string
+	stat     HostStat
+	latency  [41]int64
+	observed int64
+}
+
+func (e *hostEntry) observe(d time.Duration) {
+	i := 0
+	for i < len(latencyBounds) && d > latencyBounds[i] {
+		i++
+	}
+	e.latency[i]++
+	e.observed++
+}
+
+func (e *hostEntry) quantile(q float64) time.Duration {
+	if e.observed == 0 {
+		return 0
+	}
+	rank := int64(q*float64(e.observed) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i, n := range e.latency {
+		seen += n
+		if seen >= rank {
+			if i == len(latencyBounds) {
+				break
+			}
+			return latencyBounds[i]
+		}
+	}
+	return latencyBounds[len(latencyBounds)-1]
+}
+
+// hostStats is an LRU of per host statistics.
+type hostStats struct {
+	mu    sync.Mutex
+	limit int
+	order *list.List
+	hosts map[string]*list.Element
+}
+
+func (s *hostStats) entry(host string) *hostEntry {
+	if s.hosts == nil {
+		s.hosts = make(map[string]*list.Element)
+		s.order = list.New()
+	}
+	if el, ok := s.hosts[host]; ok {
+		s.order.MoveToFront(el)
+		return el.Value.(*hostEntry)
+	}
+	limit := s.limit
+	if limit <= 0 {
+		limit = defaultHostStatsLimit
+	}
+	for s.order.Len() >= limit {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.hosts, oldest.Value.(*hostEntry).host)
+	}
+	e := &hostEntry{host: host}
+	s.hosts[host] = s.order.PushFront(e)
+	return e
+}
+
+func (s *hostStats) gotConn(host string, reused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entry(host)
+	if reused {
+		e.stat.ReusedConnections++
+	} else {
+		e.stat.NewConnections++
+	}
+}
+
+func (s *hostStats) failed(host string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entry(host)
+	e.stat.Requests++
+	e.stat.Errors++
+	e.observe(d)
+}
+
+func (s *hostStats) completed(host string, d time.Duration, status int, in, out int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entry(host)
+	e.stat.Requests++
+	if status >= 500 {
+		e.stat.Errors++
+	}
+	e.stat.BytesIn += in
+	e.stat.BytesOut += out
+	e.observe(d)
+}
+
+func (s *hostStats) snapshot() map[string]HostStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := make(map[string]HostStat, len(s.hosts))
+	for host, el := range s.hosts {
+		e := el.Value.(*hostEntry)
+		st := e.stat
+		st.P50 = e.quantile(0.50)
+		st.P95 = e.quantile(0.95)
+		m[host] = st
+	}
+	return m
+}
+
+func (s *hostStats) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hosts = nil
+	s.order = nil
+}
@@ -0,0 +1,119 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostStats(t *testing.T) {
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "fast")
+	}))
+	defer fast.Close()
+	var n int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		if atomic.AddInt32(&n, 1)%4 == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		io.WriteString(w, "slow")
+	}))
+	defer slow.Close()
+
+	c := New()
+	for i := 0; i < 20; i++ {
+		c.Bytes(fast.URL)
+		c.Bytes(slow.URL)
+	}
+
+	stats := c.HostStats()
+	if len(stats) != 2 {
+		t.Fatalf("got stats for %d hosts, want 2", len(stats))
+	}
+	f := stats[strings.TrimPrefix(fast.URL, "http://")]
+	s := stats[strings.TrimPrefix(slow.URL, "http://")]
+	if f.Requests != 20 || s.Requests != 20 {
+		t.Errorf("got %d and %d requests, want 20 each", f.Requests, s.Requests)
+	}
+	if f.Errors != 0 || f.ErrorRate() != 0 {
+		t.Errorf("fast host: got %d errors", f.Errors)
+	}
+	if s.Errors != 5 || s.ErrorRate() != 0.25 {
+		t.Errorf("slow host: got %d errors, rate %v, want 5 and 0.25", s.Errors, s.ErrorRate())
+	}
+	if f.BytesIn != 20*4 || s.BytesIn != 15*4 {
+		t.Errorf("got %d and %d bytes in, want 80 and 60", f.BytesIn, s.BytesIn)
+	}
+	if s.P50 < 20*time.Millisecond || s.P95 < s.P50 {
+		t.Errorf("slow host: got p50 %v, p95 %v", s.P50, s.P95)
+	}
+	if f.P95 >= s.P50 {
+		t.Errorf("fast host p95 %v not below slow host p50 %v", f.P95, s.P50)
+	}
+	if f.NewConnections+f.ReusedConnections != 20 || f.ReusedConnections == 0 {
+		t.Errorf("fast host: got %d new and %d reused connections", f.NewConnections, f.ReusedConnections)
+	}
+}
+
+func TestHostStatsFailed(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	c := New()
+	c.Bytes(srv.URL)
+	s := c.HostStats()[strings.TrimPrefix(srv.URL, "http://")]
+	if s.Requests != 1 || s.Errors != 1 || s.ErrorRate() != 1 {
+		t.Errorf("got %+v, want one failed request", s)
+	}
+}
+
+func TestHostStatsLimit(t *testing.T) {
+	var hosts []string
+	for i := 0; i < 3; i++ {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer srv.Close()
+		hosts = append(hosts, srv.URL)
+	}
+	c := New(WithHostStatsLimit(2))
+	for _, i := range []int{0, 1, 0, 2} {
+		if _, err := c.Bytes(hosts[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats := c.HostStats()
+	if len(stats) != 2 {
+		t.Fatalf("got %d hosts, want 2", len(stats))
+	}
+	if _, ok := stats[strings.TrimPrefix(hosts[1], "http://")]; ok {
+		t.Error("the least recently used host was kept")
+	}
+	if s := stats[strings.TrimPrefix(hosts[0], "http://")]; s.Requests != 2 {
+		t.Errorf("got %d requests for the first host, want 2", s.Requests)
+	}
+}
+
+func TestHostEntryQuantile(t *testing.T) {
+	e := &hostEntry{}
+	if e.quantile(0.5) != 0 {
+		t.Error("got a quantile with no observation")
+	}
+	for i := 1; i <= 100; i++ {
+		e.observe(time.Duration(i) * time.Millisecond)
+	}
+	// The estimate is the upper bound of a bucket, at most 40% above.
+	for q, want := range map[float64]time.Duration{0.50: 50 * time.Millisecond, 0.95: 95 * time.Millisecond} {
+		got := e.quantile(q)
+		if got < want || got > want*14/10 {
+			t.Errorf("quantile(%v) = %v, want %v to %v", q, got, want, want*14/10)
+		}
+	}
+	e.observe(time.Hour)
+	if got := e.quantile(1); got != latencyBounds[len(latencyBounds)-1] {
+		t.Errorf("quantile(1) = %v, want the largest bound", got)
+	}
+}
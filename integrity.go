@@ -0,0 +1,136 @@
+package httpclient
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// IntegrityError is returned when a response body does not match the
+// integrity given with WithIntegrity.
+type IntegrityError struct {
+	URL string
+
+	// Expected holds the accepted digests, and Computed the digest of the
+	// body, all in the "sha384-<base64>" form.
+	Expected []string
+	Computed string
+}
+
+// Error returns the error message.
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("httpclient: integrity mismatch for %s: expected %s, computed %s", e.URL, strings.Join(e.Expected, " or "), e.Computed)
+}
+
+// integrity is a parsed integrity metadata string.
+type integrity struct {
+	alg     string
+	newHash func() hash.Hash
+	digests []string
+}
+
+// integrityAlgs are the supported algorithms, the strongest first.
+var integrityAlgs = []struct {
+	name    string
+	newHash func() hash.Hash
+}{
+	{"sha512", sha512.New},
+	{"sha384", sha512.New384},
+	{"sha256", sha256.New},
+}
+
+// WithIntegrity checks the response body against integrity, given in the
+// Subresource Integrity format: one or more space-separated tokens such as
+// "sha384-<base64 digest>". Of the sha256, sha384 and sha512 tokens, only
+// those of the strongest algorithm are used, and the body must match one
+// of them. The body is hashed as it is read; a mismatch fails the call
+// with an *IntegrityError. Tokens of other algorithms are ignored, but a
+// string without any supported token fails the call.
+func WithIntegrity(s string) RequestOption {
+	return func(o *requestOptions) {
+		in, err := parseIntegrity(s)
+		if err != nil {
+			o.fail(err)
+			return
+		}
+		o.integrity = in
+	}
+}
+
+func parseIntegrity(s string) (*integrity, error) {
+	tokens := strings.Fields(s)
+	for _, a := range integrityAlgs {
+		in := &integrity{alg: a.name, newHash: a.newHash}
+		for _, t := range tokens {
+			if i := strings.IndexByte(t, '?'); i >= 0 {
+				t = t[:i]
+			}
+			if !strings.HasPrefix(t, a.name+"-") {
+				continue
+			}
+			d := t[len(a.name)+1:]
+			p, err := base64.StdEncoding.DecodeString(d)
+			if err != nil || len(p) != a.newHash().Size() {
+				return nil, fmt.Errorf("httpclient: malformed integrity token %q", t)
+			}
+			in.digests = append(in.digests, t)
+		}
+		if in.digests != nil {
+			return in, nil
+		}
+	}
+	return nil, fmt.Errorf("httpclient: no supported hash in integrity %q", s)
+}
+
+// integrityBody hashes a response body as it is read and checks it at the
+// end, returning an *IntegrityError instead of io.EOF on a mismatch.
+type integrityBody struct {
+	io.ReadCloser
+	in  *integrity
+	h   hash.Hash
+	url string
+	err error
+}
+
+func (b *integrityBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.h.Write(p[:n])
+	if err == io.EOF {
+		computed := b.in.alg + "-" + base64.StdEncoding.EncodeToString(b.h.Sum(nil))
+		for _, d := range b.in.digests {
+			if d == computed {
+				return n, io.EOF
+			}
+		}
+		b.err = &IntegrityError{URL: b.url, Expected: b.in.digests, Computed: computed}
+		return n, b.err
+	}
+	return n, err
+}
+
+// verifyRest reads the rest of body after a decoder that may stop early:
+// all of it when it is checked for integrity, so that the check gets done,
+// and otherwise up to drainLimit bytes, so that its connection can be
+// reused and a corrupt end of a compressed body is noticed.
+func (o *requestOptions) verifyRest(body io.Reader) error {
+	if o.integrity == nil {
+		if _, err := io.CopyN(ioutil.Discard, body, drainLimit); err != io.EOF {
+			return err
+		}
+		return nil
+	}
+	_, err := io.Copy(ioutil.Discard, body)
+	return err
+}
+
+// drainLimit is how much of the unread rest of a body is read to keep its
+// connection.
+const drainLimit = 4 << 10
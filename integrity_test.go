@@ -0,0 +1,155 @@
+package httpclient
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// sri returns the integrity token of p with the hash h named alg.
+func sri(alg string, h hash.Hash, p string) string {
+	h.Write([]byte(p))
+	return alg + "-" + base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func TestWithIntegrity(t *testing.T) {
+	const body = `{"name":"manifest","version":3}`
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Disposition", `attachment; filename="manifest.json"`)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	good256 := sri("sha256", sha256.New(), body)
+	good384 := sri("sha384", sha512.New384(), body)
+	good512 := sri("sha512", sha512.New(), body)
+	bad256 := sri("sha256", sha256.New(), "other")
+	bad384 := sri("sha384", sha512.New384(), "other")
+	bad512 := sri("sha512", sha512.New(), "other")
+
+	tests := []struct {
+		name      string
+		integrity string
+		computed  string // set on a mismatch
+	}{
+		{"sha256", good256, ""},
+		{"sha384", good384, ""},
+		{"sha512", good512, ""},
+		{"options", good384 + "?ct=application/json", ""},
+		{"spaces", "  " + good256 + "\t", ""},
+		{"mismatch", bad384, good384},
+		{"alternatives", bad384 + " " + good384, ""},
+		{"alternatives in any order", good512 + " " + bad512, ""},
+		{"strongest only", good256 + " " + bad512, good512},
+		{"strongest matches", bad256 + " " + good384, ""},
+		{"unknown algorithms ignored", "md5-abc sha1-xyz " + good256, ""},
+	}
+	for _, tt := range tests {
+		got, err := Bytes(srv.URL+"/manifest", WithIntegrity(tt.integrity))
+		if tt.computed == "" {
+			if err != nil || string(got) != body {
+				t.Errorf("%s: Bytes: got %q, %v", tt.name, got, err)
+			}
+		} else {
+			var ierr *IntegrityError
+			if !errors.As(err, &ierr) || ierr.Computed != tt.computed || ierr.URL != srv.URL+"/manifest" {
+				t.Errorf("%s: Bytes: got %v, want an *IntegrityError computing %s", tt.name, err, tt.computed)
+			} else if !strings.Contains(err.Error(), "expected "+ierr.Expected[0]) || !strings.Contains(err.Error(), "computed "+tt.computed) {
+				t.Errorf("%s: got message %q", tt.name, err)
+			}
+		}
+
+		var v struct{ Name string }
+		err = JSON(srv.URL+"/manifest", &v, WithIntegrity(tt.integrity))
+		var ierr *IntegrityError
+		if tt.computed == "" && (err != nil || v.Name != "manifest") || tt.computed != "" && !errors.As(err, &ierr) {
+			t.Errorf("%s: JSON: got %+v, %v", tt.name, v, err)
+		}
+
+		dir := t.TempDir()
+		paths, err := DownloadTo([]string{srv.URL + "/manifest"}, dir, WithIntegrity(tt.integrity))
+		if tt.computed == "" {
+			if err != nil || len(paths) != 1 || !strings.HasSuffix(paths[0], "manifest.json") {
+				t.Errorf("%s: DownloadTo: got %v, %v", tt.name, paths, err)
+			}
+			continue
+		}
+		if !errors.As(err, &ierr) || paths[0] != "" {
+			t.Errorf("%s: DownloadTo: got %v, %v", tt.name, paths, err)
+		}
+		if files := spilled(t, dir); len(files) != 0 {
+			t.Errorf("%s: DownloadTo left %v", tt.name, files)
+		}
+	}
+
+	// Each file of a batch is checked against the same integrity.
+	var files []File
+	err := Files([]string{srv.URL + "/a", srv.URL + "/b"}, &files, WithIntegrity(good384))
+	if err != nil || len(files) != 2 {
+		t.Errorf("Files: got %d files, %v", len(files), err)
+	}
+	err = Files([]string{srv.URL + "/a", srv.URL + "/b"}, &files, WithIntegrity(bad384))
+	var ierr *IntegrityError
+	if !errors.As(err, &ierr) {
+		t.Errorf("Files: got %v, want an *IntegrityError", err)
+	}
+
+	// A malformed integrity fails before the request is sent.
+	atomic.StoreInt32(&requests, 0)
+	for _, s := range []string{
+		"",
+		"sha256",
+		"sha256-",
+		"sha256-not*base64",
+		"sha384-" + base64.StdEncoding.EncodeToString([]byte("short")),
+		good256[:len(good256)-4],
+		"md5-1B2M2Y8AsgTpgAmY7PhCfg==",
+		"sha384-" + good256[len("sha256-"):],
+	} {
+		if _, err := Bytes(srv.URL, WithIntegrity(s)); err == nil || !strings.Contains(err.Error(), "integrity") {
+			t.Errorf("%q: got %v", s, err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("got %d requests for malformed integrities", n)
+	}
+}
+
+func TestParseIntegrity(t *testing.T) {
+	empty256 := sri("sha256", sha256.New(), "")
+	empty512 := sri("sha512", sha512.New(), "")
+	tests := []struct {
+		s       string
+		alg     string
+		digests int
+		err     string
+	}{
+		{empty256, "sha256", 1, ""},
+		{empty256 + " " + empty256, "sha256", 2, ""},
+		{empty256 + " " + empty512, "sha512", 1, ""},
+		{"sha512-bad " + empty256, "", 0, "malformed integrity token \"sha512-bad\""},
+		{"sha1-abc", "", 0, "no supported hash"},
+		{"SHA256-" + empty256[len("sha256-"):], "", 0, "no supported hash"},
+	}
+	for _, tt := range tests {
+		in, err := parseIntegrity(tt.s)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: got %v, want %q", tt.s, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || in.alg != tt.alg || len(in.digests) != tt.digests {
+			t.Errorf("%q: got %+v, %v", tt.s, in, err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package httpclient
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// WithLocalSchemes lets the client fetch file: and data: URLs as well, so
+// that helpers such as Bytes and JSON accept a local path or inline data
+// where a URL is expected:
+//
+//	client.JSON("file:///etc/app/config.json", &cfg)
+//	client.String("data:text/plain;base64,aGVsbG8=")
+//
+// A missing file is answered with a 404 response, so that the error
+// matches ErrNotFound. It is off by default, since a URL coming from
+// untrusted input could otherwise read local files. For the same reason,
+// a redirect to a file: or data: URL is refused.
+func WithLocalSchemes() Option {
+	return func(c *httpClient) {
+		c.local = true
+	}
+}
+
+// localTransport answers file: and data: requests, and passes the others
+// on.
+type localTransport struct {
+	next http.RoundTripper
+}
+
+func (t *localTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.URL.Scheme {
+	case "file", "data":
+		if req.Response != nil {
+			return nil, fmt.Errorf("httpclient: redirect to a %s URL refused", req.URL.Scheme)
+		}
+		if req.URL.Scheme == "file" {
+			return serveFile(req)
+		}
+		return serveData(req)
+	}
+	return t.next.RoundTrip(req)
+}
+
+func serveFile(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		return newResponse(req, http.StatusMethodNotAllowed, nil, nil), nil
+	}
+	if req.URL.Host != "" && req.URL.Host != "localhost" {
+		return nil, fmt.Errorf("httpclient: file URL with host %q", req.URL.Host)
+	}
+	f, err := os.Open(filepath.FromSlash(req.URL.Path))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return newResponse(req, http.StatusNotFound, nil, nil), nil
+	case errors.Is(err, os.ErrPermission):
+		return newResponse(req, http.StatusForbidden, nil, nil), nil
+	case err != nil:
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err == nil && fi.IsDir() {
+		err = fmt.Errorf("httpclient: %s is a directory", req.URL.Path)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	resp := newResponse(req, http.StatusOK, nil, nil)
+	resp.ContentLength = fi.Size()
+	resp.Header.Set("Content-Length", strconv.FormatInt(fi.Size(), 10))
+	resp.Header.Set("Last-Modified", fi.ModTime().UTC().Format(http.TimeFormat))
+	if ct := mime.TypeByExtension(filepath.Ext(fi.Name())); ct != "" {
+		resp.Header.Set("Content-Type", ct)
+	}
+	if req.Method == "HEAD" {
+		f.Close()
+	} else {
+		resp.Body = f
+	}
+	return resp, nil
+}
+
+// serveData decodes a data URL (RFC 2397).
+func serveData(req *http.Request) (*http.Response, error) {
+	raw := req.URL.Opaque
+	if raw == "" {
+		raw = strings.TrimPrefix(req.URL.Path, "/")
+	}
+	if req.URL.RawQuery != "" {
+		raw += "?" + req.URL.RawQuery
+	}
+	i := strings.IndexByte(raw, ',')
+	if i < 0 {
+		return nil, errors.New("httpclient: malformed data URL: no comma")
+	}
+	mediaType, data := raw[:i], raw[i+1:]
+	isBase64 := false
+	if strings.HasSuffix(strings.ToLower(mediaType), ";base64") {
+		mediaType, isBase64 = mediaType[:len(mediaType)-len(";base64")], true
+	}
+	mediaType, err := neturl.PathUnescape(mediaType)
+	if err != nil {
+		return nil, fmt.Errorf("httpclient: malformed data URL: %v", err)
+	}
+	if mediaType == "" || strings.HasPrefix(mediaType, ";") {
+		mediaType = "text/plain;charset=US-ASCII" + mediaType
+	}
+
+	var body []byte
+	if data, err = neturl.PathUnescape(data); err == nil {
+		if isBase64 {
+			data = strings.TrimRight(strings.Join(strings.Fields(data), ""), "=")
+			body, err = base64.RawStdEncoding.DecodeString(data)
+		} else {
+			body = []byte(data)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("httpclient: malformed data URL: %v", err)
+	}
+
+	resp := newResponse(req, http.StatusOK, nil, body)
+	resp.Header.Set("Content-Type", mediaType)
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	if req.Method == "HEAD" {
+		resp.Body = ioutil.NopCloser(strings.NewReader(""))
+	}
+	return resp, nil
+}
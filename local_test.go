@@ -0,0 +1,113 @@
+package httpclient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(path, []byte(`{"name":"app"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := New(WithLocalSchemes())
+
+	var cfg struct{ Name string }
+	if err := c.JSON("file://"+filepath.ToSlash(path), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "app" {
+		t.Errorf("got %+v", cfg)
+	}
+
+	resp, err := c.Get("file://" + filepath.ToSlash(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("got Content-Type %q", ct)
+	}
+	if resp.ContentLength != 14 {
+		t.Errorf("got Content-Length %d, want 14", resp.ContentLength)
+	}
+
+	_, err = c.Bytes("file://" + filepath.ToSlash(filepath.Join(dir, "missing.json")))
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing file: got %v, want ErrNotFound", err)
+	}
+	if _, err := c.Bytes("file://" + filepath.ToSlash(dir)); err == nil {
+		t.Error("directory: got no error")
+	}
+	if _, err := c.Bytes("file://example.com/etc/hosts"); err == nil {
+		t.Error("remote host: got no error")
+	}
+}
+
+func TestLocalData(t *testing.T) {
+	c := New(WithLocalSchemes())
+	tests := []struct {
+		url, body, contentType string
+	}{
+		{"data:text/plain;base64,aGVsbG8=", "hello", "text/plain"},
+		{"data:;base64,aGVsbG8", "hello", "text/plain;charset=US-ASCII"},
+		{"data:,hello%20world%2C%20%C3%A9", "hello world, é", "text/plain;charset=US-ASCII"},
+		{"data:application/json,%7B%22a%22%3A1%7D", `{"a":1}`, "application/json"},
+		{"data:text/plain;charset=utf-8,a?b", "a?b", "text/plain;charset=utf-8"},
+	}
+	for _, tt := range tests {
+		resp, err := c.Get(tt.url)
+		if err != nil {
+			t.Errorf("%s: %v", tt.url, err)
+			continue
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != tt.body {
+			t.Errorf("%s: got %q, want %q", tt.url, body, tt.body)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("%s: got Content-Type %q, want %q", tt.url, ct, tt.contentType)
+		}
+	}
+}
+
+func TestLocalDataMalformed(t *testing.T) {
+	c := New(WithLocalSchemes())
+	for _, u := range []string{
+		"data:text/plain;base64",
+		"data:;base64,!!!not base64",
+		"data:,bad%zzescape",
+	} {
+		_, err := c.Bytes(u)
+		if err == nil || !strings.Contains(err.Error(), "malformed data URL") {
+			t.Errorf("%s: got %v, want a malformed data URL error", u, err)
+		}
+	}
+}
+
+func TestLocalSchemesOffByDefault(t *testing.T) {
+	if _, err := New().Bytes("data:,hello"); err == nil {
+		t.Error("got no error")
+	}
+}
+
+func TestLocalRedirectRefused(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret")
+	ioutil.WriteFile(path, []byte("secret"), 0o600)
+	for _, target := range []string{"file://" + filepath.ToSlash(path), "data:,secret"} {
+		srv := httptest.NewServer(http.RedirectHandler(target, http.StatusFound))
+		body, err := New(WithLocalSchemes()).String(srv.URL)
+		srv.Close()
+		if err == nil || !strings.Contains(err.Error(), "redirect to a") {
+			t.Errorf("%s: got %q, %v, want the redirect refused", target, body, err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package httpclient
+
+// Logger receives structured entries describing the requests a client makes.
+// It is deliberately small so that log/slog, zap, logrus and friends can be
+// adapted to it in a few lines.
+type Logger interface {
+	Log(level, msg string, fields map[string]interface{})
+}
+
+// Log levels, from the most to the least verbose.
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+var levelRank = map[string]int{
+	LevelDebug: 0,
+	LevelInfo:  1,
+	LevelWarn:  2,
+	LevelError: 3,
+}
+
+// WithLogger sets the logger the client reports request starts (debug),
+// completions (info) and failures (error) to.
+func WithLogger(l Logger) Option {
+	return func(c *httpClient) {
+		c.logger = l
+	}
+}
+
+// WithLogLevel sets the minimum level of the entries passed to the logger.
+// The default is LevelInfo.
+func WithLogLevel(level string) Option {
+	return func(c *httpClient) {
+		c.logLevel = level
+	}
+}
+
+// logs reports whether an entry at level would reach the logger, so callers
+// can skip building fields for entries that are going to be dropped.
+func (c *httpClient) logs(level string) bool {
+	return c.logger != nil && levelRank[level] >= levelRank[c.logLevel]
+}
+
+func (c *httpClient) log(level, msg string, fields map[string]interface{}) {
+	if c.logs(level) {
+		c.logger.Log(level, msg, fields)
+	}
+}
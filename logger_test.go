@@ -0,0 +1,91 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggerFiles(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, "content of "+r.URL.Path)
+	}))
+	defer srv.Close()
+	urls := []string{srv.URL + "/a", srv.URL + "/b?token=secret", srv.URL + "/missing"}
+
+	logs := &recordLogger{}
+	var files []File
+	if err := New(WithLogger(logs), WithLogLevel(LevelDebug)).Files(urls, &files); err == nil {
+		t.Fatal("got no error")
+	}
+
+	count := map[string]int{}
+	var completed []string
+	for _, e := range logs.entries {
+		count[e.level+" "+e.msg]++
+		if strings.Contains(e.fields["url"].(string), "secret") {
+			t.Errorf("%s: url %v not redacted", e.msg, e.fields["url"])
+		}
+		if e.fields["method"] != "GET" {
+			t.Errorf("%s: method %v", e.msg, e.fields["method"])
+		}
+		if e.msg == "request complete" {
+			completed = append(completed, e.fields["url"].(string))
+			if _, ok := e.fields["duration"].(time.Duration); !ok {
+				t.Errorf("duration %v is not a time.Duration", e.fields["duration"])
+			}
+			want := int64(len("content of /a"))
+			if e.fields["status"] == http.StatusNotFound {
+				want = 0
+			}
+			if e.fields["bytes"] != want {
+				t.Errorf("%v: logged %v bytes, want %d", e.fields["url"], e.fields["bytes"], want)
+			}
+		}
+		if e.msg == "request error" && e.fields["status"] != http.StatusNotFound {
+			t.Errorf("request error with status %v", e.fields["status"])
+		}
+	}
+	want := map[string]int{
+		"debug request start":   3,
+		"info request complete": 3,
+		"error request error":   1,
+	}
+	for k, n := range want {
+		if count[k] != n {
+			t.Errorf("got %d %q entries, want %d", count[k], k, n)
+		}
+	}
+	if len(count) != len(want) {
+		t.Errorf("got entries %v, want %v", count, want)
+	}
+	sort.Strings(completed)
+	if !strings.HasPrefix(completed[1], srv.URL+"/b?token=") || !strings.Contains(completed[1], "REDACTED") {
+		t.Errorf("got url %q", completed[1])
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	logs := &recordLogger{}
+	if _, err := New(WithLogger(logs)).Bytes(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if len(logs.entries) != 1 || logs.entries[0].level != LevelInfo {
+		t.Errorf("got %s, want one info entry", logs)
+	}
+	logs = &recordLogger{}
+	New(WithLogger(logs), WithLogLevel(LevelError)).Bytes(srv.URL)
+	if len(logs.entries) != 0 {
+		t.Errorf("got %s, want no entry", logs)
+	}
+}
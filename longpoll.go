@@ -0,0 +1,139 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultLongPollTimeout is the time limit of a long-poll request.
+	defaultLongPollTimeout = 90 * time.Second
+
+	// longPollBackoff and maxLongPollBackoff bound the wait after a failed
+	// long-poll request.
+	longPollBackoff    = time.Second
+	maxLongPollBackoff = 30 * time.Second
+)
+
+// WithLongPollTimeout sets the time limit of each request of LongPoll,
+// 90 seconds by default. It should be longer than the server holds a
+// request.
+func WithLongPollTimeout(d time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.longPollTimeout = d
+	}
+}
+
+// WithLongPollCursor makes LongPoll send back the named header, e.g.
+// "X-Cursor", with the value of the last response that had it, so that the
+// server can resume where the previous request stopped.
+func WithLongPollCursor(header string) RequestOption {
+	return func(o *requestOptions) {
+		o.longPollCursor = http.CanonicalHeaderKey(header)
+	}
+}
+
+// LongPoll requests url in a loop until ctx is done or handle returns an
+// error, and calls handle with the body of every response that has one.
+// After a 204 response, an empty body or a request that timed out it asks
+// again at once. After a network error or a 429 or 5xx response it waits
+// one second, doubling up to 30 seconds while errors keep coming. LongPoll
+// returns the error of handle or of ctx, and any other error, such as a
+// 4xx response or a request that cannot be made, since asking again would
+// not help.
+func (c *httpClient) LongPoll(ctx context.Context, url string, handle func(body []byte) error, opts ...RequestOption) error {
+	o := newRequestOptions(opts)
+	timeout := o.longPollTimeout
+	if timeout <= 0 {
+		timeout = defaultLongPollTimeout
+	}
+	var cursor string
+	backoff := longPollBackoff
+	for {
+		body, next, err := c.longPoll(ctx, url, timeout, cursor, o)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if next != "" {
+			cursor = next
+		}
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				continue
+			}
+			if !longPollTransient(err) {
+				return err
+			}
+			if c.logs(LevelWarn) {
+				c.log(LevelWarn, "long poll failed", map[string]interface{}{
+					"url":   c.redact.urlString(url),
+					"error": err.Error(),
+					"wait":  backoff.String(),
+				})
+			}
+			if err := sleep(ctx, backoff); err != nil {
+				return err
+			}
+			if backoff *= 2; backoff > maxLongPollBackoff {
+				backoff = maxLongPollBackoff
+			}
+			continue
+		}
+		backoff = longPollBackoff
+		if len(body) > 0 {
+			if err := handle(body); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// longPollTransient reports whether the failed request of LongPoll is worth
+// making again: a network error, or a 429 or 5xx response.
+func longPollTransient(err error) bool {
+	var herr *Error
+	if errors.As(err, &herr) && herr.StatusCode != 0 {
+		return herr.StatusCode == http.StatusTooManyRequests || herr.StatusCode >= 500
+	}
+	return transient(err)
+}
+
+// longPoll makes one request of LongPoll and returns the body and the
+// cursor header of the response.
+func (c *httpClient) longPoll(ctx context.Context, url string, timeout time.Duration, cursor string, o *requestOptions) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ro := *o
+	ro.ctx = ctx
+	req, err := c.newRequest("GET", url, nil, &ro)
+	if err != nil {
+		return nil, "", err
+	}
+	if o.longPollCursor != "" && cursor != "" {
+		req.Header.Set(o.longPollCursor, cursor)
+	}
+	resp, err := c.do(req, &ro)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	var next string
+	if o.longPollCursor != "" {
+		next = resp.Header.Get(o.longPollCursor)
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, next, nil
+	}
+	if !c.accepted(resp, o) {
+		return nil, next, c.err(resp, "")
+	}
+	body, err := c.readAll(resp)
+	return body, next, err
+}
+
+// LongPoll long-polls url with the default client.
+func LongPoll(ctx context.Context, url string, handle func(body []byte) error, opts ...RequestOption) error {
+	return defaultClient().LongPoll(ctx, url, handle, opts...)
+}
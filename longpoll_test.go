@@ -0,0 +1,204 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// longPollServer answers each request with the next of steps: "204",
+// another status such as "500", "hang" until the client gives up, or a body
+// to send. Bodies come with
+// the next cursor in X-Cursor. It keeps the cursors it was sent.
+type longPollServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	steps   []string
+	cursors []string
+}
+
+func newLongPollServer(steps ...string) *longPollServer {
+	s := &longPollServer{steps: steps}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.cursors = append(s.cursors, r.Header.Get("X-Cursor"))
+		n := len(s.cursors)
+		step := "204"
+		if n <= len(s.steps) {
+			step = s.steps[n-1]
+		}
+		s.mu.Unlock()
+		switch step {
+		case "204":
+			w.WriteHeader(http.StatusNoContent)
+		case "hang":
+			<-r.Context().Done()
+		default:
+			if code, err := strconv.Atoi(step); err == nil {
+				http.Error(w, "oops", code)
+				return
+			}
+			w.Header().Set("X-Cursor", fmt.Sprint(n))
+			io.WriteString(w, step)
+		}
+	}))
+	return s
+}
+
+func (s *longPollServer) sent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.cursors...)
+}
+
+func TestLongPoll(t *testing.T) {
+	srv := newLongPollServer("a", "204", "hang", "b", "500", "c", "d")
+	defer srv.Close()
+	logs := &recordLogger{}
+	c := New(WithLogger(logs))
+
+	stop := errors.New("stop")
+	var bodies []string
+	start := time.Now()
+	err := c.LongPoll(context.Background(), srv.URL+"/events", func(body []byte) error {
+		bodies = append(bodies, string(body))
+		if string(body) == "d" {
+			return stop
+		}
+		return nil
+	}, WithLongPollTimeout(200*time.Millisecond), WithLongPollCursor("x-cursor"))
+	if err != stop {
+		t.Fatalf("got %v, want the error of the handler", err)
+	}
+	if got := strings.Join(bodies, " "); got != "a b c d" {
+		t.Errorf("got bodies %q", got)
+	}
+	// The cursor of a body is sent back until the next one.
+	if got, want := fmt.Sprint(srv.sent()), "[ 1 1 1 4 4 6]"; got != want {
+		t.Errorf("got cursors %s, want %s", got, want)
+	}
+	// A timeout reconnects at once, the 500 after a second.
+	if d := time.Since(start); d < time.Second || d > 3*time.Second {
+		t.Errorf("took %v, want a second of backoff", d)
+	}
+	var warned int
+	for _, e := range logs.entries {
+		if e.msg == "long poll failed" {
+			if warned++; e.level != LevelWarn || e.fields["wait"] != "1s" {
+				t.Errorf("got %+v", e)
+			}
+		}
+	}
+	if warned != 1 {
+		t.Errorf("got %d warnings, want 1:\n%s", warned, logs)
+	}
+}
+
+func TestLongPollCancel(t *testing.T) {
+	srv := newLongPollServer("a", "hang")
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var bodies []string
+	done := make(chan error)
+	go func() {
+		done <- LongPoll(ctx, srv.URL, func(body []byte) error {
+			bodies = append(bodies, string(body))
+			return nil
+		})
+	}()
+	for len(srv.sent()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("LongPoll did not return")
+	}
+	if len(bodies) != 1 || bodies[0] != "a" {
+		t.Errorf("got bodies %q", bodies)
+	}
+	// Without WithLongPollCursor the cursor is not sent.
+	if got := fmt.Sprint(srv.sent()); got != "[ ]" {
+		t.Errorf("got cursors %s", got)
+	}
+}
+
+func TestLongPollPermanentErrors(t *testing.T) {
+	srv := newLongPollServer("404")
+	defer srv.Close()
+	robots := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "User-agent: *\nDisallow: /\n")
+	}))
+	defer robots.Close()
+	expired := New()
+	expired.SetBearerToken(jwtExp(-time.Hour))
+	expired.SetTokenExpiryCheck(time.Minute)
+
+	tests := []struct {
+		name string
+		c    *httpClient
+		url  string
+		opts []RequestOption
+		want func(error) bool
+	}{
+		{"bad URL", New(), "http://%zz/", nil, func(err error) bool { return err != nil }},
+		{"missing path parameter", New(), robots.URL + "/{a}/{b}", []RequestOption{WithPathParam("a", "1")}, func(err error) bool {
+			return err != nil && strings.Contains(err.Error(), "missing path parameter")
+		}},
+		{"bad option", New(), robots.URL, []RequestOption{WithQueryStruct(42)}, func(err error) bool { return err != nil }},
+		{"robots", New(WithRobotsPolicy("bot")), robots.URL + "/events", nil, func(err error) bool {
+			return errors.Is(err, ErrDisallowedByRobots)
+		}},
+		{"expired token", expired, robots.URL, nil, func(err error) bool {
+			var terr *TokenExpiredError
+			return errors.As(err, &terr)
+		}},
+		{"404", New(), srv.URL, nil, func(err error) bool {
+			var herr *Error
+			return errors.As(err, &herr) && herr.StatusCode == http.StatusNotFound
+		}},
+	}
+	for _, tt := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		start := time.Now()
+		err := tt.c.LongPoll(ctx, tt.url, func(body []byte) error {
+			t.Errorf("%s: got body %q", tt.name, body)
+			return nil
+		}, tt.opts...)
+		cancel()
+		if !tt.want(err) || errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: got %v", tt.name, err)
+		}
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("%s: took %v, want no backoff", tt.name, d)
+		}
+	}
+	if n := len(srv.sent()); n != 1 {
+		t.Errorf("404: got %d requests, want 1", n)
+	}
+}
+
+func TestLongPollTooManyRequests(t *testing.T) {
+	srv := newLongPollServer("429", "a")
+	defer srv.Close()
+	stop := errors.New("stop")
+	err := LongPoll(context.Background(), srv.URL, func(body []byte) error {
+		return stop
+	})
+	if err != stop || len(srv.sent()) != 2 {
+		t.Errorf("got %v after %d requests, want a retry after the 429", err, len(srv.sent()))
+	}
+}
@@ -0,0 +1,160 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+)
+
+// Post issues a POST to the specified URL with the body and its content
+// type. It returns an http.Response for further processing.
+func (c *httpClient) Post(url, contentType string, body io.Reader, opts ...RequestOption) (*http.Response, error) {
+	return c.sendBody("POST", url, contentType, body, newRequestOptions(opts))
+}
+
+// Put issues a PUT to the specified URL with the body and its content type.
+func (c *httpClient) Put(url, contentType string, body io.Reader, opts ...RequestOption) (*http.Response, error) {
+	return c.sendBody("PUT", url, contentType, body, newRequestOptions(opts))
+}
+
+// Patch issues a PATCH to the specified URL with the body and its content
+// type.
+func (c *httpClient) Patch(url, contentType string, body io.Reader, opts ...RequestOption) (*http.Response, error) {
+	return c.sendBody("PATCH", url, contentType, body, newRequestOptions(opts))
+}
+
+// Delete issues a DELETE to the specified URL.
+func (c *httpClient) Delete(url string, opts ...RequestOption) (*http.Response, error) {
+	return c.send("DELETE", url, nil, newRequestOptions(opts))
+}
+
+// PostForm issues a POST to the specified URL with data URL-encoded as the
+// body.
+func (c *httpClient) PostForm(url string, data neturl.Values, opts ...RequestOption) (*http.Response, error) {
+	return c.Post(url, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()), opts...)
+}
+
+// PostJSON issues a POST to the specified URL with body encoded as JSON,
+// and decodes the JSON response into result. result may be nil to ignore
+// the response, which may also have no body, e.g. with a 204 status. A
+// response with a status other than 2xx fails with an *Error.
+func (c *httpClient) PostJSON(url string, body, result interface{}, opts ...RequestOption) error {
+	return c.sendJSON("POST", url, body, result, opts)
+}
+
+// PutJSON is PostJSON with a PUT.
+func (c *httpClient) PutJSON(url string, body, result interface{}, opts ...RequestOption) error {
+	return c.sendJSON("PUT", url, body, result, opts)
+}
+
+// PatchJSON is PostJSON with a PATCH.
+func (c *httpClient) PatchJSON(url string, body, result interface{}, opts ...RequestOption) error {
+	return c.sendJSON("PATCH", url, body, result, opts)
+}
+
+// DeleteJSON issues a DELETE to the specified URL and decodes the JSON
+// response, if any, into result, as PostJSON does.
+func (c *httpClient) DeleteJSON(url string, result interface{}, opts ...RequestOption) error {
+	o := newRequestOptions(opts)
+	resp, err := c.send("DELETE", url, nil, o)
+	if err != nil {
+		return err
+	}
+	return c.decodeJSON(resp, result, o)
+}
+
+// sendBody makes a request with the body, setting its Content-Type unless
+// the options already do.
+func (c *httpClient) sendBody(method, url, contentType string, body io.Reader, o *requestOptions) (*http.Response, error) {
+	if contentType != "" && o.header.Get("Content-Type") == "" {
+		WithRequestHeader("Content-Type", contentType)(o)
+	}
+	return c.send(method, url, body, o)
+}
+
+func (c *httpClient) sendJSON(method, url string, body, result interface{}, opts []RequestOption) error {
+	p, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	o := newRequestOptions(opts)
+	resp, err := c.sendBody(method, url, "application/json", bytes.NewReader(p), o)
+	if err != nil {
+		return err
+	}
+	return c.decodeJSON(resp, result, o)
+}
+
+// decodeJSON decodes the JSON body of resp, if any, into result, unless it
+// is nil, and closes it.
+func (c *httpClient) decodeJSON(resp *http.Response, result interface{}, o *requestOptions) error {
+	defer resp.Body.Close()
+	if !c.accepted(resp, o) {
+		return c.err(resp, "")
+	}
+	p, err := c.readAll(resp)
+	if err != nil {
+		return c.bodyFailed(resp, err)
+	}
+	if result == nil || len(bytes.TrimSpace(p)) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(p))
+	if o.strict {
+		dec.DisallowUnknownFields()
+	}
+	err = dec.Decode(result)
+	if _, ok := err.(*json.SyntaxError); ok {
+		err = c.err(resp, "JSON syntax error at "+c.redact.url(resp.Request.URL).String())
+	}
+	return c.bodyFailed(resp, err)
+}
+
+// Post issues a POST with the default client.
+func Post(url, contentType string, body io.Reader, opts ...RequestOption) (*http.Response, error) {
+	return defaultClient().Post(url, contentType, body, opts...)
+}
+
+// Put issues a PUT with the default client.
+func Put(url, contentType string, body io.Reader, opts ...RequestOption) (*http.Response, error) {
+	return defaultClient().Put(url, contentType, body, opts...)
+}
+
+// Patch issues a PATCH with the default client.
+func Patch(url, contentType string, body io.Reader, opts ...RequestOption) (*http.Response, error) {
+	return defaultClient().Patch(url, contentType, body, opts...)
+}
+
+// Delete issues a DELETE with the default client.
+func Delete(url string, opts ...RequestOption) (*http.Response, error) {
+	return defaultClient().Delete(url, opts...)
+}
+
+// PostForm issues a form POST with the default client.
+func PostForm(url string, data neturl.Values, opts ...RequestOption) (*http.Response, error) {
+	return defaultClient().PostForm(url, data, opts...)
+}
+
+// PostJSON issues a JSON POST with the default client.
+func PostJSON(url string, body, result interface{}, opts ...RequestOption) error {
+	return defaultClient().PostJSON(url, body, result, opts...)
+}
+
+// PutJSON issues a JSON PUT with the default client.
+func PutJSON(url string, body, result interface{}, opts ...RequestOption) error {
+	return defaultClient().PutJSON(url, body, result, opts...)
+}
+
+// PatchJSON issues a JSON PATCH with the default client.
+func PatchJSON(url string, body, result interface{}, opts ...RequestOption) error {
+	return defaultClient().PatchJSON(url, body, result, opts...)
+}
+
+// DeleteJSON issues a DELETE with the default client and decodes the JSON
+// response.
+func DeleteJSON(url string, result interface{}, opts ...RequestOption) error {
+	return defaultClient().DeleteJSON(url, result, opts...)
+}
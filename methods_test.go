@@ -0,0 +1,147 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"strings"
+	"testing"
+)
+
+// methodServer answers /none with a 204, /blank with an empty 200, /fail
+// with a 422 and its JSON error, and echoes the other requests as
+// echoHandler does.
+func methodServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/none":
+			w.WriteHeader(http.StatusNoContent)
+		case "/blank":
+		case "/fail":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"error":"invalid name"}`))
+		default:
+			echoHandler(w, r)
+		}
+	}))
+}
+
+func TestMethods(t *testing.T) {
+	srv := methodServer()
+	defer srv.Close()
+	c := New()
+	form := neturl.Values{"name": {"ann"}, "tag": {"a", "b"}}
+
+	tests := []struct {
+		name, method, body, contentType string
+		send                            func() (*http.Response, error)
+	}{
+		{"Post", "POST", "hello", "text/plain", func() (*http.Response, error) {
+			return c.Post(srv.URL+"/x", "text/plain", strings.NewReader("hello"))
+		}},
+		{"Put", "PUT", "<a/>", "application/xml", func() (*http.Response, error) {
+			return c.Put(srv.URL+"/x", "application/xml", strings.NewReader("<a/>"))
+		}},
+		{"Patch", "PATCH", "[]", "application/json-patch+json", func() (*http.Response, error) {
+			return c.Patch(srv.URL+"/x", "application/json-patch+json", strings.NewReader("[]"))
+		}},
+		{"Delete", "DELETE", "", "", func() (*http.Response, error) {
+			return c.Delete(srv.URL + "/x")
+		}},
+		{"PostForm", "POST", "name=ann&tag=a&tag=b", "application/x-www-form-urlencoded", func() (*http.Response, error) {
+			return c.PostForm(srv.URL+"/x", form)
+		}},
+		{"Content-Type option", "POST", "hello", "text/markdown", func() (*http.Response, error) {
+			return c.Post(srv.URL+"/x", "text/plain", strings.NewReader("hello"), WithRequestHeader("Content-Type", "text/markdown"))
+		}},
+	}
+	for _, tt := range tests {
+		resp, err := tt.send()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var got echo
+		err = json.NewDecoder(resp.Body).Decode(&got)
+		resp.Body.Close()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got.Method != tt.method || got.Body != tt.body || got.Header.Get("Content-Type") != tt.contentType {
+			t.Errorf("%s: got %s %q of type %q, want %s %q of type %q", tt.name, got.Method, got.Body, got.Header.Get("Content-Type"), tt.method, tt.body, tt.contentType)
+		}
+	}
+
+	// These return the response of any status, for the caller to check.
+	resp, err := c.Post(srv.URL+"/fail", "text/plain", strings.NewReader("x"))
+	if err != nil || resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("fail: got %v, %v", resp, err)
+	} else {
+		resp.Body.Close()
+	}
+}
+
+func TestJSONMethods(t *testing.T) {
+	srv := methodServer()
+	defer srv.Close()
+	c := New()
+	in := map[string]interface{}{"name": "ann", "age": 7}
+
+	tests := []struct {
+		name, method string
+		send         func(url string, result interface{}) error
+	}{
+		{"PostJSON", "POST", func(url string, result interface{}) error { return c.PostJSON(url, in, result) }},
+		{"PutJSON", "PUT", func(url string, result interface{}) error { return c.PutJSON(url, in, result) }},
+		{"PatchJSON", "PATCH", func(url string, result interface{}) error { return c.PatchJSON(url, in, result) }},
+		{"DeleteJSON", "DELETE", func(url string, result interface{}) error { return c.DeleteJSON(url, result) }},
+	}
+	for _, tt := range tests {
+		var got echo
+		if err := tt.send(srv.URL+"/x", &got); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		wantBody, wantType := `{"age":7,"name":"ann"}`, "application/json"
+		if tt.method == "DELETE" {
+			wantBody, wantType = "", ""
+		}
+		if got.Method != tt.method || got.Body != wantBody || got.Header.Get("Content-Type") != wantType {
+			t.Errorf("%s: got %s %q of type %q", tt.name, got.Method, got.Body, got.Header.Get("Content-Type"))
+		}
+
+		// A response without a body leaves the result alone, and a nil
+		// result ignores the response.
+		for _, path := range []string{"/none", "/blank"} {
+			kept := echo{Method: "kept"}
+			if err := tt.send(srv.URL+path, &kept); err != nil || kept.Method != "kept" {
+				t.Errorf("%s %s: got %+v, %v", tt.name, path, kept, err)
+			}
+			if err := tt.send(srv.URL+path, nil); err != nil {
+				t.Errorf("%s %s with nil: %v", tt.name, path, err)
+			}
+		}
+		if err := tt.send(srv.URL+"/x", nil); err != nil {
+			t.Errorf("%s with nil: %v", tt.name, err)
+		}
+
+		// A status other than 2xx fails with an *Error holding the body.
+		err := tt.send(srv.URL+"/fail", &got)
+		var herr *Error
+		if !errors.As(err, &herr) || herr.StatusCode != http.StatusUnprocessableEntity || string(herr.Body) != `{"error":"invalid name"}` {
+			t.Errorf("%s fail: got %v, want a 422 *Error with the body", tt.name, err)
+		}
+		if err := tt.send(srv.URL+"/missing", nil); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s missing: got %v, want ErrNotFound", tt.name, err)
+		}
+	}
+
+	// A body that cannot be encoded is not sent.
+	if err := c.PostJSON(srv.URL+"/x", make(chan int), nil); err == nil {
+		t.Error("unencodable body: got no error")
+	}
+}
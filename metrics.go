@@ -0,0 +1,140 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements of the requests a client makes. It is
+// called from every method, including each download of Files, so
+// implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveRequest is called for every response once its body has been
+	// closed.
+	ObserveRequest(method, host string, status int, duration time.Duration, bytesIn, bytesOut int64)
+
+	// IncRetry is called every time a request is retried.
+	IncRetry(host string)
+
+	// IncError is called for every failed request with one of the
+	// ErrorKind constants.
+	IncError(host, kind string)
+}
+
+// Kinds of errors reported to Metrics.IncError.
+const (
+	// ErrorKindNetwork is a request that did not get a response.
+	ErrorKindNetwork = "network"
+	// ErrorKindStatus is a response with an unexpected status code.
+	ErrorKindStatus = "status"
+	// ErrorKindBody is a response whose body could not be read or decoded.
+	ErrorKindBody = "body"
+)
+
+// InFlightMetrics is implemented by Metrics that also track the number of
+// requests in progress. A request is in flight from the moment it is sent
+// until it fails or its response body is closed.
+type InFlightMetrics interface {
+	IncInFlight(host string)
+	DecInFlight(host string)
+}
+
+// WithMetrics sets the Metrics the client reports to. If m also implements
+// InFlightMetrics it is told about requests starting and finishing.
+func WithMetrics(m Metrics) Option {
+	return func(c *httpClient) {
+		if m == nil {
+			m = noMetrics{}
+		}
+		c.metrics = m
+		c.inFlight, _ = m.(InFlightMetrics)
+	}
+}
+
+type noMetrics struct{}
+
+func (noMetrics) ObserveRequest(string, string, int, time.Duration, int64, int64) {}
+func (noMetrics) IncRetry(string)                                                 {}
+func (noMetrics) IncError(string, string)                                         {}
+
+// CounterMetrics is a Metrics that keeps simple counts in memory. It is
+// mostly useful in tests.
+type CounterMetrics struct {
+	mu       sync.Mutex
+	requests map[string]int64
+	statuses map[int]int64
+	retries  map[string]int64
+	errors   map[[2]string]int64
+	bytesIn  int64
+	bytesOut int64
+}
+
+// NewCounterMetrics returns an empty CounterMetrics.
+func NewCounterMetrics() *CounterMetrics {
+	return &CounterMetrics{
+		requests: make(map[string]int64),
+		statuses: make(map[int]int64),
+		retries:  make(map[string]int64),
+		errors:   make(map[[2]string]int64),
+	}
+}
+
+// ObserveRequest implements Metrics.
+func (m *CounterMetrics) ObserveRequest(method, host string, status int, duration time.Duration, bytesIn, bytesOut int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[host]++
+	m.statuses[status]++
+	m.bytesIn += bytesIn
+	m.bytesOut += bytesOut
+}
+
+// IncRetry implements Metrics.
+func (m *CounterMetrics) IncRetry(host string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries[host]++
+}
+
+// IncError implements Metrics.
+func (m *CounterMetrics) IncError(host, kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[[2]string{host, kind}]++
+}
+
+// Requests returns the number of responses observed from host.
+func (m *CounterMetrics) Requests(host string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.requests[host]
+}
+
+// Statuses returns the number of responses observed with the status code.
+func (m *CounterMetrics) Statuses(status int) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.statuses[status]
+}
+
+// Retries returns the number of retries of requests to host.
+func (m *CounterMetrics) Retries(host string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.retries[host]
+}
+
+// Errors returns the number of errors of the kind for requests to host.
+func (m *CounterMetrics) Errors(host, kind string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.errors[[2]string{host, kind}]
+}
+
+// Bytes returns the total number of bytes read from response bodies and
+// sent in request bodies.
+func (m *CounterMetrics) Bytes() (in, out int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.bytesIn, m.bytesOut
+}
@@ -0,0 +1,98 @@
+package httpclient
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCounterMetrics(t *testing.T) {
+	var flaky int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case "/flaky":
+			if atomic.AddInt32(&flaky, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		io.WriteString(w, "hello")
+	}))
+	defer srv.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	downHost := strings.TrimPrefix(down.URL, "http://")
+
+	m := NewCounterMetrics()
+	c := New(WithMetrics(m), WithRetry(2, time.Millisecond))
+	for i := 0; i < 3; i++ {
+		if _, err := c.Bytes(srv.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Bytes(srv.URL + "/missing"); err == nil {
+		t.Fatal("got no error for /missing")
+	}
+	if _, err := c.Bytes(srv.URL + "/flaky"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Post(srv.URL, "text/plain", strings.NewReader("12345678"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if _, err := New(WithMetrics(m)).Bytes(down.URL); err == nil {
+		t.Fatal("got no error for a closed server")
+	}
+
+	if got := m.Requests(host); got != 7 {
+		t.Errorf("got %d requests, want 7", got)
+	}
+	for status, want := range map[int]int64{200: 5, 404: 1, 503: 1} {
+		if got := m.Statuses(status); got != want {
+			t.Errorf("got %d responses with status %d, want %d", got, status, want)
+		}
+	}
+	if got := m.Retries(host); got != 1 {
+		t.Errorf("got %d retries, want 1", got)
+	}
+	if got := m.Errors(host, ErrorKindStatus); got != 1 {
+		t.Errorf("got %d status errors, want 1", got)
+	}
+	if got := m.Errors(downHost, ErrorKindNetwork); got != 1 {
+		t.Errorf("got %d network errors, want 1", got)
+	}
+	if got := m.Errors(host, ErrorKindNetwork); got != 0 {
+		t.Errorf("got %d network errors for the live server, want 0", got)
+	}
+	in, out := m.Bytes()
+	if in != 5*5 || out != 8 {
+		t.Errorf("got %d bytes in and %d out, want 25 and 8", in, out)
+	}
+}
+
+func TestCounterMetricsBodyError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		io.WriteString(w, "not gzip at all")
+	}))
+	defer srv.Close()
+	m := NewCounterMetrics()
+	if _, err := New(WithMetrics(m), WithCompression("gzip")).Bytes(srv.URL); err == nil {
+		t.Fatal("got no error")
+	}
+	if got := m.Errors(strings.TrimPrefix(srv.URL, "http://"), ErrorKindBody); got != 1 {
+		t.Errorf("got %d body errors, want 1", got)
+	}
+}
@@ -0,0 +1,105 @@
+// Package otelclient traces the requests of an httpclient client with
+// OpenTelemetry.
+//
+//	client := httpclient.New(httpclient.WithTracing(otelclient.New(tp)))
+//	err := client.JSON(url, &v, httpclient.WithContext(ctx))
+//
+// Every request gets a client span named "HTTP GET example.com", a child of
+// the span in the request context, and W3C traceparent headers are injected
+// into the outgoing request. Each retry gets a span of its own, with the
+// number of earlier attempts as http.request.resend_count.
+package otelclient
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/tamnd/httpclient"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/tamnd/httpclient/otelclient"
+
+// Tracer implements httpclient.Tracer.
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+var _ httpclient.Tracer = (*Tracer)(nil)
+
+// An Option configures a Tracer.
+type Option func(*Tracer)
+
+// WithPropagator sets the propagator used to inject the span context into
+// outgoing requests. The default is propagation.TraceContext.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(t *Tracer) {
+		t.propagator = p
+	}
+}
+
+// New returns a Tracer that creates spans with tp.
+func New(tp trace.TracerProvider, opts ...Option) *Tracer {
+	t := &Tracer{
+		tracer:     tp.Tracer(instrumentationName),
+		propagator: propagation.TraceContext{},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Start implements httpclient.Tracer.
+func (t *Tracer) Start(req *http.Request) (*http.Request, httpclient.Span) {
+	ctx, span := t.tracer.Start(req.Context(), "HTTP "+req.Method+" "+req.URL.Hostname(),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.full", safeURL(req.URL)),
+			attribute.String("server.address", req.URL.Hostname()),
+		),
+	)
+	req = req.WithContext(ctx)
+	req.Header = req.Header.Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return req, &otelSpan{span: span}
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s *otelSpan) End(result httpclient.SpanResult) {
+	if result.StatusCode != 0 {
+		s.span.SetAttributes(attribute.Int("http.response.status_code", result.StatusCode))
+	}
+	if result.Attempt > 1 {
+		s.span.SetAttributes(attribute.Int("http.request.resend_count", result.Attempt-1))
+	}
+	switch {
+	case result.Err != nil:
+		s.span.RecordError(result.Err)
+		s.span.SetStatus(codes.Error, result.Err.Error())
+	case result.StatusCode >= 400:
+		s.span.SetStatus(codes.Error, http.StatusText(result.StatusCode))
+	}
+	s.span.End()
+}
+
+// safeURL returns u without credentials and query, which often carry
+// secrets.
+func safeURL(u *url.URL) string {
+	v := *u
+	v.User = nil
+	v.RawQuery = ""
+	v.ForceQuery = false
+	return v.String()
+}
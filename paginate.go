@@ -0,0 +1,149 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrMaxPages is returned by JSONPages when there were more pages than
+// allowed with WithMaxPages.
+var ErrMaxPages = errors.New("httpclient: maximum number of pages reached")
+
+// WithMaxPages limits the number of pages fetched by a pagination helper
+// such as JSONPages. By default there is no limit.
+func WithMaxPages(n int) RequestOption {
+	return func(o *requestOptions) {
+		o.maxPages = n
+	}
+}
+
+// JSONPages fetches url and calls each with the body of the response, then
+// follows the rel="next" link in the Link header of the response (RFC 8288)
+// to the next page, until there is no such link or each returns false or
+// an error. The error of each is returned as is.
+//
+// Path and query parameters set with opts only apply to the first page;
+// the next pages are fetched from the links as given.
+func (c *httpClient) JSONPages(url string, each func(page json.RawMessage) (bool, error), opts ...RequestOption) error {
+	o := newRequestOptions(opts)
+	for pages := 0; url != ""; pages++ {
+		if o.maxPages > 0 && pages >= o.maxPages {
+			return ErrMaxPages
+		}
+		page, next, err := c.jsonPage(url, o)
+		if err != nil {
+			return err
+		}
+		more, err := each(page)
+		if err != nil || !more {
+			return err
+		}
+		url = next
+		o.pathParams, o.query = nil, nil
+	}
+	return nil
+}
+
+// jsonPage fetches one page and returns its body and the URL of the next.
+func (c *httpClient) jsonPage(url string, o *requestOptions) (json.RawMessage, string, error) {
+	resp, err := c.send("GET", url, nil, o)
+	if err != nil {
+		return nil, "", err
+	}
+	p, err := c.readJSON(resp, o)
+	if err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if link, ok := parseLinks(resp.Header)["next"]; ok {
+		u, err := resp.Request.URL.Parse(link)
+		if err != nil {
+			return nil, "", c.err(resp, "invalid next link at "+c.redact.url(resp.Request.URL).String())
+		}
+		next = u.String()
+	}
+	return p, next, nil
+}
+
+// JSONPages follows the pages of url with the default client.
+func JSONPages(url string, each func(page json.RawMessage) (bool, error), opts ...RequestOption) error {
+	return defaultClient().JSONPages(url, each, opts...)
+}
+
+// parseLinks returns the targets of the Link headers in h by relation
+// type. A link with several relation types is listed under each; for a
+// type given more than once, the first link wins.
+func parseLinks(h http.Header) map[string]string {
+	links := make(map[string]string)
+	for _, s := range h.Values("Link") {
+		for s != "" {
+			s = strings.TrimLeft(s, " \t,")
+			if !strings.HasPrefix(s, "<") {
+				break
+			}
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				break
+			}
+			target := s[1:end]
+			s = s[end+1:]
+			var rel string
+			for {
+				s = strings.TrimLeft(s, " \t")
+				if !strings.HasPrefix(s, ";") {
+					break
+				}
+				var name, value string
+				name, value, s = linkParam(s[1:])
+				if strings.EqualFold(name, "rel") && rel == "" {
+					rel = value
+				}
+			}
+			for _, r := range strings.Fields(rel) {
+				r = strings.ToLower(r)
+				if _, ok := links[r]; !ok {
+					links[r] = target
+				}
+			}
+		}
+	}
+	return links
+}
+
+// linkParam parses a name=value link parameter, where value may be a
+// quoted string, and returns the rest of s.
+func linkParam(s string) (name, value, rest string) {
+	s = strings.TrimLeft(s, " \t")
+	i := strings.IndexAny(s, "=;,")
+	if i < 0 || s[i] != '=' {
+		if i < 0 {
+			i = len(s)
+		}
+		return strings.TrimSpace(s[:i]), "", s[i:]
+	}
+	name, s = strings.TrimSpace(s[:i]), strings.TrimLeft(s[i+1:], " \t")
+	if !strings.HasPrefix(s, `"`) {
+		j := strings.IndexAny(s, ";,")
+		if j < 0 {
+			j = len(s)
+		}
+		return name, strings.TrimSpace(s[:j]), s[j:]
+	}
+	var b strings.Builder
+	for j := 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			if j+1 < len(s) {
+				j++
+				b.WriteByte(s[j])
+			}
+		case '"':
+			return name, b.String(), s[j+1:]
+		default:
+			b.WriteByte(s[j])
+		}
+	}
+	return name, b.String(), ""
+}
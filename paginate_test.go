@@ -0,0 +1,170 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pagedServer serves three pages of items at /items. The first page links
+// to the second with an absolute URL among other relations, the second to
+// the third with a relative one. The first request for the second page
+// fails with a 503.
+type pagedServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string
+}
+
+func newPagedServer() *pagedServer {
+	s := &pagedServer{}
+	failed := false
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.URL.RequestURI())
+		fail := r.URL.Query().Get("page") == "2" && !failed
+		failed = failed || fail
+		s.mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Query().Get("page") {
+		case "", "1":
+			w.Header().Add("Link", fmt.Sprintf(`<%s/items?page=3>; rel="last", <%s/items?page=2>; title="next; page"; rel="next prefetch"`, s.URL, s.URL))
+			fmt.Fprint(w, `[1, 2]`)
+		case "2":
+			w.Header().Add("Link", `</items?page=1>; rel=first`)
+			w.Header().Add("Link", `<?page=3>; rel="next", <?page=1>; rel="prev"`)
+			fmt.Fprint(w, `[3, 4]`)
+		case "3":
+			w.Header().Add("Link", `<?page=2>; rel="prev"`)
+			fmt.Fprint(w, `[5]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return s
+}
+
+func TestJSONPages(t *testing.T) {
+	srv := newPagedServer()
+	defer srv.Close()
+	c := New(WithBaseURL(srv.URL), WithRetry(2, time.Millisecond))
+
+	var items []int
+	pages := 0
+	err := c.JSONPages("/items", func(page json.RawMessage) (bool, error) {
+		pages++
+		var p []int
+		if err := json.Unmarshal(page, &p); err != nil {
+			return false, err
+		}
+		items = append(items, p...)
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages != 3 || fmt.Sprint(items) != "[1 2 3 4 5]" {
+		t.Errorf("got %d pages, items %v", pages, items)
+	}
+	want := "[/items /items?page=2 /items?page=2 /items?page=3]"
+	if got := fmt.Sprint(srv.requests); got != want {
+		t.Errorf("got requests %s, want %s", got, want)
+	}
+}
+
+func TestJSONPagesStop(t *testing.T) {
+	srv := newPagedServer()
+	defer srv.Close()
+	c := New(WithBaseURL(srv.URL), WithRetry(2, time.Millisecond))
+
+	// The callback stops.
+	pages := 0
+	err := c.JSONPages("/items", func(json.RawMessage) (bool, error) {
+		pages++
+		return false, nil
+	})
+	if err != nil || pages != 1 {
+		t.Errorf("stop: got %d pages, %v", pages, err)
+	}
+
+	// The callback fails.
+	boom := errors.New("boom")
+	pages = 0
+	err = c.JSONPages("/items", func(json.RawMessage) (bool, error) {
+		pages++
+		return pages < 2, boom
+	})
+	if err != boom || pages != 1 {
+		t.Errorf("error: got %d pages, %v", pages, err)
+	}
+
+	// The page cap is hit.
+	pages = 0
+	err = c.JSONPages("/items", func(json.RawMessage) (bool, error) {
+		pages++
+		return true, nil
+	}, WithMaxPages(2))
+	if !errors.Is(err, ErrMaxPages) || pages != 2 {
+		t.Errorf("max pages: got %d pages, %v", pages, err)
+	}
+
+	// A page fails.
+	err = c.JSONPages("/items?page=9", func(json.RawMessage) (bool, error) {
+		t.Error("callback called for a failed page")
+		return true, nil
+	})
+	var herr *Error
+	if !errors.As(err, &herr) || herr.StatusCode != 404 {
+		t.Errorf("404: got %v", err)
+	}
+}
+
+func TestParseLinks(t *testing.T) {
+	tests := []struct {
+		link []string
+		want map[string]string
+	}{
+		{nil, map[string]string{}},
+		{[]string{`<https://x.test/2>; rel="next"`}, map[string]string{"next": "https://x.test/2"}},
+		{[]string{`<https://x.test/2>; rel=next`}, map[string]string{"next": "https://x.test/2"}},
+		{
+			[]string{`<https://x.test/2>; rel="next", <https://x.test/9>; rel="last"`},
+			map[string]string{"next": "https://x.test/2", "last": "https://x.test/9"},
+		},
+		{
+			[]string{`</p/2>; rel="Next Prefetch"`},
+			map[string]string{"next": "/p/2", "prefetch": "/p/2"},
+		},
+		{
+			[]string{`<?p=2>; title="a, b; rel=\"prev\""; rel="next"`},
+			map[string]string{"next": "?p=2"},
+		},
+		{
+			[]string{`<a>; rel="next"; rel="prev"`},
+			map[string]string{"next": "a"},
+		},
+		{
+			[]string{`<a>; rel="next"`, `<b>; rel="next", <c>; rel="prev"`},
+			map[string]string{"next": "a", "prev": "c"},
+		},
+		{[]string{`<a>; anchor="x"`}, map[string]string{}},
+		{[]string{`garbage, <a>; rel="next"`}, map[string]string{}},
+		{[]string{`<unterminated; rel="next"`}, map[string]string{}},
+	}
+	for _, tt := range tests {
+		h := http.Header{"Link": tt.link}
+		got := parseLinks(h)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: got %v, want %v", strings.Join(tt.link, " | "), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// WithPathParam sets the value of the {name} placeholder in the path of the
+// request URL:
+//
+//	client.JSON("/repos/{owner}/{repo}/issues/{number}", &out,
+//		httpclient.WithPathParam("owner", owner),
+//		httpclient.WithPathParam("repo", repo),
+//		httpclient.WithPathParam("number", 42))
+//
+// The value may be a string, an integer or a fmt.Stringer and is escaped on
+// its own, so a "/" in it does not start a new path segment. The request
+// fails if a placeholder is left without a value or a parameter has no
+// placeholder. Without any WithPathParam, the URL is used as it is, braces
+// included.
+func WithPathParam(name string, value interface{}) RequestOption {
+	return func(o *requestOptions) {
+		s, err := pathValue(value)
+		if err != nil {
+			o.fail(fmt.Errorf("httpclient: path parameter %q: %v", name, err))
+			return
+		}
+		if o.pathParams == nil {
+			o.pathParams = make(map[string]string)
+		}
+		o.pathParams[name] = s
+	}
+}
+
+func pathValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported type %T", value)
+}
+
+// expandPath returns rawurl with the placeholders in its path replaced by
+// the escaped path parameters of o, or rawurl itself if o has none.
+func (o *requestOptions) expandPath(rawurl string) (string, error) {
+	if o.pathParams == nil {
+		return rawurl, nil
+	}
+	end := strings.IndexAny(rawurl, "?#")
+	if end < 0 {
+		end = len(rawurl)
+	}
+	path, rest := rawurl[:end], rawurl[end:]
+
+	var b strings.Builder
+	used := make(map[string]bool)
+	for {
+		i := strings.IndexByte(path, '{')
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(path[i:], '}')
+		if j < 0 {
+			return "", fmt.Errorf("httpclient: unterminated path parameter in %q", rawurl)
+		}
+		name := path[i+1 : i+j]
+		v, ok := o.pathParams[name]
+		if !ok {
+			return "", fmt.Errorf("httpclient: missing path parameter %q in %q", name, rawurl)
+		}
+		used[name] = true
+		b.WriteString(path[:i])
+		b.WriteString(url.PathEscape(v))
+		path = path[i+j+1:]
+	}
+	for name := range o.pathParams {
+		if !used[name] {
+			return "", fmt.Errorf("httpclient: path parameter %q not in %q", name, rawurl)
+		}
+	}
+	b.WriteString(path)
+	b.WriteString(rest)
+	return b.String(), nil
+}
@@ -0,0 +1,69 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandPath(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		opts []RequestOption
+		want string
+		err  string
+	}{
+		{"no params", "http://h/a/{b}?q={c}", nil, "http://h/a/{b}?q={c}", ""},
+		{"string", "http://h/repos/{owner}/{repo}", []RequestOption{WithPathParam("owner", "go"), WithPathParam("repo", "net")}, "http://h/repos/go/net", ""},
+		{"slash", "http://h/files/{name}", []RequestOption{WithPathParam("name", "a/b")}, "http://h/files/a%2Fb", ""},
+		{"unicode", "http://h/users/{name}", []RequestOption{WithPathParam("name", "jürgen é")}, "http://h/users/j%C3%BCrgen%20%C3%A9", ""},
+		{"int", "http://h/issues/{n}", []RequestOption{WithPathParam("n", 42)}, "http://h/issues/42", ""},
+		{"uint", "http://h/issues/{n}", []RequestOption{WithPathParam("n", uint8(7))}, "http://h/issues/7", ""},
+		{"Stringer", "http://h/wait/{d}", []RequestOption{WithPathParam("d", time.Second)}, "http://h/wait/1s", ""},
+		{"query left alone", "http://h/{a}?x={a}#{a}", []RequestOption{WithPathParam("a", "1")}, "http://h/1?x={a}#{a}", ""},
+		{"missing", "http://h/{a}/{b}", []RequestOption{WithPathParam("a", "1")}, "", `missing path parameter "b"`},
+		{"unused", "http://h/{a}", []RequestOption{WithPathParam("a", "1"), WithPathParam("b", "2")}, "", `path parameter "b" not in`},
+		{"unterminated", "http://h/{a", []RequestOption{WithPathParam("a", "1")}, "", "unterminated path parameter"},
+	}
+	for _, tt := range tests {
+		o := newRequestOptions(tt.opts)
+		got, err := o.expandPath(tt.url)
+		switch {
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case got != tt.want:
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPathParamUnsupportedType(t *testing.T) {
+	o := newRequestOptions([]RequestOption{WithPathParam("a", 1.5)})
+	if o.err == nil || !strings.Contains(o.err.Error(), "unsupported type float64") {
+		t.Errorf("got %v", o.err)
+	}
+}
+
+func TestPathParamWithBaseURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.RequestURI)
+	}))
+	defer srv.Close()
+	c := New(WithBaseURL(srv.URL + "/v1"))
+	got, err := c.String("repos/{owner}/{repo}", WithPathParam("owner", "a b"), WithPathParam("repo", "x/y"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/v1/repos/a%20b/x%2Fy"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, err := c.String("repos/{owner}", WithPathParam("repo", "x")); err == nil {
+		t.Error("got no error for a missing parameter")
+	}
+}
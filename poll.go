@@ -0,0 +1,97 @@
+package httpclient
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Poll fetches url every interval until until reports done or an error,
+// ctx is done or the attempts set with WithMaxPolls run out, and returns
+// the last response. Up to a tenth of interval is added at random to each
+// wait. A 429 or 503 response is not passed to until; Poll waits as long
+// as its Retry-After header asks, if it has one, then tries again.
+//
+// A network error ends the poll, as does ctx, whose error is returned. If
+// the attempts run out, Poll returns the last response with ErrMaxPolls.
+func (c *httpClient) Poll(ctx context.Context, url string, interval time.Duration, until func(resp *Response) (bool, error), opts ...RequestOption) (*Response, error) {
+	o := newRequestOptions(withContext(ctx, opts))
+	var last *Response
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		resp, wait, done, err := c.poll(url, o, until)
+		if resp != nil {
+			last = resp
+		}
+		if o.pollHook != nil {
+			o.pollHook(PollAttempt{Attempt: attempt, Duration: time.Since(start), Err: err})
+		}
+		if done || err != nil {
+			return last, err
+		}
+		if o.maxPolls > 0 && attempt >= o.maxPolls {
+			return last, ErrMaxPolls
+		}
+		if wait == 0 {
+			wait = interval
+			if n := int64(interval / 10); n > 0 {
+				wait += time.Duration(rand.Int63n(n))
+			}
+		}
+		if err := pollWait(ctx, wait); err != nil {
+			return last, err
+		}
+	}
+}
+
+// pollWait waits between the attempts of Poll. Tests replace it with a
+// fake clock.
+var pollWait = sleep
+
+// poll makes one attempt of Poll. It returns the wait the server asked for,
+// if any.
+func (c *httpClient) poll(url string, o *requestOptions, until func(*Response) (bool, error)) (*Response, time.Duration, bool, error) {
+	resp, err := c.send("GET", url, nil, o)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	r, err := c.readResponse(resp, o)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if r.StatusCode == http.StatusTooManyRequests || r.StatusCode == http.StatusServiceUnavailable {
+		wait, _ := retryAfter(r.Header.Get("Retry-After"))
+		return r, wait, false, nil
+	}
+	done, err := until(r)
+	return r, 0, done, err
+}
+
+// Poll fetches url with the default client until until reports done.
+func Poll(ctx context.Context, url string, interval time.Duration, until func(resp *Response) (bool, error), opts ...RequestOption) (*Response, error) {
+	return defaultClient().Poll(ctx, url, interval, until, opts...)
+}
+
+// retryAfter parses the value of a Retry-After header, either a number of
+// seconds or an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		if n < 0 {
+			return 0, false
+		}
+		return time.Duration(n) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := time.Until(t); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
@@ -0,0 +1,196 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock stands for pollWait: it returns at once and keeps the waits
+// asked for.
+type fakeClock struct {
+	mu    sync.Mutex
+	waits []time.Duration
+}
+
+func (c *fakeClock) sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.waits = append(c.waits, d)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *fakeClock) elapsed() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var d time.Duration
+	for _, w := range c.waits {
+		d += w
+	}
+	return d
+}
+
+func useFakeClock(t *testing.T) *fakeClock {
+	c := &fakeClock{}
+	pollWait = c.sleep
+	t.Cleanup(func() { pollWait = sleep })
+	return c
+}
+
+// jobServer serves the status of a job at /jobs/1: the statuses in order,
+// the last one repeated. A status that is a number is sent as the status
+// code of the response, with a Retry-After header of 2 seconds.
+func jobServer(statuses ...string) (*httptest.Server, *int) {
+	var mu sync.Mutex
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		status := statuses[len(statuses)-1]
+		if n < len(statuses) {
+			status = statuses[n]
+		}
+		n++
+		mu.Unlock()
+		var code int
+		if _, err := fmt.Sscan(status, &code); err == nil {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(code)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"id": "1", "status": status})
+	}))
+	return srv, &n
+}
+
+func jobDone(resp *Response) (bool, error) {
+	var job struct{ Status string }
+	if err := resp.JSON(&job); err != nil {
+		return false, err
+	}
+	switch job.Status {
+	case "complete":
+		return true, nil
+	case "failed":
+		return false, errors.New("job failed")
+	}
+	return false, nil
+}
+
+func TestPoll(t *testing.T) {
+	clock := useFakeClock(t)
+	srv, n := jobServer("running", "running", "running", "complete")
+	defer srv.Close()
+
+	var attempts []PollAttempt
+	resp, err := New().Poll(context.Background(), srv.URL+"/jobs/1", 10*time.Second, jobDone,
+		WithPollHook(func(a PollAttempt) { attempts = append(attempts, a) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.String() != `{"id":"1","status":"complete"}`+"\n" || *n != 4 || len(attempts) != 4 {
+		t.Errorf("got %q after %d requests, %d attempts", resp, *n, len(attempts))
+	}
+	if len(clock.waits) != 3 {
+		t.Fatalf("got waits %v, want 3", clock.waits)
+	}
+	for _, w := range clock.waits {
+		if w < 10*time.Second || w >= 11*time.Second {
+			t.Errorf("got wait %v, want 10s plus up to a tenth", w)
+		}
+	}
+	if d := clock.elapsed(); d < 30*time.Second || d >= 33*time.Second {
+		t.Errorf("got %v in all, want about 30s", d)
+	}
+	for i, a := range attempts {
+		if a.Attempt != i+1 || a.Err != nil {
+			t.Errorf("attempt %d: got %+v", i+1, a)
+		}
+	}
+}
+
+func TestPollRetryAfter(t *testing.T) {
+	clock := useFakeClock(t)
+	srv, n := jobServer("running", "429", "503", "complete")
+	defer srv.Close()
+
+	called := 0
+	resp, err := Poll(context.Background(), srv.URL, time.Second, func(resp *Response) (bool, error) {
+		called++
+		return jobDone(resp)
+	})
+	if err != nil || resp.StatusCode != 200 || *n != 4 {
+		t.Fatalf("got %v, %v after %d requests", resp, err, *n)
+	}
+	if called != 2 {
+		t.Errorf("until called %d times, want 2: not for the 429 and 503", called)
+	}
+	if len(clock.waits) != 3 || clock.waits[0] >= 2*time.Second || clock.waits[1] != 2*time.Second || clock.waits[2] != 2*time.Second {
+		t.Errorf("got waits %v, want about 1s, 2s, 2s", clock.waits)
+	}
+}
+
+func TestPollStop(t *testing.T) {
+	useFakeClock(t)
+	srv, n := jobServer("running")
+	defer srv.Close()
+
+	// The attempts run out.
+	resp, err := Poll(context.Background(), srv.URL, time.Minute, jobDone, WithMaxPolls(5))
+	if !errors.Is(err, ErrMaxPolls) || resp == nil || *n != 5 {
+		t.Errorf("max polls: got %v, %v after %d requests", resp, err, *n)
+	}
+
+	// The predicate fails.
+	failed, _ := jobServer("running", "failed")
+	defer failed.Close()
+	resp, err = Poll(context.Background(), failed.URL, time.Minute, jobDone)
+	if err == nil || err.Error() != "job failed" || resp == nil {
+		t.Errorf("failed: got %v, %v", resp, err)
+	}
+
+	// The context is canceled while waiting.
+	ctx, cancel := context.WithCancel(context.Background())
+	polls := 0
+	_, err = Poll(ctx, srv.URL, time.Minute, func(resp *Response) (bool, error) {
+		if polls++; polls == 2 {
+			cancel()
+		}
+		return false, nil
+	})
+	if !errors.Is(err, context.Canceled) || polls != 2 {
+		t.Errorf("canceled: got %v after %d polls", err, polls)
+	}
+
+	// A network error ends the poll.
+	srv.Close()
+	if _, err = Poll(context.Background(), srv.URL, time.Minute, jobDone); err == nil {
+		t.Error("network error: got none")
+	}
+}
+
+func TestPollKeepsOptions(t *testing.T) {
+	useFakeClock(t)
+	srv, _ := jobServer("complete")
+	defer srv.Close()
+
+	// The context is not written into the spare capacity of the options of
+	// the caller.
+	opts := make([]RequestOption, 1, 2)
+	opts[0] = WithMaxPolls(3)
+	if _, err := Poll(context.Background(), srv.URL, time.Second, jobDone, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if opts[:2][1] != nil {
+		t.Error("Poll appended to the options of the caller")
+	}
+}
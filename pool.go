@@ -0,0 +1,108 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// maxPooledBuffer is the capacity above which buffers are left to the
+// garbage collector rather than put back in the pool, so that one huge
+// response does not pin its memory for the life of the process.
+const maxPooledBuffer = 1 << 20
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// PooledBytes is a response body held in a pooled buffer. It saves the
+// copy Bytes makes, at the price of having to call Release once the body is
+// no longer needed.
+type PooledBytes struct {
+	buf *bytes.Buffer
+}
+
+// Bytes returns the body. It is only valid until Release is called.
+func (b *PooledBytes) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// Release puts the buffer back in the pool. Neither b nor the slice
+// returned by Bytes may be used afterwards.
+func (b *PooledBytes) Release() {
+	if b.buf != nil {
+		putBuffer(b.buf)
+		b.buf = nil
+	}
+}
+
+// BytesPooled fetches the specified url and returns the response body in a
+// pooled buffer, which must be released.
+func (c *httpClient) BytesPooled(url string, opts ...RequestOption) (*PooledBytes, error) {
+	buf, err := c.buffer(url, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &PooledBytes{buf: buf}, nil
+}
+
+// chunkSize is the size of the chunks bodies of unknown length are read in.
+const chunkSize = 32 << 10
+
+var chunkPool = sync.Pool{
+	New: func() interface{} { return new([chunkSize]byte) },
+}
+
+// readChunks reads r in pooled chunks and returns its contents in a slice
+// allocated once at the end, so that reading a body of unknown length
+// neither regrows a buffer nor keeps one larger than needed. If limit is
+// positive and r is longer, it stops and returns tooLarge as the error.
+func readChunks(r io.Reader, limit int64, tooLarge func() error) ([]byte, error) {
+	var chunks []*[chunkSize]byte
+	defer func() {
+		for _, c := range chunks {
+			chunkPool.Put(c)
+		}
+	}()
+	var total int64
+	last := chunkSize
+	for {
+		if last == chunkSize {
+			chunks = append(chunks, chunkPool.Get().(*[chunkSize]byte))
+			last = 0
+		}
+		n, err := r.Read(chunks[len(chunks)-1][last:])
+		last += n
+		total += int64(n)
+		if limit > 0 && total > limit {
+			return nil, tooLarge()
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	p := make([]byte, 0, total)
+	for i, c := range chunks {
+		if i == len(chunks)-1 {
+			p = append(p, c[:last]...)
+		} else {
+			p = append(p, c[:]...)
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,104 @@
+package httpclient
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestBytesPooled(t *testing.T) {
+	body := bytes.Repeat([]byte("abc"), 1000)
+	c := New(WithTransport(&staticTransport{body: body, length: int64(len(body))}))
+	for i := 0; i < 3; i++ {
+		b, err := c.BytesPooled("http://example.com/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b.Bytes(), body) {
+			t.Errorf("got %d bytes, want %d", len(b.Bytes()), len(body))
+		}
+		b.Release()
+		b.Release()
+	}
+}
+
+func TestBytesDoesNotShareBuffers(t *testing.T) {
+	first := New(WithTransport(&staticTransport{body: []byte("first"), length: -1}))
+	second := New(WithTransport(&staticTransport{body: []byte("other"), length: -1}))
+	a, err := first.String("http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := first.Bytes("http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		second.String("http://example.com/")
+		second.Bytes("http://example.com/")
+	}
+	if a != "first" || string(p) != "first" {
+		t.Errorf("results changed to %q and %q by later calls", a, p)
+	}
+}
+
+func TestPutBufferDropsLargeBuffers(t *testing.T) {
+	buf := getBuffer()
+	buf.Grow(maxPooledBuffer + 1)
+	putBuffer(buf)
+	for i := 0; i < 10; i++ {
+		if b := getBuffer(); b.Cap() > maxPooledBuffer {
+			t.Fatal("large buffer put back in the pool")
+		}
+	}
+}
+
+func benchmarkBytes(b *testing.B, size int) {
+	body := bytes.Repeat([]byte("x"), size)
+	rt := &staticTransport{body: body, length: -1}
+	b.Run("ReadAll", func(b *testing.B) {
+		hc := &http.Client{Transport: rt}
+		b.ReportAllocs()
+		b.SetBytes(int64(size))
+		for i := 0; i < b.N; i++ {
+			resp, err := hc.Get("http://example.com/")
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := ioutil.ReadAll(resp.Body); err != nil {
+				b.Fatal(err)
+			}
+			resp.Body.Close()
+		}
+	})
+	c := New(WithTransport(rt))
+	b.Run("Bytes", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(size))
+		for i := 0; i < b.N; i++ {
+			if _, err := c.Bytes("http://example.com/"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("BytesPooled", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(size))
+		for i := 0; i < b.N; i++ {
+			p, err := c.BytesPooled("http://example.com/")
+			if err != nil {
+				b.Fatal(err)
+			}
+			p.Release()
+		}
+	})
+}
+
+// The Bytes benchmarks compare the allocations of ioutil.ReadAll with
+// those of Bytes and BytesPooled, for bodies of unknown length. Buffers
+// that grew past maxPooledBuffer are not pooled, so Large stays below it.
+
+func BenchmarkBytesSmall(b *testing.B)  { benchmarkBytes(b, 1<<10) }
+func BenchmarkBytesMedium(b *testing.B) { benchmarkBytes(b, 64<<10) }
+func BenchmarkBytesLarge(b *testing.B)  { benchmarkBytes(b, 512<<10) }
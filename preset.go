@@ -0,0 +1,113 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	neturl "net/url"
+)
+
+// Preset is a set of request options applied to every request made
+// through it, for calls of the same kind made from many places:
+//
+//	api := client.NewPreset(
+//		httpclient.WithRequestHeader("X-Tenant", tenant),
+//		httpclient.WithQueryParam("api-version", "2"),
+//		httpclient.WithDisallowUnknownFields())
+//	err := api.JSON("/items", &items)
+//
+// Options given to a call are applied after those of the preset and win
+// over them: a header or query parameter set by the call replaces the one
+// of the preset with the same name.
+//
+// A Preset cannot be changed once created, so it can be shared freely.
+type Preset struct {
+	c    *httpClient
+	opts []RequestOption
+}
+
+var _ Client = (*Preset)(nil)
+
+// NewPreset returns a Preset making requests with c and opts.
+func (c *httpClient) NewPreset(opts ...RequestOption) *Preset {
+	return &Preset{c: c, opts: append([]RequestOption(nil), opts...)}
+}
+
+// NewPreset returns a Preset making requests with the default client.
+func NewPreset(opts ...RequestOption) *Preset {
+	return defaultClient().NewPreset(opts...)
+}
+
+// With returns a new Preset with opts applied after those of p.
+func (p *Preset) With(opts ...RequestOption) *Preset {
+	return &Preset{c: p.c, opts: []RequestOption{p.layer(opts)}}
+}
+
+// layer returns an option applying the options of p, then opts.
+func (p *Preset) layer(opts []RequestOption) RequestOption {
+	return func(o *requestOptions) {
+		for _, opt := range p.opts {
+			opt(o)
+		}
+		query, header := o.query, o.header
+		o.query, o.header = nil, nil
+		for _, opt := range opts {
+			opt(o)
+		}
+		for k, vs := range query {
+			if _, ok := o.query[k]; !ok {
+				if o.query == nil {
+					o.query = make(neturl.Values)
+				}
+				o.query[k] = vs
+			}
+		}
+		for k, vs := range header {
+			if _, ok := o.header[k]; !ok {
+				if o.header == nil {
+					o.header = make(http.Header)
+				}
+				o.header[k] = vs
+			}
+		}
+	}
+}
+
+// Get issues a GET request with the options of p.
+func (p *Preset) Get(url string, opts ...RequestOption) (*http.Response, error) {
+	return p.c.Get(url, p.layer(opts))
+}
+
+// Bytes fetches url with the options of p and returns the response body.
+func (p *Preset) Bytes(url string, opts ...RequestOption) ([]byte, error) {
+	return p.c.Bytes(url, p.layer(opts))
+}
+
+// String fetches url with the options of p and returns the response body.
+func (p *Preset) String(url string, opts ...RequestOption) (string, error) {
+	return p.c.String(url, p.layer(opts))
+}
+
+// Reader fetches url with the options of p and returns the response body.
+func (p *Preset) Reader(url string, opts ...RequestOption) (io.ReadCloser, error) {
+	return p.c.Reader(url, p.layer(opts))
+}
+
+// JSON fetches url with the options of p and decodes the JSON body into v.
+func (p *Preset) JSON(url string, v interface{}, opts ...RequestOption) error {
+	return p.c.JSON(url, v, p.layer(opts))
+}
+
+// XML fetches url with the options of p and decodes the XML body into v.
+func (p *Preset) XML(url string, v interface{}, opts ...RequestOption) error {
+	return p.c.XML(url, v, p.layer(opts))
+}
+
+// Files downloads urls with the options of p.
+func (p *Preset) Files(urls []string, files *[]File, opts ...RequestOption) error {
+	return p.c.Files(urls, files, p.layer(opts))
+}
+
+// Download downloads urls with the options of p.
+func (p *Preset) Download(urls []string, files *[]File, opts ...RequestOption) error {
+	return p.c.Download(urls, files, p.layer(opts))
+}
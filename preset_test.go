@@ -0,0 +1,151 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPresetLayering(t *testing.T) {
+	srv := echoServer()
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL))
+	c.SetHeader("X-Tenant", "client")
+	c.SetHeader("X-Client", "c")
+	c.SetHeader("X-Removed", "c")
+	p := c.NewPreset(
+		WithRequestHeader("X-Tenant", "preset"),
+		WithRequestHeader("X-Preset", "p"),
+		WithRequestHeader("X-Removed", ""),
+		WithQueryParam("api-version", "2"),
+		WithQueryParam("tag", "p"),
+	)
+
+	tests := []struct {
+		name   string
+		url    string
+		opts   []RequestOption
+		header map[string]string
+		query  string
+	}{
+		{
+			"client and preset",
+			"/items?api-version=1&sort=new",
+			nil,
+			map[string]string{"X-Tenant": "preset", "X-Client": "c", "X-Preset": "p", "X-Removed": ""},
+			"api-version=2&sort=new&tag=p",
+		},
+		{
+			"call wins",
+			"/items",
+			[]RequestOption{
+				WithRequestHeader("X-Tenant", "call"),
+				WithRequestHeader("X-Client", "call"),
+				WithRequestHeader("X-Removed", "call"),
+				WithQueryParam("api-version", "3"),
+			},
+			map[string]string{"X-Tenant": "call", "X-Client": "call", "X-Preset": "p", "X-Removed": "call"},
+			"api-version=3&tag=p",
+		},
+		{
+			"call removes",
+			"/items",
+			[]RequestOption{WithRequestHeader("X-Preset", ""), WithRequestHeader("X-Client", "")},
+			map[string]string{"X-Tenant": "preset", "X-Client": "", "X-Preset": ""},
+			"api-version=2&tag=p",
+		},
+		{
+			"call adds to a repeated parameter",
+			"/items",
+			[]RequestOption{WithQueryParam("tag", "a"), WithQueryParam("tag", "b")},
+			map[string]string{"X-Preset": "p"},
+			"api-version=2&tag=a&tag=b",
+		},
+	}
+	for _, tt := range tests {
+		var got echo
+		if err := p.JSON(tt.url, &got, tt.opts...); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		for k, v := range tt.header {
+			if got.Header.Get(k) != v {
+				t.Errorf("%s: %s: got %q, want %q", tt.name, k, got.Header.Get(k), v)
+			}
+		}
+		if got.Query != tt.query {
+			t.Errorf("%s: got query %q, want %q", tt.name, got.Query, tt.query)
+		}
+	}
+
+	// The preset leaves the client alone.
+	var got echo
+	if err := c.JSON("/items", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Header.Get("X-Tenant") != "client" || got.Header.Get("X-Preset") != "" || got.Query != "" {
+		t.Errorf("client: got %+v", got)
+	}
+}
+
+func TestPresetWith(t *testing.T) {
+	srv := echoServer()
+	defer srv.Close()
+	c := New(WithBaseURL(srv.URL))
+
+	opts := []RequestOption{WithRequestHeader("X-Tenant", "a"), WithQueryParam("v", "1")}
+	base := c.NewPreset(opts...)
+	opts[0] = WithRequestHeader("X-Tenant", "changed")
+	child := base.With(WithRequestHeader("X-Tenant", "b"), WithRequestHeader("X-Child", "1"))
+	grandchild := child.With(WithQueryParam("v", "3"))
+
+	tests := []struct {
+		name          string
+		p             *Preset
+		opts          []RequestOption
+		tenant, child string
+		query         string
+	}{
+		{"base", base, nil, "a", "", "v=1"},
+		{"child", child, nil, "b", "1", "v=1"},
+		{"grandchild", grandchild, nil, "b", "1", "v=3"},
+		{"grandchild call", grandchild, []RequestOption{WithRequestHeader("X-Tenant", "c"), WithQueryParam("v", "4")}, "c", "1", "v=4"},
+	}
+	for _, tt := range tests {
+		var got echo
+		if err := tt.p.JSON("/", &got, tt.opts...); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got.Header.Get("X-Tenant") != tt.tenant || got.Header.Get("X-Child") != tt.child || got.Query != tt.query {
+			t.Errorf("%s: got %s, %s, %s", tt.name, got.Header.Get("X-Tenant"), got.Header.Get("X-Child"), got.Query)
+		}
+	}
+}
+
+func TestPresetDecoding(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"widget","extra":true}`))
+	}))
+	defer srv.Close()
+
+	p := New(WithBaseURL(srv.URL)).NewPreset(WithDisallowUnknownFields())
+	var item struct{ Name string }
+	if err := p.JSON("/", &item); err == nil {
+		t.Error("strict preset: got no error for an unknown field")
+	}
+	var full struct {
+		Name  string
+		Extra bool
+	}
+	if err := p.JSON("/", &full); err != nil || full.Name != "widget" || !full.Extra {
+		t.Errorf("got %+v, %v", full, err)
+	}
+	if b, err := p.Bytes("/"); err != nil || len(b) == 0 {
+		t.Errorf("Bytes: got %q, %v", b, err)
+	}
+	if s, err := p.String("/"); err != nil || s != `{"name":"widget","extra":true}` {
+		t.Errorf("String: got %q, %v", s, err)
+	}
+}
@@ -0,0 +1,136 @@
+package httpclient
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// priorityAging is how long a request waits for its priority to rise by
+// one, so that low-priority requests are not starved.
+const priorityAging = time.Second
+
+// WithMaxConcurrentRequests limits the number of requests of the client in
+// flight at once to n, from sending the request to closing the response
+// body. The others wait their turn, by priority, see WithPriority.
+func WithMaxConcurrentRequests(n int) Option {
+	return func(c *httpClient) {
+		c.sem = nil
+		if n > 0 {
+			c.sem = newSemaphore(n)
+		}
+	}
+}
+
+// WithPriority sets the priority of the request when it has to wait for
+// WithMaxConcurrentRequests, 0 by default. Higher priorities go first, in
+// the order they came within a priority. A waiting request gains one level
+// of priority every second, so that low priorities are not starved. Given
+// to Files, it applies to every download of the batch.
+func WithPriority(p int) RequestOption {
+	return func(o *requestOptions) {
+		o.priority = p
+	}
+}
+
+// semaphore is a counting semaphore whose waiters are served by priority.
+type semaphore struct {
+	mu      sync.Mutex
+	free    int
+	waiters waitQueue
+	epoch   time.Time
+	seq     uint64
+}
+
+func newSemaphore(n int) *semaphore {
+	return &semaphore{free: n, epoch: time.Now()}
+}
+
+type waiter struct {
+	key   float64
+	seq   uint64
+	ready chan struct{}
+	index int
+}
+
+func (s *semaphore) acquire(ctx context.Context, priority int) error {
+	s.mu.Lock()
+	if s.free > 0 && len(s.waiters) == 0 {
+		s.free--
+		s.mu.Unlock()
+		return nil
+	}
+	// Aging raises every waiter alike, so the order of two waiters never
+	// changes and can be fixed when they come.
+	s.seq++
+	w := &waiter{
+		key:   float64(priority) - float64(time.Since(s.epoch))/float64(priorityAging),
+		seq:   s.seq,
+		ready: make(chan struct{}),
+	}
+	heap.Push(&s.waiters, w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-w.ready:
+			// Granted in the meantime; pass it on.
+			s.releaseLocked()
+		default:
+			heap.Remove(&s.waiters, w.index)
+		}
+		return ctx.Err()
+	}
+}
+
+func (s *semaphore) release() {
+	s.mu.Lock()
+	s.releaseLocked()
+	s.mu.Unlock()
+}
+
+func (s *semaphore) releaseLocked() {
+	if len(s.waiters) == 0 {
+		s.free++
+		return
+	}
+	w := heap.Pop(&s.waiters).(*waiter)
+	close(w.ready)
+}
+
+// waitQueue is a heap of waiters, the highest key first.
+type waitQueue []*waiter
+
+func (q waitQueue) Len() int { return len(q) }
+
+func (q waitQueue) Less(i, j int) bool {
+	if q[i].key != q[j].key {
+		return q[i].key > q[j].key
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index, q[j].index = i, j
+}
+
+func (q *waitQueue) Push(x interface{}) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waitQueue) Pop() interface{} {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return w
+}
@@ -0,0 +1,214 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// arrivals records the paths of the requests a handler gets, in order.
+type arrivals struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+func (a *arrivals) add(path string) {
+	a.mu.Lock()
+	a.paths = append(a.paths, path)
+	a.mu.Unlock()
+}
+
+func (a *arrivals) String() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return strings.Join(a.paths, " ")
+}
+
+// waiting returns the number of requests waiting for the semaphore of c.
+func waiting(c *httpClient) int {
+	c.sem.mu.Lock()
+	defer c.sem.mu.Unlock()
+	return len(c.sem.waiters)
+}
+
+// waitFor waits until n requests are waiting for the semaphore of c.
+func waitFor(t *testing.T, c *httpClient, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); waiting(c) != n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d waiting requests, want %d", waiting(c), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPriority(t *testing.T) {
+	var log arrivals
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r.URL.Path)
+		if strings.HasPrefix(r.URL.Path, "/bulk") {
+			time.Sleep(20 * time.Millisecond)
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+	c := New(WithBaseURL(srv.URL), WithMaxConcurrentRequests(1))
+
+	// Saturate the client with bulk downloads: one in flight, four waiting.
+	var urls []string
+	for i := 0; i < 5; i++ {
+		urls = append(urls, fmt.Sprintf("%s/bulk/%d", srv.URL, i))
+	}
+	done := make(chan error)
+	go func() {
+		var files []File
+		done <- c.Files(urls, &files, WithPriority(-1))
+	}()
+	waitFor(t, c, 4)
+
+	// An interactive call jumps the queue, and a second one at the default
+	// priority goes before the bulk work too, after the first.
+	var wg sync.WaitGroup
+	for _, p := range []struct {
+		path     string
+		priority int
+	}{{"/interactive", 10}, {"/default", 0}} {
+		wg.Add(1)
+		go func(path string, priority int) {
+			defer wg.Done()
+			var v struct{ OK bool }
+			if err := c.JSON(path, &v, WithPriority(priority)); err != nil || !v.OK {
+				t.Errorf("%s: got %+v, %v", path, v, err)
+			}
+		}(p.path, p.priority)
+		waitFor(t, c, 5)
+	}
+	wg.Wait()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Fields(log.String())
+	if len(got) != 7 || got[1] != "/interactive" || got[2] != "/default" {
+		t.Errorf("got arrival order %v, want /interactive then /default after the first download", got)
+	}
+	for _, p := range got[3:] {
+		if !strings.HasPrefix(p, "/bulk/") {
+			t.Errorf("got arrival order %v, want the bulk downloads last", got)
+			break
+		}
+	}
+}
+
+// waitSem waits until n waiters are queued on s.
+func waitSem(t *testing.T, s *semaphore, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		s.mu.Lock()
+		got := len(s.waiters)
+		s.mu.Unlock()
+		if got == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d waiters, want %d", got, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSemaphore(t *testing.T) {
+	tests := []struct {
+		name    string
+		waiters []int // priorities, in the order they come
+		age     int   // waiter whose arrival is moved back 3s, if not -1
+		want    string
+	}{
+		{"fifo", []int{0, 0, 0}, -1, "0 1 2"},
+		{"priority", []int{-1, 0, 5, 0, -1, 0}, -1, "2 1 3 5 0 4"},
+		{"aged", []int{0, 2, 1}, 0, "0 1 2"},
+		{"not aged enough", []int{0, 4, 1}, 0, "1 0 2"},
+	}
+	for _, tt := range tests {
+		s := newSemaphore(1)
+		if err := s.acquire(context.Background(), 0); err != nil {
+			t.Fatal(err)
+		}
+		var (
+			mu    sync.Mutex
+			order []string
+			wg    sync.WaitGroup
+		)
+		for i, p := range tt.waiters {
+			if i == tt.age+1 {
+				// Later waiters came 3s after the aged one.
+				s.mu.Lock()
+				s.epoch = s.epoch.Add(-3 * time.Second)
+				s.mu.Unlock()
+			}
+			wg.Add(1)
+			go func(i, p int) {
+				defer wg.Done()
+				if err := s.acquire(context.Background(), p); err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				order = append(order, fmt.Sprint(i))
+				mu.Unlock()
+				s.release()
+			}(i, p)
+			waitSem(t, s, i+1)
+		}
+		s.release()
+		wg.Wait()
+		if got := strings.Join(order, " "); got != tt.want {
+			t.Errorf("%s: got order %s, want %s", tt.name, got, tt.want)
+		}
+		if s.free != 1 {
+			t.Errorf("%s: got %d free, want 1", tt.name, s.free)
+		}
+	}
+}
+
+func TestSemaphoreCancel(t *testing.T) {
+	s := newSemaphore(1)
+	s.acquire(context.Background(), 0)
+
+	// A cancelled waiter leaves the queue, and the others are still served.
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 2)
+	go func() { errc <- s.acquire(ctx, 10) }()
+	waitSem(t, s, 1)
+	go func() { errc <- s.acquire(context.Background(), 0) }()
+	waitSem(t, s, 2)
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	waitSem(t, s, 1)
+	s.release()
+	if err := <-errc; err != nil {
+		t.Error(err)
+	}
+	s.release()
+	if s.free != 1 || len(s.waiters) != 0 {
+		t.Errorf("got %d free, %d waiters", s.free, len(s.waiters))
+	}
+
+	// A request cancelled while waiting fails with the context error.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	c := New(WithMaxConcurrentRequests(1))
+	c.sem.acquire(context.Background(), 0)
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.BytesContext(ctx, srv.URL); err == nil || !strings.Contains(err.Error(), "deadline exceeded") {
+		t.Errorf("got %v, want a deadline error", err)
+	}
+}
@@ -0,0 +1,174 @@
+// Package prommetrics exposes the measurements of an httpclient client as
+// Prometheus metrics.
+//
+//	collector := prommetrics.New(prommetrics.WithHostLabeler(prommetrics.DropPort))
+//	prometheus.MustRegister(collector)
+//	client := httpclient.New(httpclient.WithMetrics(collector))
+package prommetrics
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tamnd/httpclient"
+)
+
+// Collector implements httpclient.Metrics and httpclient.InFlightMetrics
+// on top of Prometheus metrics, and prometheus.Collector so that it can be
+// registered with an existing registry.
+type Collector struct {
+	namespace string
+	buckets   []float64
+	labeler   func(host string) string
+
+	duration *prometheus.HistogramVec
+	bytesIn  *prometheus.CounterVec
+	bytesOut *prometheus.CounterVec
+	retries  *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	inFlight *prometheus.GaugeVec
+}
+
+var (
+	_ httpclient.Metrics         = (*Collector)(nil)
+	_ httpclient.InFlightMetrics = (*Collector)(nil)
+	_ prometheus.Collector       = (*Collector)(nil)
+)
+
+// An Option configures a Collector.
+type Option func(*Collector)
+
+// WithNamespace sets the namespace of the metric names. The default is
+// "httpclient".
+func WithNamespace(namespace string) Option {
+	return func(c *Collector) {
+		c.namespace = namespace
+	}
+}
+
+// WithBuckets sets the buckets of the request duration histogram, in
+// seconds. The default is prometheus.DefBuckets.
+func WithBuckets(buckets []float64) Option {
+	return func(c *Collector) {
+		c.buckets = buckets
+	}
+}
+
+// WithHostLabeler maps every host through fn before it is used as a label
+// value. Use it to keep the number of series bounded when the client talks
+// to many hosts, e.g. by collapsing them into a handful of groups.
+func WithHostLabeler(fn func(host string) string) Option {
+	return func(c *Collector) {
+		c.labeler = fn
+	}
+}
+
+// DropPort is a host labeler that removes the port from the host.
+func DropPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// New returns a Collector.
+func New(opts ...Option) *Collector {
+	c := &Collector{
+		namespace: "httpclient",
+		buckets:   prometheus.DefBuckets,
+		labeler:   func(host string) string { return host },
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: c.namespace,
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests, until the response body was closed.",
+		Buckets:   c.buckets,
+	}, []string{"method", "host", "status"})
+	c.bytesIn = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: c.namespace,
+		Name:      "response_bytes_total",
+		Help:      "Bytes read from HTTP response bodies.",
+	}, []string{"host"})
+	c.bytesOut = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: c.namespace,
+		Name:      "request_bytes_total",
+		Help:      "Bytes sent in HTTP request bodies.",
+	}, []string{"host"})
+	c.retries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: c.namespace,
+		Name:      "retries_total",
+		Help:      "HTTP requests retried.",
+	}, []string{"host"})
+	c.errors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: c.namespace,
+		Name:      "errors_total",
+		Help:      "Failed HTTP requests by kind of failure.",
+	}, []string{"host", "kind"})
+	c.inFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: c.namespace,
+		Name:      "in_flight_requests",
+		Help:      "HTTP requests in progress.",
+	}, []string{"host"})
+	return c
+}
+
+// ObserveRequest implements httpclient.Metrics.
+func (c *Collector) ObserveRequest(method, host string, status int, duration time.Duration, bytesIn, bytesOut int64) {
+	host = c.labeler(host)
+	c.duration.WithLabelValues(method, host, statusClass(status)).Observe(duration.Seconds())
+	c.bytesIn.WithLabelValues(host).Add(float64(bytesIn))
+	c.bytesOut.WithLabelValues(host).Add(float64(bytesOut))
+}
+
+// IncRetry implements httpclient.Metrics.
+func (c *Collector) IncRetry(host string) {
+	c.retries.WithLabelValues(c.labeler(host)).Inc()
+}
+
+// IncError implements httpclient.Metrics.
+func (c *Collector) IncError(host, kind string) {
+	c.errors.WithLabelValues(c.labeler(host), kind).Inc()
+}
+
+// IncInFlight implements httpclient.InFlightMetrics.
+func (c *Collector) IncInFlight(host string) {
+	c.inFlight.WithLabelValues(c.labeler(host)).Inc()
+}
+
+// DecInFlight implements httpclient.InFlightMetrics.
+func (c *Collector) DecInFlight(host string) {
+	c.inFlight.WithLabelValues(c.labeler(host)).Dec()
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.duration.Describe(ch)
+	c.bytesIn.Describe(ch)
+	c.bytesOut.Describe(ch)
+	c.retries.Describe(ch)
+	c.errors.Describe(ch)
+	c.inFlight.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.duration.Collect(ch)
+	c.bytesIn.Collect(ch)
+	c.bytesOut.Collect(ch)
+	c.retries.Collect(ch)
+	c.errors.Collect(ch)
+	c.inFlight.Collect(ch)
+}
+
+// statusClass returns "2xx" for 200 and so on.
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return strconv.Itoa(status)
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
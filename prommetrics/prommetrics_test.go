@@ -0,0 +1,159 @@
+package prommetrics
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tamnd/httpclient"
+)
+
+func TestCollector(t *testing.T) {
+	var flaky int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case "/flaky":
+			if atomic.AddInt32(&flaky, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		io.WriteString(w, "hello")
+	}))
+	defer srv.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	// Both servers listen on 127.0.0.1, so DropPort collapses them into a
+	// single host label.
+	col := New(WithHostLabeler(DropPort))
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(col)
+
+	c := httpclient.New(httpclient.WithMetrics(col), httpclient.WithRetry(2, time.Millisecond))
+	for i := 0; i < 3; i++ {
+		if _, err := c.Bytes(srv.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Bytes(srv.URL + "/missing"); err == nil {
+		t.Fatal("got no error for /missing")
+	}
+	if _, err := c.Bytes(srv.URL + "/flaky"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Post(srv.URL, "text/plain", strings.NewReader("12345678"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if _, err := httpclient.New(httpclient.WithMetrics(col)).Bytes(down.URL); err == nil {
+		t.Fatal("got no error for a closed server")
+	}
+
+	want := `
+# HELP httpclient_errors_total Failed HTTP requests by kind of failure.
+# TYPE httpclient_errors_total counter
+httpclient_errors_total{host="127.0.0.1",kind="network"} 1
+httpclient_errors_total{host="127.0.0.1",kind="status"} 1
+# HELP httpclient_in_flight_requests HTTP requests in progress.
+# TYPE httpclient_in_flight_requests gauge
+httpclient_in_flight_requests{host="127.0.0.1"} 0
+# HELP httpclient_request_bytes_total Bytes sent in HTTP request bodies.
+# TYPE httpclient_request_bytes_total counter
+httpclient_request_bytes_total{host="127.0.0.1"} 8
+# HELP httpclient_response_bytes_total Bytes read from HTTP response bodies.
+# TYPE httpclient_response_bytes_total counter
+httpclient_response_bytes_total{host="127.0.0.1"} 25
+# HELP httpclient_retries_total HTTP requests retried.
+# TYPE httpclient_retries_total counter
+httpclient_retries_total{host="127.0.0.1"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want),
+		"httpclient_errors_total", "httpclient_in_flight_requests", "httpclient_request_bytes_total",
+		"httpclient_response_bytes_total", "httpclient_retries_total"); err != nil {
+		t.Error(err)
+	}
+
+	// The durations vary, so only the sample counts are compared.
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]uint64)
+	for _, mf := range families {
+		if mf.GetName() != "httpclient_request_duration_seconds" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			var labels []string
+			for _, l := range m.GetLabel() {
+				labels = append(labels, l.GetName()+"="+l.GetValue())
+			}
+			counts[strings.Join(labels, ",")] = m.GetHistogram().GetSampleCount()
+			if m.GetHistogram().GetSampleSum() <= 0 {
+				t.Errorf("%v: sum of durations is %v", labels, m.GetHistogram().GetSampleSum())
+			}
+		}
+	}
+	wantCounts := map[string]uint64{
+		"host=127.0.0.1,method=GET,status=2xx":  4,
+		"host=127.0.0.1,method=POST,status=2xx": 1,
+		"host=127.0.0.1,method=GET,status=4xx":  1,
+		"host=127.0.0.1,method=GET,status=5xx":  1,
+	}
+	if len(counts) != len(wantCounts) {
+		t.Errorf("got duration series %v, want %v", counts, wantCounts)
+	}
+	for k, n := range wantCounts {
+		if counts[k] != n {
+			t.Errorf("%s: got %d samples, want %d", k, counts[k], n)
+		}
+	}
+}
+
+func TestCollectorInFlight(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+	defer srv.Close()
+	col := New(WithNamespace("app"), WithHostLabeler(func(string) string { return "api" }))
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(col)
+
+	resp, err := httpclient.New(httpclient.WithMetrics(col)).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(col.inFlight.WithLabelValues("api")); got != 1 {
+		t.Errorf("got %v requests in flight with the body open, want 1", got)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if got := testutil.ToFloat64(col.inFlight.WithLabelValues("api")); got != 0 {
+		t.Errorf("got %v requests in flight after Close, want 0", got)
+	}
+	if n, err := testutil.GatherAndCount(reg, "app_request_duration_seconds"); err != nil || n != 1 {
+		t.Errorf("got %d duration series (%v), want 1", n, err)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	for status, want := range map[int]string{200: "2xx", 304: "3xx", 404: "4xx", 599: "5xx", 0: "0", 600: "600"} {
+		if got := statusClass(status); got != want {
+			t.Errorf("statusClass(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
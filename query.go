@@ -0,0 +1,52 @@
+package httpclient
+
+import (
+	"net/url"
+)
+
+// WithQuery adds the parameters in values to the query of the request URL.
+// Parameters set this way replace any parameter with the same name already
+// in the URL. The resulting query is encoded sorted by name.
+func WithQuery(values url.Values) RequestOption {
+	return func(o *requestOptions) {
+		for k, vs := range values {
+			for _, v := range vs {
+				o.addQuery(k, v)
+			}
+		}
+	}
+}
+
+// WithQueryParam adds a parameter to the query of the request URL, as
+// WithQuery does. It can be repeated to give a parameter several values:
+//
+//	client.JSON("/search", &out, httpclient.WithQueryParam("q", "a&b=c"))
+func WithQueryParam(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		o.addQuery(key, value)
+	}
+}
+
+func (o *requestOptions) addQuery(key, value string) {
+	if o.query == nil {
+		o.query = make(url.Values)
+	}
+	o.query.Add(key, value)
+}
+
+// withQuery returns rawurl with the query parameters of o merged in.
+func (o *requestOptions) withQuery(rawurl string) (string, error) {
+	if len(o.query) == 0 {
+		return rawurl, nil
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	for k, vs := range o.query {
+		q[k] = vs
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,81 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestWithQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		opts []RequestOption
+		want string
+	}{
+		{"none", "https://x.test/s?b=2&a=1", nil, "https://x.test/s?b=2&a=1"},
+		{"add", "https://x.test/s", []RequestOption{WithQueryParam("q", "go")}, "https://x.test/s?q=go"},
+		{
+			"merge sorted",
+			"https://x.test/s?z=26&a=1",
+			[]RequestOption{WithQueryParam("m", "13")},
+			"https://x.test/s?a=1&m=13&z=26",
+		},
+		{
+			"replace",
+			"https://x.test/s?page=1&page=2&q=go",
+			[]RequestOption{WithQueryParam("page", "3")},
+			"https://x.test/s?page=3&q=go",
+		},
+		{
+			"duplicates",
+			"https://x.test/s",
+			[]RequestOption{WithQueryParam("tag", "a"), WithQueryParam("tag", "b"), WithQuery(url.Values{"tag": {"c"}})},
+			"https://x.test/s?tag=a&tag=b&tag=c",
+		},
+		{
+			"escaping",
+			"https://x.test/s",
+			[]RequestOption{WithQueryParam("q", "a&b=c d+e/f?#%"), WithQueryParam("name", "Ünïcødé 日本")},
+			"https://x.test/s?name=%C3%9Cn%C3%AFc%C3%B8d%C3%A9+%E6%97%A5%E6%9C%AC&q=a%26b%3Dc+d%2Be%2Ff%3F%23%25",
+		},
+		{"empty value", "https://x.test/s", []RequestOption{WithQueryParam("flag", "")}, "https://x.test/s?flag="},
+		{"fragment kept", "https://x.test/s#top", []RequestOption{WithQueryParam("a", "1")}, "https://x.test/s?a=1#top"},
+	}
+	for _, tt := range tests {
+		got, err := newRequestOptions(tt.opts).withQuery(tt.url)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestWithQueryHelpers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/json" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{"q": r.URL.Query().Get("q"), "raw": r.URL.RawQuery})
+			return
+		}
+		io.WriteString(w, r.URL.RawQuery)
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL))
+	var out struct{ Q, Raw string }
+	if err := c.JSON("/json?lang=en", &out, WithQueryParam("q", "a&b=c")); err != nil {
+		t.Fatal(err)
+	}
+	if out.Q != "a&b=c" || out.Raw != "lang=en&q=a%26b%3Dc" {
+		t.Errorf("JSON: got %+v", out)
+	}
+	if s, err := c.String("/search", WithQuery(url.Values{"q": {"x y"}})); err != nil || s != "q=x+y" {
+		t.Errorf("String: got %q, %v", s, err)
+	}
+	if p, err := c.Bytes("/search", WithQueryParam("q", "é")); err != nil || string(p) != "q=%C3%A9" {
+		t.Errorf("Bytes: got %q, %v", p, err)
+	}
+}
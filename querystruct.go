@@ -0,0 +1,183 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithQueryStruct adds the fields of the struct v, or pointer to one, to
+// the query of the request URL, as WithQuery does. Fields are encoded as
+// described by EncodeQuery; if that fails, so does the request.
+func WithQueryStruct(v interface{}) RequestOption {
+	return func(o *requestOptions) {
+		values, err := EncodeQuery(v)
+		if err != nil {
+			o.fail(err)
+			return
+		}
+		WithQuery(values)(o)
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// EncodeQuery encodes the fields of the struct v, or pointer to one, as
+// query parameters, following their "query" tags:
+//
+//	type ListOptions struct {
+//		Page  int       `query:"page,omitempty"`
+//		Tags  []string  `query:"tag"`           // tag=a&tag=b
+//		IDs   []int     `query:"ids,comma"`     // ids=1,2,3
+//		Since time.Time `query:"since,unix"`
+//		Owner *string   `query:"owner"`         // omitted when nil
+//		Debug bool      `query:"-"`             // never encoded
+//	}
+//
+// Fields without a tag use the field name; unexported fields are skipped
+// and embedded structs are flattened. Strings, booleans, integers, floats,
+// time.Time and slices or arrays of those are supported, as well as
+// pointers to any of them. An empty slice adds no parameter. The flags
+// are:
+//
+//	omitempty   skip the field when it has its zero value
+//	comma       join the values of a slice with commas instead of
+//	            repeating the parameter
+//	rfc3339     encode a time as RFC 3339, the default
+//	date        encode a time as 2006-01-02
+//	unix        encode a time as seconds since the Unix epoch
+//	unixmilli   encode a time as milliseconds since the Unix epoch
+//
+// A field of any other kind is an error naming the field.
+func EncodeQuery(v interface{}) (url.Values, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return url.Values{}, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("httpclient: query struct: %T is not a struct", v)
+	}
+	values := make(url.Values)
+	if err := encodeStruct(values, rv); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func encodeStruct(values url.Values, rv reflect.Value) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("query")
+		if tag == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+		if f.Anonymous && tag == "" {
+			for fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					break
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+				if err := encodeStruct(values, fv); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		name, flags := f.Name, ""
+		if tag != "" {
+			parts := strings.SplitN(tag, ",", 2)
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			if len(parts) == 2 {
+				flags = "," + parts[1] + ","
+			}
+		}
+		has := func(flag string) bool { return strings.Contains(flags, ","+flag+",") }
+
+		for fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Ptr || has("omitempty") && fv.IsZero() {
+			continue
+		}
+		if (fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array) && fv.Type().Elem().Kind() != reflect.Uint8 {
+			var s []string
+			for j := 0; j < fv.Len(); j++ {
+				v, err := encodeValue(fv.Index(j), has)
+				if err != nil {
+					return fmt.Errorf("httpclient: query struct: field %s: %v", f.Name, err)
+				}
+				s = append(s, v)
+			}
+			switch {
+			case len(s) == 0:
+			case has("comma"):
+				values.Add(name, strings.Join(s, ","))
+			default:
+				for _, v := range s {
+					values.Add(name, v)
+				}
+			}
+			continue
+		}
+		v, err := encodeValue(fv, has)
+		if err != nil {
+			return fmt.Errorf("httpclient: query struct: field %s: %v", f.Name, err)
+		}
+		values.Add(name, v)
+	}
+	return nil
+}
+
+func encodeValue(v reflect.Value, has func(string) bool) (string, error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		switch {
+		case has("unix"):
+			return strconv.FormatInt(t.Unix(), 10), nil
+		case has("unixmilli"):
+			return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10), nil
+		case has("date"):
+			return t.Format("2006-01-02"), nil
+		}
+		return t.Format(time.RFC3339), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), nil
+	}
+	return "", fmt.Errorf("unsupported kind %s", v.Kind())
+}
@@ -0,0 +1,204 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type queryPage struct {
+	Page int `query:"page,omitempty"`
+	Size int `query:"size,omitempty"`
+}
+
+func TestEncodeQuery(t *testing.T) {
+	since := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+	owner, zero := "ann", 0
+	ownerp := &owner
+
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"string", struct {
+			Q string `query:"q"`
+		}{"a b&c"}, "q=a+b%26c"},
+		{"empty string", struct {
+			Q string `query:"q"`
+		}{}, "q="},
+		{"field name", struct{ Lang string }{"go"}, "Lang=go"},
+		{"empty name", struct {
+			Lang string `query:",omitempty"`
+		}{"go"}, "Lang=go"},
+		{"skipped", struct {
+			Q     string `query:"-"`
+			debug bool
+		}{"x", true}, ""},
+		{"bool", struct {
+			On  bool `query:"on"`
+			Off bool `query:"off"`
+		}{true, false}, "off=false&on=true"},
+		{"ints", struct {
+			A int   `query:"a"`
+			B int8  `query:"b"`
+			C int64 `query:"c"`
+		}{-1, 127, 1 << 40}, "a=-1&b=127&c=1099511627776"},
+		{"uints", struct {
+			A uint   `query:"a"`
+			B uint16 `query:"b"`
+			C uint64 `query:"c"`
+		}{1, 65535, 1 << 63}, "a=1&b=65535&c=9223372036854775808"},
+		{"floats", struct {
+			A float32 `query:"a"`
+			B float64 `query:"b"`
+			C float64 `query:"c"`
+		}{0.1, 2.5, 1e21}, "a=0.1&b=2.5&c=1e%2B21"},
+		{"repeated", struct {
+			Tags []string `query:"tag"`
+		}{[]string{"a", "b", "a"}}, "tag=a&tag=b&tag=a"},
+		{"comma", struct {
+			IDs []int `query:"ids,comma"`
+		}{[]int{1, 2, 3}}, "ids=1%2C2%2C3"},
+		{"array", struct {
+			Box [2]float64 `query:"box,comma"`
+		}{[2]float64{1.5, -2}}, "box=1.5%2C-2"},
+		{"empty slice", struct {
+			Tags []string `query:"tag"`
+			IDs  []int    `query:"ids,comma"`
+		}{}, ""},
+		{"empty slice omitted", struct {
+			IDs []int `query:"ids,comma,omitempty"`
+		}{IDs: []int{}}, ""},
+		{"time", struct {
+			Since time.Time `query:"since"`
+		}{since}, "since=2024-03-05T14%3A30%3A00Z"},
+		{"rfc3339", struct {
+			Since time.Time `query:"since,rfc3339"`
+		}{since.In(time.FixedZone("", 2*3600))}, "since=2024-03-05T16%3A30%3A00%2B02%3A00"},
+		{"date", struct {
+			Day time.Time `query:"day,date"`
+		}{since}, "day=2024-03-05"},
+		{"unix", struct {
+			Since time.Time `query:"since,unix"`
+		}{since}, "since=1709649000"},
+		{"unixmilli", struct {
+			Since time.Time `query:"since,unixmilli"`
+		}{since.Add(250 * time.Millisecond)}, "since=1709649000250"},
+		{"times", struct {
+			Days []time.Time `query:"d,date,comma"`
+		}{[]time.Time{since, since.AddDate(0, 0, 1)}}, "d=2024-03-05%2C2024-03-06"},
+		{"pointer", struct {
+			Owner *string `query:"owner"`
+			N     *int    `query:"n"`
+		}{&owner, &zero}, "n=0&owner=ann"},
+		{"nil pointer", struct {
+			Owner *string    `query:"owner"`
+			Since *time.Time `query:"since"`
+		}{}, ""},
+		{"pointer to pointer", struct {
+			Owner **string `query:"owner"`
+		}{&ownerp}, "owner=ann"},
+		{"slice of pointers", struct {
+			Tags []*string `query:"tag"`
+		}{[]*string{&owner, &owner}}, "tag=ann&tag=ann"},
+		{"omitempty", struct {
+			Page  int       `query:"page,omitempty"`
+			Q     string    `query:"q,omitempty"`
+			On    bool      `query:"on,omitempty"`
+			Since time.Time `query:"since,omitempty"`
+			Tags  []string  `query:"tag,omitempty"`
+		}{}, ""},
+		{"omitempty pointer to zero", struct {
+			N *int `query:"n,omitempty"`
+		}{&zero}, ""},
+		{"without omitempty", struct {
+			Page int `query:"page"`
+		}{}, "page=0"},
+		{"embedded", struct {
+			queryPage
+			Q string `query:"q"`
+		}{queryPage{Page: 2}, "go"}, "page=2&q=go"},
+		{"embedded pointer", struct {
+			*queryPage
+			Q string `query:"q"`
+		}{&queryPage{Size: 50}, "go"}, "q=go&size=50"},
+		{"embedded nil", struct {
+			*queryPage
+		}{}, ""},
+		{"pointer to struct", &queryPage{Page: 3}, "page=3"},
+		{"nil struct", (*queryPage)(nil), ""},
+	}
+	for _, tt := range tests {
+		values, err := EncodeQuery(tt.v)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := values.Encode(); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeQueryErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"not a struct", map[string]string{"a": "b"}, "map[string]string is not a struct"},
+		{"map", struct {
+			M map[string]int `query:"m"`
+		}{}, "field M: unsupported kind map"},
+		{"nested struct", struct {
+			Page queryPage `query:"page"`
+		}{}, "field Page: unsupported kind struct"},
+		{"slice of maps", struct {
+			Ms []map[string]int `query:"m"`
+		}{[]map[string]int{{}}}, "field Ms: unsupported kind map"},
+		{"func", struct {
+			F func() `query:"f"`
+		}{func() {}}, "field F: unsupported kind func"},
+	}
+	for _, tt := range tests {
+		_, err := EncodeQuery(tt.v)
+		if err == nil || !strings.HasPrefix(err.Error(), "httpclient: query struct: ") || !strings.HasSuffix(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error ending in %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestWithQueryStruct(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		io.WriteString(w, r.URL.RawQuery)
+	}))
+	defer srv.Close()
+
+	type listOpts struct {
+		Page  int       `query:"page"`
+		Tags  []string  `query:"tag"`
+		Since time.Time `query:"since,rfc3339"`
+	}
+	c := New(WithBaseURL(srv.URL))
+	opts := listOpts{Page: 2, Tags: []string{"go", "http"}, Since: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	s, err := c.String("/items?sort=new", WithQueryStruct(opts))
+	if want := "page=2&since=2024-01-02T03%3A04%3A05Z&sort=new&tag=go&tag=http"; err != nil || s != want {
+		t.Errorf("got %q, %v, want %q", s, err, want)
+	}
+
+	_, err = c.String("/items", WithQueryStruct(struct {
+		Filter map[string]string `query:"filter"`
+	}{}))
+	if err == nil || !strings.Contains(err.Error(), "field Filter") {
+		t.Errorf("got %v, want an error naming the field", err)
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+}
@@ -0,0 +1,173 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrRangeIgnored is returned by BytesRange when the server answers a
+// ranged request with the whole resource.
+var ErrRangeIgnored = errors.New("httpclient: server does not support ranged requests")
+
+// WithAllowFullBodyFallback makes BytesRange accept a server that ignores
+// the range and sends the whole resource, and cut the range out of it.
+func WithAllowFullBodyFallback() RequestOption {
+	return func(o *requestOptions) {
+		o.fullBodyFallback = true
+	}
+}
+
+// BytesRange fetches length bytes of url starting at offset with a Range
+// request. It returns fewer bytes if the resource ends before. The
+// Content-Range of the 206 response must match the request. If the server
+// sends the whole resource instead, BytesRange fails with ErrRangeIgnored,
+// unless WithAllowFullBodyFallback is given.
+func (c *httpClient) BytesRange(url string, offset, length int64, opts ...RequestOption) ([]byte, error) {
+	if offset < 0 || length <= 0 {
+		return nil, fmt.Errorf("httpclient: invalid range %d+%d", offset, length)
+	}
+	o := newRequestOptions(opts)
+	WithRequestHeader("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))(o)
+	resp, err := c.send("GET", url, nil, o)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		if !o.fullBodyFallback {
+			return nil, fmt.Errorf("%w: %s", ErrRangeIgnored, c.redact.url(resp.Request.URL))
+		}
+		if _, err := io.CopyN(ioutil.Discard, resp.Body, offset); err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		resp.ContentLength = -1
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.LimitReader(resp.Body, length), resp.Body}
+		return c.readAll(resp)
+	default:
+		return nil, c.err(resp, "")
+	}
+
+	start, end, _, ok := parseContentRange(resp.Header.Get("Content-Range"))
+	if !ok || start != offset || end > offset+length-1 || end < start {
+		return nil, c.err(resp, fmt.Sprintf("Content-Range %q does not match bytes=%d-%d at %s",
+			resp.Header.Get("Content-Range"), offset, offset+length-1, c.redact.url(resp.Request.URL)))
+	}
+	p, err := c.readAll(resp)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(p)) != end-start+1 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return p, nil
+}
+
+// BytesRange fetches a range of url with the default client.
+func BytesRange(url string, offset, length int64, opts ...RequestOption) ([]byte, error) {
+	return defaultClient().BytesRange(url, offset, length, opts...)
+}
+
+// parseContentRange parses a "bytes start-end/size" header value. size is
+// -1 if unknown.
+func parseContentRange(v string) (start, end, size int64, ok bool) {
+	if !strings.HasPrefix(v, "bytes ") {
+		return 0, 0, 0, false
+	}
+	v = strings.TrimSpace(v[len("bytes "):])
+	i, j := strings.IndexByte(v, '-'), strings.IndexByte(v, '/')
+	if i < 0 || j < i {
+		return 0, 0, 0, false
+	}
+	var err error
+	if start, err = strconv.ParseInt(v[:i], 10, 64); err != nil {
+		return 0, 0, 0, false
+	}
+	if end, err = strconv.ParseInt(v[i+1:j], 10, 64); err != nil {
+		return 0, 0, 0, false
+	}
+	size = -1
+	if v[j+1:] != "*" {
+		if size, err = strconv.ParseInt(v[j+1:], 10, 64); err != nil {
+			return 0, 0, 0, false
+		}
+	}
+	return start, end, size, true
+}
+
+// NewRemoteReaderAt returns an io.ReaderAt reading the resource at url
+// with ranged requests, along with its size, found with a HEAD request.
+// It lets packages such as archive/zip read a remote file directly:
+//
+//	r, size, err := client.NewRemoteReaderAt(url)
+//	if err != nil { ... }
+//	z, err := zip.NewReader(r, size)
+//
+// The options apply to every request.
+func (c *httpClient) NewRemoteReaderAt(url string, opts ...RequestOption) (io.ReaderAt, int64, error) {
+	resp, err := c.send("HEAD", url, nil, newRequestOptions(opts))
+	if err != nil {
+		return nil, 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, 0, c.err(resp, "")
+	}
+	if resp.ContentLength < 0 {
+		return nil, 0, c.err(resp, "unknown size of "+c.redact.url(resp.Request.URL).String())
+	}
+	if resp.Header.Get("Accept-Ranges") == "none" {
+		return nil, 0, fmt.Errorf("%w: %s", ErrRangeIgnored, c.redact.url(resp.Request.URL))
+	}
+	return &remoteReaderAt{c: c, url: resp.Request.URL.String(), size: resp.ContentLength, opts: opts}, resp.ContentLength, nil
+}
+
+// NewRemoteReaderAt returns an io.ReaderAt for url with the default client.
+func NewRemoteReaderAt(url string, opts ...RequestOption) (io.ReaderAt, int64, error) {
+	return defaultClient().NewRemoteReaderAt(url, opts...)
+}
+
+type remoteReaderAt struct {
+	c    *httpClient
+	url  string
+	size int64
+	opts []RequestOption
+}
+
+func (r *remoteReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("httpclient: negative offset %d", off)
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n := int64(len(p))
+	if off+n > r.size {
+		n = r.size - off
+	}
+	b, err := r.c.BytesRange(r.url, off, n, r.opts...)
+	copy(p, b)
+	if err != nil {
+		return len(b), err
+	}
+	if len(b) < len(p) {
+		return len(b), io.EOF
+	}
+	return len(b), nil
+}
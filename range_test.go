@@ -0,0 +1,250 @@
+package httpclient
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rangeServer serves data at /file, honoring ranges, and at /full,
+// ignoring them. /norange answers HEAD requests with Accept-Ranges: none,
+// and /liar every request with the first ten bytes. It records the ranges
+// asked for and counts the body bytes it sends.
+type rangeServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	sent   int64
+	ranges []string
+}
+
+func newRangeServer(data []byte) *rangeServer {
+	s := &rangeServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cw := &countingWriter{ResponseWriter: w}
+		s.mu.Lock()
+		s.ranges = append(s.ranges, r.Method+" "+r.Header.Get("Range"))
+		s.mu.Unlock()
+		switch r.URL.Path {
+		case "/file":
+			http.ServeContent(cw, r, "file", time.Time{}, bytes.NewReader(data))
+		case "/full":
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+			if r.Method != "HEAD" {
+				cw.Write(data)
+			}
+		case "/norange":
+			w.Header().Set("Accept-Ranges", "none")
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		case "/liar":
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-9/%d", len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+			cw.Write(data[:10])
+		}
+		s.mu.Lock()
+		s.sent += cw.n
+		s.mu.Unlock()
+	}))
+	return s
+}
+
+func (s *rangeServer) bytesSent() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sent
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+func TestBytesRange(t *testing.T) {
+	data := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	srv := newRangeServer(data)
+	defer srv.Close()
+	c := New(WithBaseURL(srv.URL))
+
+	tests := []struct {
+		name           string
+		path           string
+		offset, length int64
+		opts           []RequestOption
+		want           string
+		err            error
+	}{
+		{"start", "/file", 0, 4, nil, "0123", nil},
+		{"middle", "/file", 10, 6, nil, "abcdef", nil},
+		{"short at the end", "/file", 30, 100, nil, "uvwxyz", nil},
+		{"last byte", "/file", 35, 1, nil, "z", nil},
+		{"ignored", "/full", 10, 6, nil, "", ErrRangeIgnored},
+		{"fallback", "/full", 10, 6, []RequestOption{WithAllowFullBodyFallback()}, "abcdef", nil},
+		{"fallback short", "/full", 30, 100, []RequestOption{WithAllowFullBodyFallback()}, "uvwxyz", nil},
+		{"fallback past the end", "/full", 100, 4, []RequestOption{WithAllowFullBodyFallback()}, "", io.EOF},
+	}
+	for _, tt := range tests {
+		got, err := c.BytesRange(tt.path, tt.offset, tt.length, tt.opts...)
+		if !errors.Is(err, tt.err) || string(got) != tt.want {
+			t.Errorf("%s: got %q, %v, want %q, %v", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+	if want := "GET bytes=10-15"; srv.ranges[1] != want {
+		t.Errorf("got %q, want %q", srv.ranges[1], want)
+	}
+
+	// A range past the end gets a 416, a wrong Content-Range an error.
+	_, err := c.BytesRange("/file", 100, 4)
+	var herr *Error
+	if !errors.As(err, &herr) || herr.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("past the end: got %v, want a 416", err)
+	}
+	_, err = c.BytesRange("/liar", 5, 5)
+	if err == nil || !strings.Contains(err.Error(), `Content-Range "bytes 0-9/36" does not match bytes=5-9`) {
+		t.Errorf("liar: got %v", err)
+	}
+	for _, r := range [][2]int64{{-1, 4}, {0, 0}, {4, -1}} {
+		if _, err := c.BytesRange("/file", r[0], r[1]); err == nil || !strings.Contains(err.Error(), "invalid range") {
+			t.Errorf("%v: got %v", r, err)
+		}
+	}
+}
+
+func TestRemoteReaderAtZip(t *testing.T) {
+	// A zip with a large incompressible member and a small one.
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	big := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(big)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{"big.bin", big}, {"hello.txt", []byte("hello, remote zip")}} {
+		w, err := z.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(f.data)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	srv := newRangeServer(buf.Bytes())
+	defer srv.Close()
+
+	r, size, err := NewRemoteReaderAt(srv.URL + "/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(buf.Len()) {
+		t.Errorf("got size %d, want %d", size, buf.Len())
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != 2 || zr.File[1].Name != "hello.txt" {
+		t.Fatalf("got %d files", len(zr.File))
+	}
+	f, err := zr.File[1].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil || string(p) != "hello, remote zip" {
+		t.Errorf("got %q, %v", p, err)
+	}
+	if n := srv.bytesSent(); n > size/10 {
+		t.Errorf("read %d bytes of %d, want only the directory and one member", n, size)
+	}
+	if srv.ranges[0] != "HEAD " {
+		t.Errorf("got first request %q, want a HEAD", srv.ranges[0])
+	}
+}
+
+func TestRemoteReaderAt(t *testing.T) {
+	data := []byte("0123456789")
+	srv := newRangeServer(data)
+	defer srv.Close()
+
+	r, size, err := NewRemoteReaderAt(srv.URL + "/file")
+	if err != nil || size != 10 {
+		t.Fatalf("got %d, %v", size, err)
+	}
+	tests := []struct {
+		off  int64
+		n    int
+		want string
+		err  error
+	}{
+		{0, 4, "0123", nil},
+		{6, 4, "6789", nil},
+		{8, 4, "89", io.EOF},
+		{10, 4, "", io.EOF},
+		{3, 0, "", nil},
+	}
+	for _, tt := range tests {
+		p := make([]byte, tt.n)
+		n, err := r.ReadAt(p, tt.off)
+		if err != tt.err || string(p[:n]) != tt.want {
+			t.Errorf("ReadAt(%d, %d): got %q, %v, want %q, %v", tt.n, tt.off, p[:n], err, tt.want, tt.err)
+		}
+	}
+	if _, err := r.ReadAt(make([]byte, 1), -1); err == nil {
+		t.Error("negative offset: got no error")
+	}
+
+	// A server that ignores ranges is found out on the first read, one
+	// that says so on the HEAD request.
+	r, _, err = NewRemoteReaderAt(srv.URL + "/full")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadAt(make([]byte, 4), 2); !errors.Is(err, ErrRangeIgnored) {
+		t.Errorf("full: got %v, want ErrRangeIgnored", err)
+	}
+	if _, _, err := NewRemoteReaderAt(srv.URL + "/norange"); !errors.Is(err, ErrRangeIgnored) {
+		t.Errorf("norange: got %v, want ErrRangeIgnored", err)
+	}
+	if _, _, err := NewRemoteReaderAt(srv.URL + "/missing"); err == nil {
+		t.Error("missing: got no error")
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		v                string
+		start, end, size int64
+		ok               bool
+	}{
+		{"bytes 0-9/36", 0, 9, 36, true},
+		{"bytes 10-15/*", 10, 15, -1, true},
+		{"bytes  5-5/6", 5, 5, 6, true},
+		{"bytes */36", 0, 0, 0, false},
+		{"items 0-9/36", 0, 0, 0, false},
+		{"bytes 0-x/36", 0, 0, 0, false},
+		{"bytes 0-9", 0, 0, 0, false},
+		{"", 0, 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, end, size, ok := parseContentRange(tt.v)
+		if start != tt.start || end != tt.end || size != tt.size || ok != tt.ok {
+			t.Errorf("%q: got %d, %d, %d, %v", tt.v, start, end, size, ok)
+		}
+	}
+}
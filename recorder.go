@@ -0,0 +1,203 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// TestingT is the part of testing.TB used by the assertion helpers, so that
+// this package does not depend on the testing package.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// A RequestRecorder is a RoundTripper middleware that records the requests
+// going through it, and provides assertions about them for tests:
+//
+//	rec := httpclient.NewRequestRecorder(stub)
+//	client := httpclient.NewWithTransport(rec)
+//	...
+//	rec.AssertRequested(t, "POST", "https://api.example.com/items")
+//	rec.AssertJSONBody(t, "https://api.example.com/items", `{"name": "foo"}`)
+//
+// URL patterns are globs as in StubTransport.Handle. Every assertion reports
+// a failure through t.Errorf, listing the recorded requests, and returns
+// whether it passed.
+type RequestRecorder struct {
+	next http.RoundTripper
+
+	mu    sync.Mutex
+	calls []RecordedCall
+}
+
+// NewRequestRecorder returns a recorder that sends requests with next, or
+// with http.DefaultTransport if next is nil.
+func NewRequestRecorder(next http.RoundTripper) *RequestRecorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &RequestRecorder{next: next}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *RequestRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	call := RecordedCall{Method: req.Method, URL: req.URL.String(), Header: req.Header.Clone()}
+	if req.Body != nil && req.Body != http.NoBody {
+		p, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		call.Body = p
+		req.Body = ioutil.NopCloser(bytes.NewReader(p))
+	}
+	r.mu.Lock()
+	r.calls = append(r.calls, call)
+	r.mu.Unlock()
+	return r.next.RoundTrip(req)
+}
+
+// Requests returns the requests recorded so far, in the order they were
+// sent.
+func (r *RequestRecorder) Requests() []RecordedCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	calls := make([]RecordedCall, len(r.calls))
+	copy(calls, r.calls)
+	return calls
+}
+
+// matching returns the recorded requests with the method, unless it is
+// empty, and a URL matching pattern.
+func (r *RequestRecorder) matching(method, pattern string) []RecordedCall {
+	re := globRegexp(pattern)
+	var calls []RecordedCall
+	for _, c := range r.Requests() {
+		if (method == "" || c.Method == method) && re.MatchString(c.URL) {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// AssertRequested checks that a request with the method and a URL matching
+// urlPattern was sent.
+func (r *RequestRecorder) AssertRequested(t TestingT, method, urlPattern string) bool {
+	t.Helper()
+	if len(r.matching(method, urlPattern)) > 0 {
+		return true
+	}
+	t.Errorf("httpclient: no %s request to %s\n%s", method, urlPattern, r.describe())
+	return false
+}
+
+// AssertNumberOfCalls checks that n requests were sent to URLs matching
+// urlPattern.
+func (r *RequestRecorder) AssertNumberOfCalls(t TestingT, urlPattern string, n int) bool {
+	t.Helper()
+	if got := len(r.matching("", urlPattern)); got != n {
+		t.Errorf("httpclient: %d requests to %s, want %d\n%s", got, urlPattern, n, r.describe())
+		return false
+	}
+	return true
+}
+
+// AssertHeader checks that every request sent to a URL matching urlPattern
+// has the header, with a value starting with wantPrefix. There must be at
+// least one such request.
+func (r *RequestRecorder) AssertHeader(t TestingT, urlPattern, name, wantPrefix string) bool {
+	t.Helper()
+	calls := r.matching("", urlPattern)
+	if len(calls) == 0 {
+		t.Errorf("httpclient: no request to %s\n%s", urlPattern, r.describe())
+		return false
+	}
+	for _, c := range calls {
+		if v, ok := c.Header[http.CanonicalHeaderKey(name)]; !ok || !strings.HasPrefix(v[0], wantPrefix) {
+			t.Errorf("httpclient: %s %s: header %s = %q, want prefix %q", c.Method, c.URL, name, c.Header.Get(name), wantPrefix)
+			return false
+		}
+	}
+	return true
+}
+
+// AssertJSONBody checks that a request was sent to a URL matching urlPattern
+// with a JSON body containing wantSubsetJSON. Objects match when every field
+// of the wanted object matches, regardless of order and extra fields;
+// arrays match element by element.
+func (r *RequestRecorder) AssertJSONBody(t TestingT, urlPattern string, wantSubsetJSON string) bool {
+	t.Helper()
+	var want interface{}
+	if err := json.Unmarshal([]byte(wantSubsetJSON), &want); err != nil {
+		t.Errorf("httpclient: invalid wanted JSON %s: %v", wantSubsetJSON, err)
+		return false
+	}
+	calls := r.matching("", urlPattern)
+	for _, c := range calls {
+		var got interface{}
+		if json.Unmarshal(c.Body, &got) == nil && jsonSubset(want, got) {
+			return true
+		}
+	}
+	var b strings.Builder
+	for _, c := range calls {
+		fmt.Fprintf(&b, "  %s %s\n    %s\n", c.Method, c.URL, c.Body)
+	}
+	if len(calls) == 0 {
+		b.WriteString("  (no requests)\n")
+	}
+	t.Errorf("httpclient: no request to %s with a JSON body containing %s; got:\n%s", urlPattern, wantSubsetJSON, b.String())
+	return false
+}
+
+// jsonSubset reports whether the decoded JSON value want is contained in
+// got.
+func jsonSubset(want, got interface{}) bool {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, wv := range w {
+			gv, ok := g[k]
+			if !ok || !jsonSubset(wv, gv) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for i := range w {
+			if !jsonSubset(w[i], g[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(want, got)
+}
+
+func (r *RequestRecorder) describe() string {
+	calls := r.Requests()
+	if len(calls) == 0 {
+		return "recorded requests: none"
+	}
+	var b strings.Builder
+	b.WriteString("recorded requests:")
+	for _, c := range calls {
+		fmt.Fprintf(&b, "\n  %s %s", c.Method, c.URL)
+	}
+	return b.String()
+}
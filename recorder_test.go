@@ -0,0 +1,152 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeT collects the failures reported by the assertion helpers.
+type fakeT struct {
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Fatalf(format string, args ...interface{}) {
+	t.Errorf(format, args...)
+}
+
+// recordedWorkload sends a few requests through a recorder in front of a
+// stub and returns the recorder.
+func recordedWorkload(t *testing.T) *RequestRecorder {
+	rec := NewRequestRecorder(NewStub().HandleDefault(Stub(200, "{}")))
+	c := NewWithTransport(rec)
+	auth := WithRequestHeader("Authorization", "Bearer abc")
+	if err := c.PostJSON("https://api.example.com/items", map[string]interface{}{
+		"name": "foo", "tags": []string{"a", "b"}, "owner": map[string]interface{}{"id": 7, "team": "x"},
+	}, nil, auth); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Bytes("https://api.example.com/items/1", auth); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Bytes("https://api.example.com/items/2"); err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func TestRequestRecorderPasses(t *testing.T) {
+	rec := recordedWorkload(t)
+	ft := &fakeT{}
+	ok := rec.AssertRequested(ft, "POST", "https://api.example.com/items") &&
+		rec.AssertRequested(ft, "GET", "https://api.example.com/items/*") &&
+		rec.AssertNumberOfCalls(ft, "https://api.example.com/items*", 3) &&
+		rec.AssertNumberOfCalls(ft, "*/items/?", 2) &&
+		rec.AssertHeader(ft, "https://api.example.com/items", "authorization", "Bearer ") &&
+		// Field order and extra fields do not matter.
+		rec.AssertJSONBody(ft, "*/items", `{"owner": {"team": "x"}, "name": "foo"}`) &&
+		rec.AssertJSONBody(ft, "*/items", `{"tags": ["a", "b"]}`)
+	if !ok || len(ft.errors) > 0 {
+		t.Errorf("got failures %q", ft.errors)
+	}
+	if calls := rec.Requests(); len(calls) != 3 || calls[0].Header.Get("Content-Type") != "application/json" {
+		t.Errorf("got recorded calls %+v", calls)
+	}
+}
+
+func TestRequestRecorderFailures(t *testing.T) {
+	rec := recordedWorkload(t)
+	recorded := "recorded requests:\n" +
+		"  POST https://api.example.com/items\n" +
+		"  GET https://api.example.com/items/1\n" +
+		"  GET https://api.example.com/items/2"
+
+	tests := []struct {
+		name   string
+		assert func(TestingT) bool
+		want   string
+	}{
+		{
+			"method",
+			func(t TestingT) bool { return rec.AssertRequested(t, "DELETE", "*/items/*") },
+			"httpclient: no DELETE request to */items/*\n" + recorded,
+		},
+		{
+			"count",
+			func(t TestingT) bool { return rec.AssertNumberOfCalls(t, "*/items/*", 3) },
+			"httpclient: 2 requests to */items/*, want 3\n" + recorded,
+		},
+		{
+			"header",
+			func(t TestingT) bool { return rec.AssertHeader(t, "*/items/*", "Authorization", "Bearer ") },
+			`httpclient: GET https://api.example.com/items/2: header Authorization = "", want prefix "Bearer "`,
+		},
+		{
+			"header without request",
+			func(t TestingT) bool { return rec.AssertHeader(t, "*/users", "Authorization", "Bearer ") },
+			"httpclient: no request to */users\n" + recorded,
+		},
+		{
+			"array subset",
+			// Arrays match element by element.
+			func(t TestingT) bool { return rec.AssertJSONBody(t, "*/items", `{"tags": ["b", "a"]}`) },
+			`httpclient: no request to */items with a JSON body containing {"tags": ["b", "a"]}; got:` + "\n" +
+				"  POST https://api.example.com/items\n" +
+				`    {"name":"foo","owner":{"id":7,"team":"x"},"tags":["a","b"]}` + "\n",
+		},
+		{
+			"body without request",
+			func(t TestingT) bool { return rec.AssertJSONBody(t, "*/users", `{}`) },
+			"httpclient: no request to */users with a JSON body containing {}; got:\n  (no requests)\n",
+		},
+		{
+			"invalid JSON",
+			func(t TestingT) bool { return rec.AssertJSONBody(t, "*/items", `{name}`) },
+			"httpclient: invalid wanted JSON {name}: invalid character 'n' looking for beginning of object key string",
+		},
+	}
+	for _, tt := range tests {
+		ft := &fakeT{}
+		if tt.assert(ft) {
+			t.Errorf("%s: passed", tt.name)
+		}
+		if len(ft.errors) != 1 || ft.errors[0] != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, strings.Join(ft.errors, "\n---\n"), tt.want)
+		}
+	}
+}
+
+func TestJSONSubset(t *testing.T) {
+	tests := []struct {
+		want, got string
+		ok        bool
+	}{
+		{`{}`, `{"a":1}`, true},
+		{`{"a":1}`, `{"b":2,"a":1}`, true},
+		{`{"a":{"b":[1,{"c":2}]}}`, `{"a":{"b":[1,{"c":2,"d":3}],"e":4}}`, true},
+		{`{"a":1}`, `{"a":"1"}`, false},
+		{`{"a":null}`, `{}`, false},
+		{`[1,2]`, `[1,2,3]`, false},
+		{`{"a":[]}`, `{"a":{}}`, false},
+		{`"x"`, `"x"`, true},
+	}
+	for _, tt := range tests {
+		var want, got interface{}
+		if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(tt.got), &got); err != nil {
+			t.Fatal(err)
+		}
+		if jsonSubset(want, got) != tt.ok {
+			t.Errorf("jsonSubset(%s, %s) = %v", tt.want, tt.got, !tt.ok)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// redacted replaces sensitive values in diagnostics.
+const redacted = "[REDACTED]"
+
+var (
+	defaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+	defaultRedactedParams  = []string{"access_token", "api_key", "apikey", "client_secret", "key", "password", "secret", "sig", "signature", "token"}
+)
+
+// WithRedaction adds to the headers, query parameters and JSON body fields
+// whose values are redacted from every diagnostic the client produces: debug
+// dumps, curl commands, logs, HAR files and error messages. By default the
+// Authorization, Proxy-Authorization, Cookie, Set-Cookie and X-Api-Key
+// headers and common token query parameters are redacted.
+//
+// Body paths are dotted field names, e.g. "user.password"; arrays along the
+// way are searched element by element. Query parameters also apply to form
+// encoded bodies.
+func WithRedaction(headerNames []string, queryParams []string, bodyJSONPaths []string) Option {
+	return func(c *httpClient) {
+		c.redact.add(headerNames, queryParams, bodyJSONPaths)
+	}
+}
+
+// WithUnsafeNoRedaction turns redaction off, so that diagnostics include
+// credentials and other secrets verbatim. Never use it in production.
+func WithUnsafeNoRedaction() Option {
+	return func(c *httpClient) {
+		c.redact.off = true
+	}
+}
+
+// A redactor holds the redaction rules shared by all diagnostics of a
+// client. It is not modified after the client has been created.
+type redactor struct {
+	off     bool
+	headers map[string]bool
+	params  map[string]bool
+	paths   [][]string
+}
+
+// noRedaction leaves everything as it is.
+var noRedaction = &redactor{off: true}
+
+func newRedactor() *redactor {
+	r := &redactor{headers: make(map[string]bool), params: make(map[string]bool)}
+	r.add(defaultRedactedHeaders, defaultRedactedParams, nil)
+	return r
+}
+
+func (r *redactor) add(headerNames, queryParams, bodyJSONPaths []string) {
+	for _, h := range headerNames {
+		r.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	for _, p := range queryParams {
+		r.params[strings.ToLower(p)] = true
+	}
+	for _, p := range bodyJSONPaths {
+		r.paths = append(r.paths, strings.Split(p, "."))
+	}
+}
+
+func (r *redactor) clone() *redactor {
+	n := &redactor{off: r.off, headers: make(map[string]bool), params: make(map[string]bool)}
+	for k := range r.headers {
+		n.headers[k] = true
+	}
+	for k := range r.params {
+		n.params[k] = true
+	}
+	n.paths = append(n.paths, r.paths...)
+	return n
+}
+
+// urlHeaders are the headers whose values are URLs, which may carry
+// sensitive query parameters. Referer is set by redirects to the URL
+// redirected from.
+var urlHeaders = []string{"Referer", "Location", "Content-Location"}
+
+// header returns a copy of h with the sensitive values replaced.
+func (r *redactor) header(h http.Header) http.Header {
+	if r.off {
+		return h
+	}
+	out := h.Clone()
+	for k := range out {
+		if r.headers[k] {
+			out[k] = []string{redacted}
+		}
+	}
+	for _, k := range urlHeaders {
+		for i, v := range out[k] {
+			out[k][i] = r.urlString(v)
+		}
+	}
+	return out
+}
+
+// url returns a copy of u with the password and sensitive query values
+// replaced. The order of the query parameters is kept.
+func (r *redactor) url(u *url.URL) *url.URL {
+	v := *u
+	if r.off {
+		return &v
+	}
+	if _, ok := v.User.Password(); ok {
+		v.User = url.UserPassword(v.User.Username(), redacted)
+	}
+	v.RawQuery = r.query(v.RawQuery)
+	return &v
+}
+
+// urlString redacts the URL in s, if s parses as one.
+func (r *redactor) urlString(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || r.off {
+		return s
+	}
+	return r.url(u).String()
+}
+
+func (r *redactor) query(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	pairs := strings.Split(raw, "&")
+	for i, pair := range pairs {
+		k := pair
+		if j := strings.IndexByte(pair, '='); j >= 0 {
+			k = pair[:j]
+		}
+		name, err := url.QueryUnescape(k)
+		if err != nil {
+			name = k
+		}
+		if r.params[strings.ToLower(name)] {
+			pairs[i] = k + "=" + redacted
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// body redacts sensitive fields of a JSON or form encoded body. Other
+// bodies, and bodies that fail to parse, are returned unchanged.
+func (r *redactor) body(contentType string, p []byte) []byte {
+	if r.off || len(p) == 0 {
+		return p
+	}
+	ct := strings.ToLower(contentType)
+	switch {
+	case strings.Contains(ct, "x-www-form-urlencoded"):
+		return []byte(r.query(string(p)))
+	case strings.Contains(ct, "json") || ct == "" && json.Valid(p):
+		if len(r.paths) == 0 {
+			return p
+		}
+		var v interface{}
+		d := json.NewDecoder(bytes.NewReader(p))
+		d.UseNumber()
+		if err := d.Decode(&v); err != nil {
+			return p
+		}
+		for _, path := range r.paths {
+			redactPath(v, path)
+		}
+		out, err := json.Marshal(v)
+		if err != nil {
+			return p
+		}
+		return out
+	}
+	return p
+}
+
+// redactsBody reports whether r may change bodies of contentType. A
+// body without a content type may be JSON.
+func (r *redactor) redactsBody(contentType string) bool {
+	if r.off {
+		return false
+	}
+	ct := strings.ToLower(contentType)
+	switch {
+	case strings.Contains(ct, "x-www-form-urlencoded"):
+		return true
+	case strings.Contains(ct, "json") || ct == "":
+		return len(r.paths) > 0
+	}
+	return false
+}
+
+func redactPath(v interface{}, path []string) {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, e := range v {
+			redactPath(e, path)
+		}
+	case map[string]interface{}:
+		child, ok := v[path[0]]
+		if !ok {
+			return
+		}
+		if len(path) == 1 {
+			v[path[0]] = redacted
+			return
+		}
+		redactPath(child, path[1:])
+	}
+}
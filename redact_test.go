@@ -0,0 +1,254 @@
+package httpclient
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordLogger keeps the entries logged to it.
+type recordLogger struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+type logEntry struct {
+	level, msg string
+	fields     map[string]interface{}
+}
+
+func (l *recordLogger) Log(level, msg string, fields map[string]interface{}) {
+	l.mu.Lock()
+	l.entries = append(l.entries, logEntry{level, msg, fields})
+	l.mu.Unlock()
+}
+
+func (l *recordLogger) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var b strings.Builder
+	for _, e := range l.entries {
+		fmt.Fprintf(&b, "%s %s %v\n", e.level, e.msg, e.fields)
+	}
+	return b.String()
+}
+
+func TestRedactionAcrossDiagnostics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret-cookie")
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error":"bad","session":{"token":"secret-body"}}`)
+	}))
+	defer srv.Close()
+
+	var debug, curl syncBuffer
+	logs := &recordLogger{}
+	har := NewHARRecorder()
+	c := New(
+		WithDebug(&debug),
+		WithCurlDebug(&curl),
+		WithLogger(logs),
+		WithLogLevel(LevelDebug),
+		WithHAR(har),
+		WithRedaction([]string{"X-Custom-Secret"}, []string{"sessionid"}, []string{"password", "session.token"}),
+	)
+	err := c.PostJSON(srv.URL+"/login?token=secret-query&sessionid=secret-param&page=2",
+		map[string]string{"user": "a", "password": "secret-password"}, nil,
+		WithRequestHeader("Authorization", "Bearer secret-auth"),
+		WithRequestHeader("X-Custom-Secret", "secret-custom"))
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("got %T, want *Error", err)
+	}
+	harJSON, err := har.Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	channels := map[string]string{
+		"debug":      debug.String(),
+		"curl":       curl.String(),
+		"logs":       logs.String(),
+		"HAR":        string(harJSON),
+		"error":      e.Error() + " " + e.URL,
+		"error body": string(e.Body),
+	}
+	for name, out := range channels {
+		if out == "" {
+			t.Errorf("%s: no output", name)
+			continue
+		}
+		if strings.Contains(out, "secret-") {
+			t.Errorf("%s leaks a secret:\n%s", name, out)
+		}
+	}
+	for _, name := range []string{"debug", "curl", "logs", "HAR", "error"} {
+		if !strings.Contains(channels[name], "page=2") {
+			t.Errorf("%s lost the query parameter page:\n%s", name, channels[name])
+		}
+	}
+	if !strings.Contains(string(e.Body), `"error":"bad"`) {
+		t.Errorf("error body lost its structure: %s", e.Body)
+	}
+}
+
+func TestRedactionOfRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			w.Header().Set("Location", "/new?token=secret-location")
+			w.WriteHeader(http.StatusFound)
+			return
+		}
+		io.WriteString(w, "new")
+	}))
+	defer srv.Close()
+
+	var debug, curl syncBuffer
+	har := NewHARRecorder()
+	c := New(WithDebug(&debug), WithCurlDebug(&curl), WithHAR(har))
+	if _, err := c.Bytes(srv.URL + "/old?token=secret-referer"); err != nil {
+		t.Fatal(err)
+	}
+	harJSON, err := har.Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, out := range map[string]string{"debug": debug.String(), "curl": curl.String(), "HAR": string(harJSON)} {
+		if strings.Contains(out, "secret-") {
+			t.Errorf("%s leaks a secret:\n%s", name, out)
+		}
+	}
+	// The HAR has the Location header of the redirect and the Referer
+	// header of the request that follows it.
+	if !strings.Contains(string(harJSON), "Referer") || !strings.Contains(string(harJSON), "/new?token=") {
+		t.Errorf("HAR lost the redirect:\n%s", harJSON)
+	}
+}
+
+func TestRedactionOfBatchErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	urls := []string{
+		srv.URL + "/a?token=secret-query&page=2",
+		strings.Replace(srv.URL, "http://", "http://ann:secret-pass@", 1) + "/b",
+		closed.URL + "/c?api_key=secret-key",
+	}
+	var files []File
+	err := New().Files(urls, &files)
+	var berr *BatchError
+	if !errors.As(err, &berr) || len(berr.Errors) != 3 {
+		t.Fatalf("got %v, want a *BatchError of 3", err)
+	}
+	out := err.Error()
+	for _, fe := range berr.Errors {
+		out += " " + fe.URL + " " + fe.Error()
+	}
+	if strings.Contains(out, "secret-") {
+		t.Errorf("batch errors leak a secret: %s", out)
+	}
+	if !strings.Contains(berr.Errors[0].URL, "page=2") || !strings.Contains(berr.Errors[1].URL, "ann:") {
+		t.Errorf("got URLs %q and %q", berr.Errors[0].URL, berr.Errors[1].URL)
+	}
+}
+
+func TestDebugRedactsBeforeTruncating(t *testing.T) {
+	var big bytes.Buffer
+	big.WriteString(`{"token":"secret-first","items":[`)
+	for i := 0; big.Len() < debugBodyLimit*2; i++ {
+		fmt.Fprintf(&big, `{"n":%d,"token":"secret-%d"},`, i, i)
+	}
+	big.WriteString(`{"n":-1}]}`)
+	huge := bytes.Repeat([]byte(" "), debugRedactLimit)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(big.Bytes())
+		if r.URL.Path == "/huge" {
+			w.Write(huge)
+		}
+	}))
+	defer srv.Close()
+
+	var out syncBuffer
+	c := New(WithDebug(&out), WithRedaction(nil, nil, []string{"token", "items.token"}))
+	if _, err := c.Bytes(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	dump := out.String()
+	if strings.Contains(dump, "secret-") {
+		t.Error("dump of a truncated body leaks secrets")
+	}
+	if !strings.Contains(dump, "[body truncated after 65536 bytes]") {
+		t.Error("body not truncated")
+	}
+
+	if _, err := c.Bytes(srv.URL + "/huge"); err != nil {
+		t.Fatal(err)
+	}
+	dump = out.String()
+	if strings.Contains(dump, "secret-") {
+		t.Error("dump of a body too large to redact leaks secrets")
+	}
+	if !strings.Contains(dump, "too large to redact") {
+		t.Error("body too large to redact not elided")
+	}
+}
+
+func TestErrorBodyRedactsBeforeTruncating(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"token":"secret","pad":%q}`, strings.Repeat("x", errorBodyLimit))
+	}))
+	defer srv.Close()
+	_, err := New(WithRedaction(nil, nil, []string{"token"})).Bytes(srv.URL)
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("got %v, want *Error", err)
+	}
+	if bytes.Contains(e.Body, []byte("secret")) {
+		t.Errorf("error body leaks the token: %.60s...", e.Body)
+	}
+}
+
+func TestDebugSecretsOnlyInDebug(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	var debug, curl syncBuffer
+	c := New(WithDebug(&debug), WithDebugSecrets(), WithCurlDebug(&curl))
+	if _, err := c.Bytes(srv.URL, WithRequestHeader("Authorization", "Bearer secret-auth")); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(debug.String(), "secret-auth") {
+		t.Error("debug dump redacted with WithDebugSecrets")
+	}
+	if strings.Contains(curl.String(), "secret-auth") {
+		t.Error("WithDebugSecrets turned off redaction of curl commands")
+	}
+}
+
+func TestUnsafeNoRedaction(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	var curl syncBuffer
+	c := New(WithCurlDebug(&curl), WithUnsafeNoRedaction())
+	if _, err := c.Bytes(srv.URL+"?token=secret-query", WithRequestHeader("Authorization", "Bearer secret-auth")); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"secret-query", "secret-auth"} {
+		if !strings.Contains(curl.String(), s) {
+			t.Errorf("curl command lacks %q: %s", s, curl.String())
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package httpclient
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// slowestFiles is the number of files listed in BatchReport.Slowest.
+const slowestFiles = 5
+
+// A BatchReport summarizes a Files or Download batch. Pass one with
+// WithBatchReport to have it filled in when the batch is over.
+type BatchReport struct {
+	Total     int
+	Succeeded int
+	Failed    int
+
+	// Skipped counts the downloads that were not attempted because the
+	// request context was done before they started.
+	Skipped int
+
+	// Bytes is the total size of the downloaded files.
+	Bytes int64
+
+	// Duration is the wall time of the whole batch.
+	Duration time.Duration
+
+	// Slowest lists the slowest downloads, slowest first.
+	Slowest []FileTiming
+
+	// Failures lists the failed downloads in input order.
+	Failures []FileFailure
+}
+
+// FileTiming is the time a single download of a batch took.
+type FileTiming struct {
+	URL      string
+	Bytes    int64
+	Duration time.Duration
+}
+
+// FileFailure is a failed download of a batch.
+type FileFailure struct {
+	URL string
+	Err error
+}
+
+// WithBatchReport fills r with a summary of the Files or Download batch.
+func WithBatchReport(r *BatchReport) RequestOption {
+	return func(o *requestOptions) {
+		o.report = r
+	}
+}
+
+// Throughput returns the aggregate download rate in bytes per second.
+func (r *BatchReport) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / r.Duration.Seconds()
+}
+
+func (r *BatchReport) fill(urls []string, fs []File, errs []error, durations []time.Duration, skipped []bool, elapsed time.Duration) {
+	*r = BatchReport{Total: len(urls), Duration: elapsed}
+	var timings []FileTiming
+	for i, url := range urls {
+		switch {
+		case skipped[i]:
+			r.Skipped++
+		case errs[i] != nil:
+			r.Failed++
+			r.Failures = append(r.Failures, FileFailure{URL: url, Err: errs[i]})
+		default:
+			r.Succeeded++
+			r.Bytes += int64(len(fs[i].Data))
+			timings = append(timings, FileTiming{URL: url, Bytes: int64(len(fs[i].Data)), Duration: durations[i]})
+		}
+	}
+	sort.SliceStable(timings, func(i, j int) bool { return timings[i].Duration > timings[j].Duration })
+	if len(timings) > slowestFiles {
+		timings = timings[:slowestFiles]
+	}
+	r.Slowest = timings
+}
+
+// String renders the report as a human readable table.
+func (r *BatchReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d files: %d succeeded, %d failed, %d skipped\n", r.Total, r.Succeeded, r.Failed, r.Skipped)
+	fmt.Fprintf(&b, "%d bytes in %s (%.0f B/s)\n", r.Bytes, r.Duration, r.Throughput())
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	if len(r.Slowest) > 0 {
+		fmt.Fprintln(w, "\nSLOWEST\tBYTES\tDURATION")
+		for _, t := range r.Slowest {
+			fmt.Fprintf(w, "%s\t%d\t%s\n", t.URL, t.Bytes, t.Duration)
+		}
+	}
+	if len(r.Failures) > 0 {
+		fmt.Fprintln(w, "\nFAILED\tERROR\t")
+		for _, f := range r.Failures {
+			fmt.Fprintf(w, "%s\t%v\t\n", f.URL, f.Err)
+		}
+	}
+	w.Flush()
+	return b.String()
+}
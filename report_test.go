@@ -0,0 +1,141 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// reportServer answers /ms/N after N milliseconds with N bytes, and /fail
+// with a 500.
+func reportServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/ms/"))
+		time.Sleep(time.Duration(n) * time.Millisecond)
+		io.WriteString(w, strings.Repeat("x", n))
+	}))
+}
+
+func TestBatchReport(t *testing.T) {
+	srv := reportServer()
+	defer srv.Close()
+	paths := []string{"/ms/30", "/fail", "/ms/0", "/ms/90", "/ms/1", "/ms/60", "/ms/2", "/fail", "/ms/120", "/ms/3"}
+	var urls []string
+	for _, p := range paths {
+		urls = append(urls, srv.URL+p)
+	}
+
+	var r BatchReport
+	var files []File
+	started := time.Now()
+	err := New().Files(urls, &files, WithBatchReport(&r), WithConcurrency(len(urls)), WithPartialResults())
+	elapsed := time.Since(started)
+	var be *BatchError
+	if !errors.As(err, &be) {
+		t.Fatalf("got %v, want a *BatchError", err)
+	}
+
+	if r.Total != 10 || r.Succeeded != 8 || r.Failed != 2 || r.Skipped != 0 {
+		t.Errorf("got %d total, %d succeeded, %d failed, %d skipped", r.Total, r.Succeeded, r.Failed, r.Skipped)
+	}
+	if r.Bytes != 30+0+90+1+60+2+120+3 {
+		t.Errorf("got %d bytes", r.Bytes)
+	}
+	if r.Duration < 120*time.Millisecond || r.Duration > elapsed {
+		t.Errorf("got duration %v, batch took %v", r.Duration, elapsed)
+	}
+	if want := float64(r.Bytes) / r.Duration.Seconds(); r.Throughput() != want {
+		t.Errorf("got throughput %v, want %v", r.Throughput(), want)
+	}
+
+	want := []string{"/ms/120", "/ms/90", "/ms/60", "/ms/30"}
+	if len(r.Slowest) != slowestFiles {
+		t.Fatalf("got %d slowest files, want %d", len(r.Slowest), slowestFiles)
+	}
+	for i, p := range want {
+		s := r.Slowest[i]
+		if s.URL != srv.URL+p {
+			t.Errorf("slowest %d: got %s, want %s", i, s.URL, p)
+		}
+		n, _ := strconv.Atoi(strings.TrimPrefix(p, "/ms/"))
+		if s.Bytes != int64(n) || s.Duration < time.Duration(n)*time.Millisecond {
+			t.Errorf("%s: got %d bytes in %v", p, s.Bytes, s.Duration)
+		}
+	}
+	for i := 1; i < len(r.Slowest); i++ {
+		if r.Slowest[i].Duration > r.Slowest[i-1].Duration {
+			t.Errorf("slowest files not ordered: %v", r.Slowest)
+		}
+	}
+
+	if len(r.Failures) != 2 || r.Failures[0].URL != urls[1] || r.Failures[1].URL != urls[7] {
+		t.Fatalf("got failures %v", r.Failures)
+	}
+	for _, f := range r.Failures {
+		var e *Error
+		if !errors.As(f.Err, &e) || e.StatusCode != 500 {
+			t.Errorf("%s: got %v", f.URL, f.Err)
+		}
+	}
+
+	s := r.String()
+	for _, want := range []string{
+		"10 files: 8 succeeded, 2 failed, 0 skipped\n",
+		"306 bytes in ",
+		"SLOWEST", srv.URL + "/ms/120  120",
+		"FAILED", srv.URL + "/fail",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("report lacks %q:\n%s", want, s)
+		}
+	}
+	if strings.Index(s, "/ms/120") > strings.Index(s, "/ms/90") {
+		t.Errorf("report not ordered:\n%s", s)
+	}
+}
+
+func TestBatchReportSkipped(t *testing.T) {
+	srv := reportServer()
+	defer srv.Close()
+	var urls []string
+	for i := 0; i < 5; i++ {
+		urls = append(urls, srv.URL+"/ms/1")
+	}
+
+	// Downloads run one at a time, in order; the context is canceled as
+	// the third starts, so it fails and the last two are skipped.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var r BatchReport
+	var files []File
+	err := New().Files(urls, &files, WithContext(ctx), WithConcurrency(1), WithBatchReport(&r),
+		WithTeeFactory(func(i int, url string) io.Writer {
+			if i == 2 {
+				cancel()
+			}
+			return ioutil.Discard
+		}))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if r.Total != 5 || r.Succeeded != 2 || r.Failed != 1 || r.Skipped != 2 {
+		t.Errorf("got %d total, %d succeeded, %d failed, %d skipped", r.Total, r.Succeeded, r.Failed, r.Skipped)
+	}
+	if r.Bytes != 2 || len(r.Slowest) != 2 || len(r.Failures) != 1 || r.Failures[0].URL != urls[2] {
+		t.Errorf("got %+v", r)
+	}
+	if !strings.Contains(r.String(), "5 files: 2 succeeded, 1 failed, 2 skipped") {
+		t.Errorf("got report:\n%s", r.String())
+	}
+}
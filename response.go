@@ -0,0 +1,109 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+)
+
+// Response is a response whose body has been read in full.
+type Response struct {
+	StatusCode    int
+	Status        string
+	Header        http.Header
+	ContentLength int64
+	// URL is the URL of the request, after any redirects.
+	URL  string
+	Body []byte
+	// Timings is set when the client was created WithTimings.
+	Timings *Timings
+
+	// Spill holds the body instead of Body when it was larger than the
+	// threshold of WithSpillToDisk. It is closed by Close.
+	Spill io.ReadSeekCloser
+}
+
+// readResponse reads the body of resp, closing it, into a Response.
+func (c *httpClient) readResponse(resp *http.Response, o *requestOptions) (*Response, error) {
+	defer resp.Body.Close()
+	body, spill, err := c.readSpill(resp, o)
+	if err != nil {
+		return nil, err
+	}
+	r := responseOf(resp)
+	r.Body, r.Spill = body, spill
+	return r, nil
+}
+
+// responseOf returns a Response with the status and header of resp, and no
+// body.
+func responseOf(resp *http.Response) *Response {
+	return &Response{
+		StatusCode:    resp.StatusCode,
+		Status:        resp.Status,
+		Header:        resp.Header,
+		ContentLength: resp.ContentLength,
+		URL:           resp.Request.URL.String(),
+		Timings:       TimingsOf(resp),
+	}
+}
+
+// Close removes the file of a body kept on disk. It does nothing for a
+// body held in memory.
+func (r *Response) Close() error {
+	if r.Spill == nil {
+		return nil
+	}
+	return r.Spill.Close()
+}
+
+// reader returns a reader of the body from its start.
+func (r *Response) reader() (io.Reader, error) {
+	if r.Spill == nil {
+		return bytes.NewReader(r.Body), nil
+	}
+	if _, err := r.Spill.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return r.Spill, nil
+}
+
+// String returns the body as a string, or the empty string if it is kept
+// on disk and cannot be read.
+func (r *Response) String() string {
+	if r.Spill == nil {
+		return string(r.Body)
+	}
+	body, err := r.reader()
+	if err != nil {
+		return ""
+	}
+	p, _ := io.ReadAll(body)
+	return string(p)
+}
+
+// JSON unmarshals the body as JSON into v.
+func (r *Response) JSON(v interface{}) error {
+	if r.Spill == nil {
+		return json.Unmarshal(r.Body, v)
+	}
+	body, err := r.reader()
+	if err != nil {
+		return err
+	}
+	return json.NewDecoder(body).Decode(v)
+}
+
+// XML unmarshals the body as XML into v.
+func (r *Response) XML(v interface{}) error {
+	if r.Spill == nil {
+		return xml.Unmarshal(r.Body, v)
+	}
+	body, err := r.reader()
+	if err != nil {
+		return err
+	}
+	return xml.NewDecoder(body).Decode(v)
+}
@@ -0,0 +1,126 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// responseServer answers /v with a value in JSON or XML, following the
+// extension, /moved with a redirect to it, /bad with a 400 and /garbled
+// with a body that is neither.
+func responseServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "42")
+		switch r.URL.Path {
+		case "/v.json":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"n":7}`)
+		case "/v.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			io.WriteString(w, `<v><n>7</n></v>`)
+		case "/moved.json", "/moved.xml":
+			http.Redirect(w, r, "/v"+r.URL.Path[len("/moved"):], http.StatusFound)
+		case "/bad.json", "/bad.xml":
+			http.Error(w, "bad request", http.StatusBadRequest)
+		default:
+			io.WriteString(w, "<<garbled")
+		}
+	}))
+}
+
+func TestDecodeResponse(t *testing.T) {
+	srv := responseServer()
+	defer srv.Close()
+	c := New(WithTimings())
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	type value struct {
+		N int `json:"n" xml:"n"`
+	}
+	for _, d := range []struct {
+		ext    string
+		decode func(url string, v interface{}) (*Response, error)
+	}{
+		{".json", func(url string, v interface{}) (*Response, error) { return c.JSONResponse(url, v) }},
+		{".xml", func(url string, v interface{}) (*Response, error) { return c.XMLResponse(url, v) }},
+	} {
+		var v value
+		r, err := d.decode(srv.URL+"/moved"+d.ext, &v)
+		if err != nil || v.N != 7 {
+			t.Fatalf("%s: got %+v, %v", d.ext, v, err)
+		}
+		if r.StatusCode != 200 || r.Status != "200 OK" || r.Header.Get("X-Request-Id") != "42" || r.URL != srv.URL+"/v"+d.ext {
+			t.Errorf("%s: got %d %q, header %v, URL %s", d.ext, r.StatusCode, r.Status, r.Header, r.URL)
+		}
+		if r.Body != nil || r.Timings == nil || r.Timings.Total() <= 0 {
+			t.Errorf("%s: got body %q, timings %+v, want no body and the timings", d.ext, r.Body, r.Timings)
+		}
+
+		// A rejected status returns the Response with the *Error.
+		r, err = d.decode(srv.URL+"/bad"+d.ext, &v)
+		var herr *Error
+		if !errors.As(err, &herr) || r == nil || r.StatusCode != http.StatusBadRequest || herr.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s bad: got %+v, %v", d.ext, r, err)
+		}
+
+		// So does a body that cannot be decoded.
+		r, err = d.decode(srv.URL+"/garbled", &v)
+		if !errors.As(err, &herr) || r == nil || r.StatusCode != 200 {
+			t.Errorf("%s garbled: got %+v, %v", d.ext, r, err)
+		}
+
+		// Without a response, there is none to return.
+		if r, err = d.decode(closed.URL, &v); err == nil || r != nil {
+			t.Errorf("%s closed: got %+v, %v", d.ext, r, err)
+		}
+	}
+}
+
+func TestFileMetadata(t *testing.T) {
+	modified := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/typed":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		case "/sniffed":
+		case "/untyped":
+			w.Header()["Content-Type"] = nil
+		case "/bad-date":
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Last-Modified", "yesterday")
+		}
+		io.WriteString(w, "<html>")
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path         string
+		contentType  string
+		lastModified time.Time
+	}{
+		{"/typed", "application/pdf", modified},
+		{"/sniffed", "text/html; charset=utf-8", time.Time{}},
+		{"/untyped", "", time.Time{}},
+		{"/bad-date", "text/csv", time.Time{}},
+	}
+	var urls []string
+	for _, tt := range tests {
+		urls = append(urls, srv.URL+tt.path)
+	}
+	var files []File
+	if err := Files(urls, &files); err != nil {
+		t.Fatal(err)
+	}
+	for i, tt := range tests {
+		f := files[i]
+		if f.ContentType != tt.contentType || !f.LastModified.Equal(tt.lastModified) || string(f.Data) != "<html>" {
+			t.Errorf("%s: got %q, modified %v, want %q, %v", tt.path, f.ContentType, f.LastModified, tt.contentType, tt.lastModified)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"syscall"
+	"time"
+)
+
+// A RetryPolicy says how the client retries requests that failed with a
+// transient error: a network error, or a 429 or 5xx response. Other 4xx
+// responses are never retried.
+//
+// Only requests with an idempotent method (GET, HEAD, OPTIONS, TRACE, PUT
+// and DELETE) or an Idempotency-Key header are retried, and only if their
+// body, if any, can be sent again.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a request is sent at most,
+	// including the first. Below 2, requests are not retried.
+	MaxAttempts int
+
+	// Backoff is the wait before the first retry. It doubles with every
+	// retry after that.
+	Backoff time.Duration
+
+	// MaxBackoff caps the wait between two attempts, including the one a
+	// Retry-After header asks for. Zero means an hour.
+	MaxBackoff time.Duration
+
+	// Jitter waits a random duration between half and all of the backoff
+	// instead, so that clients failing together do not retry together.
+	Jitter bool
+}
+
+// WithRetry retries requests that failed with a transient error up to
+// maxAttempts times in all, waiting backoff before the first retry and
+// twice as long before each next one, or as long as the Retry-After header
+// of the response asks.
+func WithRetry(maxAttempts int, backoff time.Duration) Option {
+	return WithRetryPolicy(RetryPolicy{MaxAttempts: maxAttempts, Backoff: backoff})
+}
+
+// WithRetryPolicy retries requests that failed with a transient error as p
+// says.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *httpClient) {
+		c.retry = p
+	}
+}
+
+// defaultMaxBackoff is the cap of RetryPolicy.MaxBackoff when it is zero.
+const defaultMaxBackoff = time.Hour
+
+// attemptsKey is the context key of the attempt counter of a request.
+type attemptsKey struct{}
+
+// attempts returns the number of times req was sent.
+func attempts(req *http.Request) int {
+	if n, ok := req.Context().Value(attemptsKey{}).(*int); ok {
+		return *n
+	}
+	return 1
+}
+
+// wait reports whether the attempt of req that got resp or err is to be
+// retried, and after how long.
+func (p *RetryPolicy) wait(req *http.Request, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts || req.Context().Err() != nil || !replayable(req) {
+		return 0, false
+	}
+	max := p.MaxBackoff
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+	if err != nil {
+		if !transient(err) {
+			return 0, false
+		}
+	} else {
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return 0, false
+		}
+		if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			if after > max {
+				after = max
+			}
+			return after, true
+		}
+	}
+	shift := uint(attempt - 1)
+	d := p.Backoff << shift
+	if shift >= 63 || d>>shift != p.Backoff || d > max {
+		d = max
+	}
+	if p.Jitter && d > 1 {
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+	return d, true
+}
+
+// replayable reports whether req may be sent again.
+func replayable(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+	default:
+		if req.Header.Get("Idempotency-Key") == "" {
+			return false
+		}
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// transient reports whether err, returned by the transport, is a network
+// error worth retrying.
+func transient(err error) bool {
+	if ue, ok := err.(*neturl.Error); ok {
+		err = ue.Err
+	}
+	var ne net.Error
+	return errors.As(err, &ne) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// retried reports the attempt x, which is about to be retried after wait,
+// and closes its response, if any.
+func (c *httpClient) retried(x *exchange, resp *http.Response, err error, wait time.Duration) {
+	fields := map[string]interface{}{
+		"method":  x.req.Method,
+		"url":     c.redact.url(x.req.URL).String(),
+		"attempt": x.attempt,
+		"wait":    wait,
+	}
+	if err != nil {
+		c.failed(x, err)
+		fields["error"] = err.Error()
+	} else {
+		x.resp = resp
+		c.hooks.received(resp, time.Since(x.start))
+		n, _ := io.CopyN(ioutil.Discard, resp.Body, 64<<10)
+		resp.Body.Close()
+		c.completed(x, n)
+		fields["status"] = resp.StatusCode
+	}
+	c.stats.retried()
+	c.metrics.IncRetry(x.host())
+	if c.logs(LevelWarn) {
+		c.log(LevelWarn, "request retry", fields)
+	}
+}
@@ -0,0 +1,93 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryWait(t *testing.T) {
+	get, _ := http.NewRequest("GET", "http://example.com/", nil)
+	post, _ := http.NewRequest("POST", "http://example.com/", strings.NewReader("x"))
+	status := func(code int, retryAfter string) *http.Response {
+		resp := &http.Response{StatusCode: code, Header: http.Header{}}
+		if retryAfter != "" {
+			resp.Header.Set("Retry-After", retryAfter)
+		}
+		return resp
+	}
+	netErr := errors.New("boom")
+	tests := []struct {
+		name    string
+		p       RetryPolicy
+		req     *http.Request
+		attempt int
+		resp    *http.Response
+		err     error
+		want    time.Duration
+		retry   bool
+	}{
+		{"first retry", RetryPolicy{MaxAttempts: 5, Backoff: time.Second}, get, 1, status(503, ""), nil, time.Second, true},
+		{"doubles", RetryPolicy{MaxAttempts: 5, Backoff: time.Second}, get, 3, status(500, ""), nil, 4 * time.Second, true},
+		{"429", RetryPolicy{MaxAttempts: 5, Backoff: time.Second}, get, 1, status(429, ""), nil, time.Second, true},
+		{"capped", RetryPolicy{MaxAttempts: 10, Backoff: time.Second, MaxBackoff: 3 * time.Second}, get, 5, status(503, ""), nil, 3 * time.Second, true},
+		{"overflow", RetryPolicy{MaxAttempts: 100, Backoff: time.Second}, get, 70, status(503, ""), nil, defaultMaxBackoff, true},
+		{"overflow past 63", RetryPolicy{MaxAttempts: 100, Backoff: time.Nanosecond}, get, 65, status(503, ""), nil, defaultMaxBackoff, true},
+		{"Retry-After", RetryPolicy{MaxAttempts: 5, Backoff: time.Second}, get, 1, status(503, "7"), nil, 7 * time.Second, true},
+		{"Retry-After capped", RetryPolicy{MaxAttempts: 5, Backoff: time.Second, MaxBackoff: 2 * time.Second}, get, 1, status(503, "7"), nil, 2 * time.Second, true},
+		{"last attempt", RetryPolicy{MaxAttempts: 2, Backoff: time.Second}, get, 2, status(503, ""), nil, 0, false},
+		{"404", RetryPolicy{MaxAttempts: 5, Backoff: time.Second}, get, 1, status(404, ""), nil, 0, false},
+		{"POST", RetryPolicy{MaxAttempts: 5, Backoff: time.Second}, post, 1, status(503, ""), nil, 0, false},
+		{"not transient", RetryPolicy{MaxAttempts: 5, Backoff: time.Second}, get, 1, nil, netErr, 0, false},
+		{"EOF", RetryPolicy{MaxAttempts: 5, Backoff: time.Second}, get, 1, nil, io.ErrUnexpectedEOF, time.Second, true},
+	}
+	for _, tt := range tests {
+		d, retry := tt.p.wait(tt.req, tt.attempt, tt.resp, tt.err)
+		if d != tt.want || retry != tt.retry {
+			t.Errorf("%s: got %v, %v, want %v, %v", tt.name, d, retry, tt.want, tt.retry)
+		}
+	}
+}
+
+func TestRetryJitter(t *testing.T) {
+	get, _ := http.NewRequest("GET", "http://example.com/", nil)
+	p := RetryPolicy{MaxAttempts: 5, Backoff: time.Second, Jitter: true}
+	for i := 0; i < 100; i++ {
+		d, _ := p.wait(get, 2, &http.Response{StatusCode: 503}, nil)
+		if d < time.Second || d > 2*time.Second {
+			t.Fatalf("got %v, want between 1s and 2s", d)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var n int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&n, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	got, err := New(WithRetry(3, time.Millisecond)).String(srv.URL)
+	if err != nil || got != "ok" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	if n != 3 {
+		t.Errorf("server got %d requests, want 3", n)
+	}
+
+	atomic.StoreInt32(&n, 0)
+	_, err = New(WithRetry(2, time.Millisecond)).String(srv.URL)
+	var e *Error
+	if !errors.As(err, &e) || e.StatusCode != http.StatusServiceUnavailable || e.Attempts != 2 {
+		t.Errorf("got %#v, want a 503 error after 2 attempts", err)
+	}
+}
@@ -0,0 +1,324 @@
+package httpclient
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDisallowedByRobots is returned for a request to a URL that the
+// robots.txt of its host disallows, see WithRobotsPolicy.
+var ErrDisallowedByRobots = errors.New("httpclient: disallowed by robots.txt")
+
+const (
+	// defaultRobotsTTL is how long a robots.txt is cached.
+	defaultRobotsTTL = 24 * time.Hour
+
+	// robotsRetry is how long the failure to fetch a robots.txt is cached.
+	robotsRetry = time.Minute
+
+	// maxRobotsSize is how much of a robots.txt is read.
+	maxRobotsSize = 500 << 10
+
+	// robotsTimeout bounds the fetch of a robots.txt.
+	robotsTimeout = 30 * time.Second
+)
+
+// WithRobotsPolicy makes the client follow the robots.txt of every host it
+// requests, as the crawler userAgent (RFC 9309). Before the first request
+// to a host, its /robots.txt is fetched and cached for a day. A request
+// the rules of userAgent disallow fails with ErrDisallowedByRobots, and
+// requests to a host that sets a Crawl-delay are spaced by that delay.
+// Requests without a User-Agent header are sent with userAgent.
+//
+// A robots.txt answered with a 4xx status allows everything. One that
+// cannot be fetched, or is answered with a 5xx status, disallows
+// everything, unless WithRobotsAllowOnError is given; either way it is
+// tried again after a minute.
+func WithRobotsPolicy(userAgent string) Option {
+	return func(c *httpClient) {
+		c.robots = &robotsPolicy{agent: userAgent, ttl: defaultRobotsTTL, hosts: make(map[string]*robotsEntry)}
+	}
+}
+
+// WithRobotsTTL sets how long WithRobotsPolicy caches a robots.txt. It
+// must come after WithRobotsPolicy.
+func WithRobotsTTL(d time.Duration) Option {
+	return func(c *httpClient) {
+		if c.robots != nil {
+			c.robots.ttl = d
+		}
+	}
+}
+
+// WithRobotsAllowOnError makes WithRobotsPolicy allow every request to a
+// host whose robots.txt cannot be fetched or is answered with a 5xx
+// status. It must come after WithRobotsPolicy.
+func WithRobotsAllowOnError() Option {
+	return func(c *httpClient) {
+		if c.robots != nil {
+			c.robots.allowOnError = true
+		}
+	}
+}
+
+// robotsPolicy holds the settings of WithRobotsPolicy and the robots.txt
+// files fetched so far, which the clones of a client share.
+type robotsPolicy struct {
+	agent        string
+	ttl          time.Duration
+	allowOnError bool
+
+	mu    sync.Mutex
+	hosts map[string]*robotsEntry
+}
+
+// robotsTransport enforces a robotsPolicy on the requests sent with next.
+type robotsTransport struct {
+	next http.RoundTripper
+	*robotsPolicy
+}
+
+// robotsEntry is the robots.txt of a host, fetched once for the first
+// request to it while the requests wait on ready.
+type robotsEntry struct {
+	ready   chan struct{}
+	rules   *robotsRules
+	expires time.Time
+
+	// next is the time the next request may be sent, by the Crawl-delay.
+	next time.Time
+}
+
+func (t *robotsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.agent)
+	}
+	if req.URL.Path == "/robots.txt" {
+		return t.next.RoundTrip(req)
+	}
+	e, err := t.entry(req.Context(), req.URL.Scheme, req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	if !e.rules.allowed(req.URL.RequestURI()) {
+		return nil, fmt.Errorf("%w: %s", ErrDisallowedByRobots, req.URL.Path)
+	}
+	if d := e.rules.delay; d > 0 {
+		t.mu.Lock()
+		wait := time.Until(e.next)
+		if wait < 0 {
+			wait = 0
+		}
+		e.next = time.Now().Add(wait + d)
+		t.mu.Unlock()
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+	return t.next.RoundTrip(req)
+}
+
+// entry returns the robots.txt of host, fetching it if needed.
+func (t *robotsTransport) entry(ctx context.Context, scheme, host string) (*robotsEntry, error) {
+	key := scheme + "://" + host
+	t.mu.Lock()
+	e, ok := t.hosts[key]
+	if ok {
+		select {
+		case <-e.ready:
+			ok = time.Now().Before(e.expires)
+		default:
+		}
+	}
+	if !ok {
+		// The fetch outlives the request that starts it, so that its
+		// cancellation is not cached as a failure for every request, and
+		// is not rendered as that request.
+		e = &robotsEntry{ready: make(chan struct{})}
+		t.hosts[key] = e
+		go func() {
+			ctx := context.WithValue(context.WithoutCancel(ctx), wireKey{}, nil)
+			ctx, cancel := context.WithTimeout(ctx, robotsTimeout)
+			defer cancel()
+			e.rules, e.expires = t.fetch(ctx, key)
+			close(e.ready)
+		}()
+	}
+	t.mu.Unlock()
+	select {
+	case <-e.ready:
+		return e, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetch gets and parses the robots.txt at base.
+func (t *robotsTransport) fetch(ctx context.Context, base string) (*robotsRules, time.Time) {
+	failed := &robotsRules{disallowAll: !t.allowOnError}
+	req, err := http.NewRequestWithContext(ctx, "GET", base+"/robots.txt", nil)
+	if err != nil {
+		return failed, time.Now().Add(robotsRetry)
+	}
+	req.Header.Set("User-Agent", t.agent)
+	resp, err := (&http.Client{Transport: t.next}).Do(req)
+	if err != nil {
+		return failed, time.Now().Add(robotsRetry)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return failed, time.Now().Add(robotsRetry)
+	case resp.StatusCode >= 400:
+		return &robotsRules{}, time.Now().Add(t.ttl)
+	case resp.StatusCode >= 300:
+		// More redirects than the client follows.
+		return &robotsRules{}, time.Now().Add(t.ttl)
+	}
+	return parseRobots(io.LimitReader(resp.Body, maxRobotsSize), t.agent), time.Now().Add(t.ttl)
+}
+
+// robotsRules are the rules of the group of a robots.txt that applies to
+// a user agent.
+type robotsRules struct {
+	disallowAll bool
+	rules       []robotsRule
+	delay       time.Duration
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// parseRobots parses a robots.txt and returns the rules for agent: those
+// of the groups whose User-agent is the longest that agent matches, or of
+// the "*" groups if none does.
+func parseRobots(r io.Reader, agent string) *robotsRules {
+	agent = strings.ToLower(agent)
+	if i := strings.IndexAny(agent, "/ "); i >= 0 {
+		agent = agent[:i]
+	}
+
+	type group struct {
+		agents []string
+		rules  robotsRules
+	}
+	var groups []*group
+	var g *group
+	inRules := false
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+		switch key {
+		case "user-agent":
+			if g == nil || inRules {
+				g = &group{}
+				groups = append(groups, g)
+				inRules = false
+			}
+			g.agents = append(g.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if g == nil {
+				continue
+			}
+			inRules = true
+			if value != "" {
+				g.rules.rules = append(g.rules.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if g == nil {
+				continue
+			}
+			inRules = true
+			if d, err := strconv.ParseFloat(value, 64); err == nil && d > 0 {
+				g.rules.delay = time.Duration(d * float64(time.Second))
+			}
+		}
+	}
+
+	rules := &robotsRules{}
+	best := -1
+	for _, g := range groups {
+		for _, a := range g.agents {
+			n := -1
+			switch {
+			case a == "*":
+				n = 0
+			case a != "" && strings.HasPrefix(agent, a):
+				n = len(a)
+			}
+			if n < 0 || n < best {
+				continue
+			}
+			if n > best {
+				best, rules = n, &robotsRules{}
+			}
+			rules.rules = append(rules.rules, g.rules.rules...)
+			if g.rules.delay > rules.delay {
+				rules.delay = g.rules.delay
+			}
+			break
+		}
+	}
+	return rules
+}
+
+// allowed reports whether the rules allow path, which includes the query.
+// The longest matching pattern wins, and Allow wins a tie.
+func (r *robotsRules) allowed(path string) bool {
+	if r.disallowAll {
+		return false
+	}
+	allow, longest := true, -1
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > longest || n == longest && rule.allow {
+			allow, longest = rule.allow, n
+		}
+	}
+	return allow
+}
+
+// robotsMatch reports whether path matches pattern, in which "*" matches
+// any sequence of characters and a final "$" the end of the path.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	path = path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(path, part)
+		}
+		j := strings.Index(path, part)
+		if j < 0 {
+			return false
+		}
+		path = path[j+len(part):]
+	}
+	return !anchored || path == ""
+}
@@ -0,0 +1,314 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const robotsFixture = `# robots.txt for a test site
+User-agent: *
+Disallow: /private/
+Disallow: /tmp
+Allow: /private/public-page.html
+Disallow: /*.pdf$
+Disallow: /search?*q=
+
+User-agent: politebot
+User-agent: other-bot
+Disallow: /no-bots/
+Allow: /no-bots/except/
+Disallow: /shop
+Allow: /shop
+Crawl-delay: 0.2
+
+User-agent: politebot-images
+Disallow: /
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		agent, path string
+		allowed     bool
+	}{
+		// The catch-all group.
+		{"somebot/1.0", "/", true},
+		{"somebot/1.0", "/private/", false},
+		{"somebot/1.0", "/private/secret.html", false},
+		{"somebot/1.0", "/private/public-page.html", true},
+		{"somebot/1.0", "/private", true},
+		{"somebot/1.0", "/tmp", false},
+		{"somebot/1.0", "/tmp.html", false},
+		{"somebot/1.0", "/tmpfile/x", false},
+		{"somebot/1.0", "/docs/a.pdf", false},
+		{"somebot/1.0", "/docs/a.pdf?download=1", true},
+		{"somebot/1.0", "/search?lang=en&q=go", false},
+		{"somebot/1.0", "/search?lang=en", true},
+
+		// politebot has its own group, and none of the catch-all rules.
+		{"politebot/2.1 (+https://bot.test)", "/private/secret.html", true},
+		{"PoliteBot", "/no-bots/page", false},
+		{"politebot", "/no-bots/except/page", true},
+		{"politebot", "/no-bots", true},
+		{"politebot", "/shop/item", true}, // Allow wins a tie
+		{"other-bot", "/no-bots/page", false},
+
+		// The longest matching user agent wins.
+		{"politebot-images", "/anything", false},
+		{"politebot-imagesearch", "/anything", false},
+		{"polite", "/private/x", false},
+	}
+	for _, tt := range tests {
+		rules := parseRobots(strings.NewReader(robotsFixture), tt.agent)
+		if got := rules.allowed(tt.path); got != tt.allowed {
+			t.Errorf("%s %s: got allowed %v, want %v", tt.agent, tt.path, got, tt.allowed)
+		}
+	}
+	if d := parseRobots(strings.NewReader(robotsFixture), "politebot").delay; d != 200*time.Millisecond {
+		t.Errorf("got crawl delay %v, want 200ms", d)
+	}
+	if d := parseRobots(strings.NewReader(robotsFixture), "somebot").delay; d != 0 {
+		t.Errorf("got crawl delay %v, want none", d)
+	}
+}
+
+func TestRobotsMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		match         bool
+	}{
+		{"/", "/anything", true},
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish", false},
+		{"/fish/", "/fish", false},
+		{"/*.php", "/index.php", true},
+		{"/*.php", "/dir/index.php?x=1", true},
+		{"/*.php$", "/index.php", true},
+		{"/*.php$", "/index.php?x=1", false},
+		{"/fish*.php", "/fishheads/catfish.php", true},
+		{"/fish*.php", "/fish.html", false},
+		{"/a$", "/a", true},
+		{"/a$", "/ab", false},
+		{"/*a*b$", "/xaxb", true},
+		{"/*a*b$", "/xaxbc", false},
+	}
+	for _, tt := range tests {
+		if got := robotsMatch(tt.pattern, tt.path); got != tt.match {
+			t.Errorf("%s ~ %s: got %v, want %v", tt.pattern, tt.path, got, tt.match)
+		}
+	}
+}
+
+// robotsServer serves robots.txt with the status and body of the fields,
+// and 200 for every other path. It keeps the requests it gets.
+type robotsServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	body     string
+	requests []string
+	agents   []string
+	times    []time.Time
+}
+
+func newRobotsServer(status int, body string) *robotsServer {
+	s := &robotsServer{status: status, body: body}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.URL.RequestURI())
+		s.agents = append(s.agents, r.Header.Get("User-Agent"))
+		s.times = append(s.times, time.Now())
+		status, body := s.status, s.body
+		s.mu.Unlock()
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+			return
+		}
+		w.Write([]byte("page " + r.URL.Path))
+	}))
+	return s
+}
+
+func (s *robotsServer) log() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func TestRobotsPolicy(t *testing.T) {
+	srv := newRobotsServer(200, robotsFixture)
+	defer srv.Close()
+	c := New(WithBaseURL(srv.URL), WithRobotsPolicy("somebot/1.0"))
+
+	if s, err := c.String("/index.html"); err != nil || s != "page /index.html" {
+		t.Fatalf("got %q, %v", s, err)
+	}
+	_, err := c.String("/private/secret.html")
+	if !errors.Is(err, ErrDisallowedByRobots) || !strings.Contains(err.Error(), "/private/secret.html") {
+		t.Errorf("got %v, want ErrDisallowedByRobots", err)
+	}
+	if _, err := c.String("/private/public-page.html"); err != nil {
+		t.Error(err)
+	}
+	var files []File
+	err = c.Files([]string{srv.URL + "/docs/b.pdf"}, &files)
+	if !errors.Is(err, ErrDisallowedByRobots) {
+		t.Errorf("Files: got %v, want ErrDisallowedByRobots", err)
+	}
+	// robots.txt is fetched once, and disallowed pages never.
+	want := "/robots.txt /index.html /private/public-page.html"
+	if got := strings.Join(srv.log(), " "); got != want {
+		t.Errorf("got requests %s, want %s", got, want)
+	}
+	for _, a := range srv.agents {
+		if a != "somebot/1.0" {
+			t.Errorf("got User-Agent %q", a)
+		}
+	}
+	// A request with its own User-Agent keeps it.
+	if _, err := c.String("/ua", WithRequestHeader("User-Agent", "mine")); err != nil || srv.agents[len(srv.agents)-1] != "mine" {
+		t.Errorf("got %v, User-Agent %q", err, srv.agents[len(srv.agents)-1])
+	}
+}
+
+func TestRobotsPolicyTTL(t *testing.T) {
+	srv := newRobotsServer(200, "User-agent: *\nDisallow: /old\n")
+	defer srv.Close()
+	c := New(WithBaseURL(srv.URL), WithRobotsPolicy("bot"), WithRobotsTTL(100*time.Millisecond))
+
+	if _, err := c.String("/old"); !errors.Is(err, ErrDisallowedByRobots) {
+		t.Fatalf("got %v", err)
+	}
+	srv.mu.Lock()
+	srv.body = "User-agent: *\nDisallow: /new\n"
+	srv.mu.Unlock()
+	if _, err := c.String("/old"); !errors.Is(err, ErrDisallowedByRobots) {
+		t.Errorf("cached: got %v", err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if _, err := c.String("/old"); err != nil {
+		t.Errorf("expired: got %v", err)
+	}
+	if _, err := c.String("/new"); !errors.Is(err, ErrDisallowedByRobots) {
+		t.Errorf("expired: got %v", err)
+	}
+	if got := strings.Join(srv.log(), " "); got != "/robots.txt /robots.txt /old" {
+		t.Errorf("got requests %s", got)
+	}
+}
+
+func TestRobotsPolicyFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		opts    []Option
+		allowed bool
+	}{
+		{"404", 404, nil, true},
+		{"403", 403, nil, true},
+		{"500", 500, nil, false},
+		{"503", 503, nil, false},
+		{"500 allowed", 500, []Option{WithRobotsAllowOnError()}, true},
+	}
+	for _, tt := range tests {
+		srv := newRobotsServer(tt.status, "User-agent: *\nDisallow: /\n")
+		c := New(append([]Option{WithRobotsPolicy("bot")}, tt.opts...)...)
+		_, err := c.String(srv.URL + "/page")
+		if tt.allowed && err != nil || !tt.allowed && !errors.Is(err, ErrDisallowedByRobots) {
+			t.Errorf("%s: got %v", tt.name, err)
+		}
+		srv.Close()
+	}
+
+	// A host that cannot be reached is disallowed, without a second try.
+	srv := newRobotsServer(200, "")
+	srv.Close()
+	c := New(WithRobotsPolicy("bot"))
+	for i := 0; i < 2; i++ {
+		if _, err := c.String(srv.URL + "/page"); !errors.Is(err, ErrDisallowedByRobots) {
+			t.Errorf("unreachable: got %v", err)
+		}
+	}
+}
+
+func TestRobotsPolicyCancelled(t *testing.T) {
+	release := make(chan struct{})
+	srv := newRobotsServer(200, "User-agent: *\nDisallow: /private/\n")
+	defer srv.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			<-release
+		}
+		srv.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer slow.Close()
+	c := New(WithBaseURL(slow.URL), WithRobotsPolicy("bot"))
+
+	// The first request gives up while robots.txt is being fetched; that
+	// is not cached as a failure for the requests after it.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := c.StringContext(ctx, "/page")
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrDisallowedByRobots) {
+		t.Errorf("cancelled: got %v, want a deadline error", err)
+	}
+	close(release)
+	if s, err := c.String("/page"); err != nil || s != "page /page" {
+		t.Errorf("second: got %q, %v", s, err)
+	}
+	if _, err := c.String("/private/x"); !errors.Is(err, ErrDisallowedByRobots) {
+		t.Errorf("private: got %v", err)
+	}
+	if got := strings.Join(srv.log(), " "); got != "/robots.txt /page" {
+		t.Errorf("got requests %s", got)
+	}
+}
+
+func TestRobotsCrawlDelay(t *testing.T) {
+	srv := newRobotsServer(200, robotsFixture)
+	defer srv.Close()
+	c := New(WithBaseURL(srv.URL), WithRobotsPolicy("politebot/2.1"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.String("/page"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	srv.mu.Lock()
+	requests, times := srv.requests, srv.times
+	srv.mu.Unlock()
+	if len(times) != 4 || requests[0] != "/robots.txt" {
+		t.Fatalf("got requests %v", requests)
+	}
+	// The pages are spaced by the delay; robots.txt itself is not.
+	for i := 2; i < 4; i++ {
+		if d := times[i].Sub(times[i-1]); d < 180*time.Millisecond {
+			t.Errorf("request %d came %v after the previous one, want 200ms", i, d)
+		}
+	}
+
+	// Another agent, without a delay, is not held back.
+	start := time.Now()
+	c = New(WithBaseURL(srv.URL), WithRobotsPolicy("somebot"))
+	for i := 0; i < 3; i++ {
+		c.String("/page")
+	}
+	if d := time.Since(start); d > 150*time.Millisecond {
+		t.Errorf("took %v without a crawl delay", d)
+	}
+}
@@ -0,0 +1,108 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// A Signer signs requests, usually by setting headers computed from the
+// method, URL, headers and body of the request. Sign is called with the
+// final request right before it is sent, and again before every retry or
+// redirect, so that a signature covering a date stays fresh. bodyHash is
+// the SHA-256 hash of the body, computed once per request. It is nil when
+// the body cannot be read twice, i.e. the request has a body but no
+// GetBody, as with an io.Pipe: the body is then sent unsigned, and Sign
+// may return ErrUnsignedBody to refuse that, or sign with a placeholder
+// such as AWS's UNSIGNED-PAYLOAD.
+//
+// An AWS Signature Version 4 signer, for instance, would set X-Amz-Date,
+// X-Amz-Content-Sha256 to the hex encoded bodyHash, and an Authorization
+// header signing the canonical request, which includes that same hash:
+//
+//	func (s *sigV4) Sign(req *http.Request, bodyHash []byte) error {
+//		now := time.Now().UTC()
+//		req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+//		req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(bodyHash))
+//		req.Header.Set("Authorization", s.authorization(req, now, bodyHash))
+//		return nil
+//	}
+//
+// An error from Sign fails the request.
+type Signer interface {
+	Sign(req *http.Request, bodyHash []byte) error
+}
+
+// ErrUnsignedBody is returned by a Signer for a request whose body cannot
+// be hashed because it cannot be read twice.
+var ErrUnsignedBody = errors.New("httpclient: request body cannot be read twice to be signed")
+
+// WithSigner signs every request made by the client with s.
+func WithSigner(s Signer) Option {
+	return func(c *httpClient) {
+		c.signer = s
+	}
+}
+
+// signTransport signs every request before passing it on.
+type signTransport struct {
+	next   http.RoundTripper
+	signer Signer
+}
+
+type bodyHashKey struct{}
+
+// withBodyHash returns req with room in its context to keep the hash of
+// its body, so that it is only computed once across retries and redirects.
+func withBodyHash(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), bodyHashKey{}, new([]byte)))
+}
+
+func (t *signTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	sum, err := hashBody(req)
+	if err == nil {
+		err = t.signer.Sign(req, sum)
+	}
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+var emptyHash = sha256.Sum256(nil)
+
+// hashBody returns the SHA-256 hash of the body of req, read from
+// GetBody, or nil if req has a body but no GetBody.
+func hashBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return emptyHash[:], nil
+	}
+	if req.GetBody == nil {
+		return nil, nil
+	}
+	cached, _ := req.Context().Value(bodyHashKey{}).(*[]byte)
+	if cached != nil && *cached != nil {
+		return *cached, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	_, err = io.Copy(h, body)
+	body.Close()
+	if err != nil {
+		return nil, err
+	}
+	sum := h.Sum(nil)
+	if cached != nil {
+		*cached = sum
+	}
+	return sum, nil
+}
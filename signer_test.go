@@ -0,0 +1,125 @@
+package httpclient
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSigner sets an X-Sign-Count header counting its calls and
+// records the headers and body hashes it is given.
+type recordingSigner struct {
+	mu      sync.Mutex
+	headers []http.Header
+	hashes  [][]byte
+}
+
+func (s *recordingSigner) Sign(req *http.Request, bodyHash []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.headers = append(s.headers, req.Header.Clone())
+	s.hashes = append(s.hashes, bodyHash)
+	req.Header.Set("X-Sign-Count", fmt.Sprint(len(s.headers)))
+	return nil
+}
+
+func TestSignerSeesFinalHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("X-Sign-Count"))
+	}))
+	defer srv.Close()
+	s := &recordingSigner{}
+	c := New(WithSigner(s), WithCompression("gzip"))
+	c.SetCredentials(strings.TrimPrefix(srv.URL, "http://"), BearerCredential("t"))
+	got, err := c.String(srv.URL, WithRequestHeader("X-Custom", "v"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "1" {
+		t.Errorf("server got X-Sign-Count %q, want 1", got)
+	}
+	h := s.headers[0]
+	for k, want := range map[string]string{
+		"X-Custom":        "v",
+		"Authorization":   "Bearer t",
+		"Accept-Encoding": "gzip",
+	} {
+		if got := h.Get(k); got != want {
+			t.Errorf("signer saw %s %q, want %q", k, got, want)
+		}
+	}
+}
+
+func TestSignerResignsRetries(t *testing.T) {
+	var mu sync.Mutex
+	var counts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		mu.Lock()
+		counts = append(counts, r.Header.Get("X-Sign-Count"))
+		n := len(counts)
+		mu.Unlock()
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	s := &recordingSigner{}
+	c := New(WithSigner(s), WithRetry(3, time.Millisecond))
+	resp, err := c.Put(srv.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want := []string{"1", "2", "3"}; fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("server got signatures %v, want %v", counts, want)
+	}
+	sum := sha256.Sum256([]byte("payload"))
+	for i, h := range s.hashes {
+		if string(h) != string(sum[:]) {
+			t.Errorf("attempt %d: body hash %x, want %x", i+1, h, sum)
+		}
+	}
+}
+
+func TestSignerUnreplayableBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+	defer srv.Close()
+
+	pipe := func() io.Reader {
+		pr, pw := io.Pipe()
+		go func() {
+			io.WriteString(pw, "streamed")
+			pw.Close()
+		}()
+		return pr
+	}
+
+	s := &recordingSigner{}
+	resp, err := New(WithSigner(s)).Post(srv.URL, "text/plain", pipe())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "streamed" {
+		t.Errorf("server got %q, want %q", body, "streamed")
+	}
+	if s.hashes[0] != nil {
+		t.Errorf("got body hash %x, want nil", s.hashes[0])
+	}
+
+	_, err = New(WithHMACSigning("id", "secret", HMACConfig{})).Post(srv.URL, "text/plain", pipe())
+	if !errors.Is(err, ErrUnsignedBody) {
+		t.Errorf("got %v, want ErrUnsignedBody", err)
+	}
+}
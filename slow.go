@@ -0,0 +1,76 @@
+package httpclient
+
+import (
+	"time"
+)
+
+// SlowRequestInfo describes a request that took longer than the threshold
+// given to WithSlowRequestThreshold.
+type SlowRequestInfo struct {
+	Method   string
+	URL      string
+	Duration time.Duration
+
+	// StatusCode is the status of the response, zero if the request is
+	// still in progress or failed.
+	StatusCode int
+
+	// Err is the error the request failed with, if any.
+	Err error
+
+	// Timings is the breakdown of the request when the client records
+	// timings, nil otherwise.
+	Timings *Timings
+
+	// InProgress is true when the request had not completed yet when the
+	// threshold passed. Such requests are reported again once they
+	// complete.
+	InProgress bool
+}
+
+// WithSlowRequestThreshold calls fn for every request that takes longer
+// than d, including the time to read the response body. fn is called once
+// when the threshold passes with InProgress set, from its own goroutine, and
+// again when the request completes.
+func WithSlowRequestThreshold(d time.Duration, fn func(SlowRequestInfo)) Option {
+	return func(c *httpClient) {
+		c.slowThreshold = d
+		c.slow = fn
+	}
+}
+
+// watchSlow arms the in-progress notification of x.
+func (c *httpClient) watchSlow(x *exchange) {
+	if c.slow == nil {
+		return
+	}
+	x.slowTimer = time.AfterFunc(c.slowThreshold, func() {
+		c.slow(SlowRequestInfo{
+			Method:     x.req.Method,
+			URL:        c.redact.url(x.req.URL).String(),
+			Duration:   time.Since(x.start),
+			Timings:    x.snapshotTimings(),
+			InProgress: true,
+		})
+	})
+}
+
+// reportSlow reports x, which has finished with the status or err, if it
+// took longer than the threshold.
+func (c *httpClient) reportSlow(x *exchange, elapsed time.Duration, status int, err error) {
+	if c.slow == nil {
+		return
+	}
+	x.slowTimer.Stop()
+	if elapsed < c.slowThreshold {
+		return
+	}
+	c.slow(SlowRequestInfo{
+		Method:     x.req.Method,
+		URL:        c.redact.url(x.req.URL).String(),
+		Duration:   elapsed,
+		StatusCode: status,
+		Err:        err,
+		Timings:    x.snapshotTimings(),
+	})
+}
@@ -0,0 +1,97 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowRequestThreshold(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		io.WriteString(w, "hello")
+	}))
+	defer srv.Close()
+
+	infos := make(chan SlowRequestInfo, 10)
+	c := New(WithTimings(), WithSlowRequestThreshold(20*time.Millisecond, func(info SlowRequestInfo) {
+		infos <- info
+	}))
+
+	if _, err := c.Bytes(srv.URL + "/fast"); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := c.Bytes(srv.URL + "/slow?token=secret")
+		done <- err
+	}()
+
+	// The handler only answers once the request has been reported as in
+	// progress, so the order is fixed.
+	info := <-infos
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !info.InProgress || info.StatusCode != 0 || info.Err != nil || info.Method != "GET" {
+		t.Errorf("in progress: got %+v", info)
+	}
+	if info.Duration < 20*time.Millisecond {
+		t.Errorf("in progress: got duration %v before the threshold", info.Duration)
+	}
+	if !strings.HasPrefix(info.URL, srv.URL+"/slow") || strings.Contains(info.URL, "secret") {
+		t.Errorf("in progress: got URL %q", info.URL)
+	}
+	if info.Timings == nil || info.Timings.GotConn.IsZero() {
+		t.Errorf("in progress: got timings %+v", info.Timings)
+	}
+
+	info = <-infos
+	if info.InProgress || info.StatusCode != 200 || info.Err != nil {
+		t.Errorf("completed: got %+v", info)
+	}
+	if info.Duration < 20*time.Millisecond || strings.Contains(info.URL, "secret") {
+		t.Errorf("completed: got %+v", info)
+	}
+	if info.Timings == nil || info.Timings.BodyReadComplete.IsZero() {
+		t.Errorf("completed: got timings %+v", info.Timings)
+	}
+
+	// Nothing else, in particular not the fast request.
+	select {
+	case info := <-infos:
+		t.Errorf("got another notification %+v", info)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSlowRequestFailed(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	infos := make(chan SlowRequestInfo, 10)
+	c := New(WithSlowRequestThreshold(10*time.Millisecond, func(info SlowRequestInfo) {
+		infos <- info
+	}))
+	if _, err := c.Bytes(srv.URL, WithRequestTimeout(50*time.Millisecond)); err == nil {
+		t.Fatal("got no error")
+	}
+	if info := <-infos; !info.InProgress {
+		t.Errorf("got %+v, want the request in progress first", info)
+	}
+	info := <-infos
+	if info.InProgress || info.Err == nil || info.StatusCode != 0 || info.Timings != nil {
+		t.Errorf("got %+v, want the failure", info)
+	}
+}
@@ -0,0 +1,123 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+// WithSpillToDisk keeps response bodies larger than threshold bytes in a
+// temporary file in dir, or in os.TempDir if dir is empty, rather than in
+// memory. It applies to the Response returned by RequestBuilder.Do and
+// Poll, and to each download of Files: a body that spills is in the Spill
+// field instead of Body or Data. The file is removed when Spill is closed,
+// or right away if the download fails.
+func WithSpillToDisk(threshold int64, dir string) RequestOption {
+	return func(o *requestOptions) {
+		o.spillThreshold, o.spillDir = threshold, dir
+	}
+}
+
+// spillFile is a body kept in a temporary file, removed on Close.
+type spillFile struct {
+	*os.File
+}
+
+func (f *spillFile) Close() error {
+	err := f.File.Close()
+	if rerr := os.Remove(f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// readSpill reads the body of resp into memory if it is at most the spill
+// threshold of o long, or else into a temporary file.
+func (c *httpClient) readSpill(resp *http.Response, o *requestOptions) ([]byte, io.ReadSeekCloser, error) {
+	if o.spillThreshold <= 0 || resp.ContentLength >= 0 && resp.ContentLength <= o.spillThreshold {
+		p, err := c.readAll(resp)
+		return p, nil, err
+	}
+	if c.maxBody > 0 && resp.ContentLength > c.maxBody {
+		return nil, nil, c.tooLarge(resp)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if _, err := buf.ReadFrom(io.LimitReader(resp.Body, o.spillThreshold+1)); err != nil {
+		return nil, nil, err
+	}
+	if int64(buf.Len()) <= o.spillThreshold {
+		return append([]byte(nil), buf.Bytes()...), nil, nil
+	}
+
+	tmp, err := ioutil.TempFile(o.spillDir, "httpclient-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	f := &spillFile{tmp}
+	rest := io.Reader(resp.Body)
+	if c.maxBody > 0 {
+		rest = io.LimitReader(rest, c.maxBody+1-int64(buf.Len()))
+	}
+	n, err := io.Copy(f, io.MultiReader(bytes.NewReader(buf.Bytes()), rest))
+	if err == nil && c.maxBody > 0 && n > c.maxBody {
+		err = c.tooLarge(resp)
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return nil, f, nil
+}
+
+// BytesInto fetches url and reads the response body into buf, from its
+// start, and returns it. buf is grown if it is too small, so that the
+// result may be passed in again to reuse its memory across calls.
+func (c *httpClient) BytesInto(url string, buf []byte, opts ...RequestOption) ([]byte, error) {
+	o := newRequestOptions(opts)
+	resp, err := c.send("GET", url, nil, o)
+	if err != nil {
+		return buf[:0], err
+	}
+	defer resp.Body.Close()
+	if !c.accepted(resp, o) {
+		return buf[:0], c.err(resp, "")
+	}
+	if n := resp.ContentLength; n > 0 {
+		if c.maxBody > 0 && n > c.maxBody {
+			return buf[:0], c.tooLarge(resp)
+		}
+		if n <= maxPrealloc && int64(cap(buf)) < n+1 {
+			buf = make([]byte, 0, n+1)
+		}
+	}
+	buf = buf[:0]
+	for {
+		if len(buf) == cap(buf) {
+			buf = append(buf, 0)[:len(buf)]
+		}
+		n, err := resp.Body.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if c.maxBody > 0 && int64(len(buf)) > c.maxBody {
+			return buf[:0], c.tooLarge(resp)
+		}
+		if err == io.EOF {
+			return buf, nil
+		}
+		if err != nil {
+			return buf[:0], err
+		}
+	}
+}
+
+// BytesInto fetches url with the default client and reads the response
+// body into buf.
+func BytesInto(url string, buf []byte, opts ...RequestOption) ([]byte, error) {
+	return defaultClient().BytesInto(url, buf, opts...)
+}
@@ -0,0 +1,227 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBytesInto(t *testing.T) {
+	body := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	for _, length := range []int64{int64(len(body)), -1} {
+		c := New(WithTransport(&staticTransport{body: body, length: length}))
+
+		buf, err := c.BytesInto("http://example.com/", nil)
+		if err != nil || !bytes.Equal(buf, body) {
+			t.Fatalf("length %d: got %d bytes, %v", length, len(buf), err)
+		}
+		// The buffer is reused: same memory, and fewer allocations than
+		// reading into a new one each time.
+		again, err := c.BytesInto("http://example.com/", buf)
+		if err != nil || !bytes.Equal(again, body) || &again[0] != &buf[0] {
+			t.Errorf("length %d: buffer not reused", length)
+		}
+		reused := testing.AllocsPerRun(20, func() {
+			buf, _ = c.BytesInto("http://example.com/", buf)
+		})
+		fresh := testing.AllocsPerRun(20, func() {
+			c.BytesInto("http://example.com/", nil)
+		})
+		if reused >= fresh {
+			t.Errorf("length %d: got %v allocations reusing the buffer, %v without", length, reused, fresh)
+		}
+		// So does Bytes, which allocates the body every time.
+		if plain := testing.AllocsPerRun(20, func() { c.Bytes("http://example.com/") }); reused >= plain {
+			t.Errorf("length %d: got %v allocations reusing the buffer, %v with Bytes", length, reused, plain)
+		}
+	}
+
+	// A shorter body fits in the buffer, overwriting it from the start.
+	big := make([]byte, 0, 1024)
+	big = append(big, "stale contents"...)
+	c := New(WithTransport(&staticTransport{body: []byte("new"), length: 3}))
+	got, err := c.BytesInto("http://example.com/", big)
+	if err != nil || string(got) != "new" || &got[:1][0] != &big[:1][0] {
+		t.Errorf("got %q, %v", got, err)
+	}
+
+	// Errors return the buffer empty, so that it can still be reused.
+	c = New(WithTransport(&staticTransport{body: body, length: -1}), WithMaxBodySize(100))
+	got, err = c.BytesInto("http://example.com/", big)
+	if err == nil || len(got) != 0 || cap(got) != cap(big) {
+		t.Errorf("too large: got %d bytes of %d, %v", len(got), cap(got), err)
+	}
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	got, err = BytesInto(srv.URL, big)
+	if !errors.Is(err, ErrNotFound) || len(got) != 0 || cap(got) != cap(big) {
+		t.Errorf("404: got %d bytes of %d, %v", len(got), cap(got), err)
+	}
+}
+
+// spilled returns the names of the files in dir.
+func spilled(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestSpillToDisk(t *testing.T) {
+	const threshold = 1000
+	for _, size := range []int{0, threshold - 1, threshold, threshold + 1, 50000} {
+		for _, known := range []bool{true, false} {
+			body := bytes.Repeat([]byte{'x'}, size)
+			length := int64(size)
+			if !known {
+				length = -1
+			}
+			dir := t.TempDir()
+			c := New(WithTransport(&staticTransport{body: body, length: length}))
+			resp, err := c.NewRequest().Path("http://example.com/").Option(WithSpillToDisk(threshold, dir)).Do(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			files := spilled(t, dir)
+			if size <= threshold {
+				if resp.Spill != nil || len(resp.Body) != size || len(files) != 0 {
+					t.Errorf("%d, known %v: got spill %v, %d bytes, files %v", size, known, resp.Spill != nil, len(resp.Body), files)
+				}
+				continue
+			}
+			if resp.Spill == nil || resp.Body != nil || len(files) != 1 || !strings.HasPrefix(files[0], "httpclient-") {
+				t.Fatalf("%d, known %v: got spill %v, %d bytes, files %v", size, known, resp.Spill != nil, len(resp.Body), files)
+			}
+			// The spilled body reads back whole, twice.
+			for i := 0; i < 2; i++ {
+				if s := resp.String(); len(s) != size {
+					t.Errorf("%d, known %v: read %d bytes back", size, known, len(s))
+				}
+			}
+			if err := resp.Close(); err != nil {
+				t.Error(err)
+			}
+			if files := spilled(t, dir); len(files) != 0 {
+				t.Errorf("%d, known %v: files left after Close: %v", size, known, files)
+			}
+		}
+	}
+}
+
+// failingBody returns n bytes, then an error.
+type failingBody struct {
+	n int
+}
+
+func (b *failingBody) Read(p []byte) (int, error) {
+	if b.n == 0 {
+		return 0, errors.New("connection reset")
+	}
+	if len(p) > b.n {
+		p = p[:b.n]
+	}
+	for i := range p {
+		p[i] = 'x'
+	}
+	b.n -= len(p)
+	return len(p), nil
+}
+
+func (b *failingBody) Close() error { return nil }
+
+// brokenTransport answers every request with a body that breaks after n
+// bytes.
+type brokenTransport struct {
+	n int
+}
+
+func (t brokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := newResponse(req, http.StatusOK, nil, nil)
+	resp.Body, resp.ContentLength = &failingBody{n: t.n}, -1
+	return resp, nil
+}
+
+func TestSpillToDiskCleanup(t *testing.T) {
+	dir := t.TempDir()
+
+	// The body breaks after the threshold.
+	c := New(WithTransport(brokenTransport{n: 5000}))
+	_, err := c.NewRequest().Path("http://example.com/").Option(WithSpillToDisk(100, dir)).Do(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Errorf("broken body: got %v", err)
+	}
+	if files := spilled(t, dir); len(files) != 0 {
+		t.Errorf("broken body: files left: %v", files)
+	}
+
+	// The body grows past the limit of the client.
+	c = New(WithTransport(&staticTransport{body: make([]byte, 5000), length: -1}), WithMaxBodySize(2000))
+	_, err = c.NewRequest().Path("http://example.com/").Option(WithSpillToDisk(100, dir)).Do(context.Background())
+	if err == nil {
+		t.Error("too large: got no error")
+	}
+	if files := spilled(t, dir); len(files) != 0 {
+		t.Errorf("too large: files left: %v", files)
+	}
+}
+
+func TestSpillToDiskFiles(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		if err != nil {
+			http.Error(w, "no", http.StatusInternalServerError)
+			return
+		}
+		w.Write(bytes.Repeat([]byte{'y'}, n))
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+
+	var files []File
+	err := Files([]string{srv.URL + "/10", srv.URL + "/5000", srv.URL + "/100", srv.URL + "/101"}, &files, WithSpillToDisk(100, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []bool{false, true, false, true} {
+		if (files[i].Spill != nil) != want || (files[i].Data != nil) == want {
+			t.Errorf("file %d: got spill %v, %d bytes", i, files[i].Spill != nil, len(files[i].Data))
+		}
+	}
+	if n := len(spilled(t, dir)); n != 2 {
+		t.Errorf("got %d files, want 2", n)
+	}
+	p, err := ioutil.ReadAll(files[1].Spill)
+	if err != nil || len(p) != 5000 {
+		t.Errorf("got %d bytes, %v", len(p), err)
+	}
+	for _, f := range files {
+		if f.Spill != nil {
+			f.Spill.Close()
+		}
+	}
+	if names := spilled(t, dir); len(names) != 0 {
+		t.Errorf("files left after Close: %v", names)
+	}
+
+	// When a download fails, the others are removed.
+	files = nil
+	err = Files([]string{srv.URL + "/5000", srv.URL + "/oops", srv.URL + "/6000"}, &files, WithSpillToDisk(100, dir))
+	if err == nil || files != nil {
+		t.Errorf("got %v, %d files", err, len(files))
+	}
+	if names := spilled(t, dir); len(names) != 0 {
+		t.Errorf("files left after a failure: %v", names)
+	}
+}
@@ -0,0 +1,156 @@
+package httpclient
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Stats are totals of the requests made by a client since it was created or
+// its statistics were last reset.
+type Stats struct {
+	// Requests counts the requests sent, by method.
+	Requests map[string]int64
+
+	// Responses counts the responses received, by status class ("2xx",
+	// "4xx"...).
+	Responses map[string]int64
+
+	// BytesDownloaded is the number of bytes read from response bodies.
+	BytesDownloaded int64
+
+	// BytesUploaded is the number of bytes sent in request bodies.
+	BytesUploaded int64
+
+	// Active is the number of requests in progress.
+	Active int64
+
+	// NewConnections and ReusedConnections count the connections requests
+	// were sent on, by whether they were dialed for the request or reused
+	// from an earlier one. In-process transports, such as the one of
+	// NewForHandler, use no connections.
+	NewConnections    int64
+	ReusedConnections int64
+
+	// Retries is the number of times a request was sent again after a
+	// transient failure.
+	Retries int64
+
+	// CacheHits is the number of responses served from the cache of
+	// WithCache after a 304 Not Modified.
+	CacheHits int64
+}
+
+// stats holds the counters behind Stats. Scalars are updated atomically;
+// the maps are guarded by mu.
+type stats struct {
+	mu         sync.Mutex
+	requests   map[string]int64
+	responses  map[string]int64
+	downloaded int64
+	uploaded   int64
+	active     int64
+	newConns   int64
+	reused     int64
+	retries    int64
+	cacheHits  int64
+}
+
+func (s *stats) started(method string) {
+	atomic.AddInt64(&s.active, 1)
+	s.mu.Lock()
+	if s.requests == nil {
+		s.requests = make(map[string]int64)
+	}
+	s.requests[method]++
+	s.mu.Unlock()
+}
+
+func (s *stats) gotConn(reused bool) {
+	if reused {
+		atomic.AddInt64(&s.reused, 1)
+	} else {
+		atomic.AddInt64(&s.newConns, 1)
+	}
+}
+
+func (s *stats) retried() {
+	atomic.AddInt64(&s.retries, 1)
+}
+
+func (s *stats) cacheHit() {
+	atomic.AddInt64(&s.cacheHits, 1)
+}
+
+func (s *stats) failed() {
+	atomic.AddInt64(&s.active, -1)
+}
+
+func (s *stats) completed(status int, in, out int64) {
+	atomic.AddInt64(&s.active, -1)
+	atomic.AddInt64(&s.downloaded, in)
+	atomic.AddInt64(&s.uploaded, out)
+	s.mu.Lock()
+	if s.responses == nil {
+		s.responses = make(map[string]int64)
+	}
+	s.responses[statusClass(status)]++
+	s.mu.Unlock()
+}
+
+func (s *stats) snapshot() Stats {
+	st := Stats{
+		Requests:        make(map[string]int64),
+		Responses:       make(map[string]int64),
+		BytesDownloaded: atomic.LoadInt64(&s.downloaded),
+		BytesUploaded:   atomic.LoadInt64(&s.uploaded),
+		Active:          atomic.LoadInt64(&s.active),
+
+		NewConnections:    atomic.LoadInt64(&s.newConns),
+		ReusedConnections: atomic.LoadInt64(&s.reused),
+		Retries:           atomic.LoadInt64(&s.retries),
+		CacheHits:         atomic.LoadInt64(&s.cacheHits),
+	}
+	s.mu.Lock()
+	for k, v := range s.requests {
+		st.Requests[k] = v
+	}
+	for k, v := range s.responses {
+		st.Responses[k] = v
+	}
+	s.mu.Unlock()
+	return st
+}
+
+func (s *stats) reset() {
+	s.mu.Lock()
+	s.requests = nil
+	s.responses = nil
+	s.mu.Unlock()
+	atomic.StoreInt64(&s.downloaded, 0)
+	atomic.StoreInt64(&s.uploaded, 0)
+	atomic.StoreInt64(&s.newConns, 0)
+	atomic.StoreInt64(&s.reused, 0)
+	atomic.StoreInt64(&s.retries, 0)
+	atomic.StoreInt64(&s.cacheHits, 0)
+}
+
+// Stats returns the totals of the requests made by the client.
+func (c *httpClient) Stats() Stats {
+	return c.stats.snapshot()
+}
+
+// Reset zeroes the client's statistics, including the per host ones.
+// Requests in progress are still counted as active.
+func (c *httpClient) Reset() {
+	c.stats.reset()
+	c.hosts.reset()
+}
+
+// statusClass returns "2xx" for 200 and so on.
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return strconv.Itoa(status)
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
@@ -0,0 +1,217 @@
+package httpclient
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	var flaky int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/flaky":
+			if atomic.AddInt32(&flaky, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		io.WriteString(w, "0123456789")
+	}))
+	defer srv.Close()
+
+	c := New(WithRetry(2, time.Millisecond))
+	for i := 0; i < 3; i++ {
+		if _, err := c.Bytes(srv.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Bytes(srv.URL + "/missing"); err == nil {
+		t.Fatal("got no error for /missing")
+	}
+	if _, err := c.Bytes(srv.URL + "/flaky"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Post(srv.URL, "text/plain", strings.NewReader("12345"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	// A body of unknown length is sent chunked.
+	pr, pw := io.Pipe()
+	go func() {
+		io.WriteString(pw, "abcdefg")
+		pw.Close()
+	}()
+	resp, err = c.Post(srv.URL, "text/plain", pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	st := c.Stats()
+	if st.Requests["GET"] != 6 || st.Requests["POST"] != 2 {
+		t.Errorf("got requests %v, want 6 GET and 2 POST", st.Requests)
+	}
+	if st.Responses["2xx"] != 6 || st.Responses["4xx"] != 1 || st.Responses["5xx"] != 1 {
+		t.Errorf("got responses %v", st.Responses)
+	}
+	if st.BytesUploaded != 12 {
+		t.Errorf("got %d bytes uploaded, want 12", st.BytesUploaded)
+	}
+	// The body of the 404 is read into the error too, the 503 has none.
+	if st.BytesDownloaded != 7*10 {
+		t.Errorf("got %d bytes downloaded, want 70", st.BytesDownloaded)
+	}
+	if st.Retries != 1 {
+		t.Errorf("got %d retries, want 1", st.Retries)
+	}
+	if st.Active != 0 {
+		t.Errorf("got %d active, want 0", st.Active)
+	}
+	if st.NewConnections+st.ReusedConnections != 8 || st.ReusedConnections == 0 {
+		t.Errorf("got %d new and %d reused connections", st.NewConnections, st.ReusedConnections)
+	}
+
+	c.Reset()
+	st = c.Stats()
+	if len(st.Requests) != 0 || st.BytesDownloaded != 0 || st.BytesUploaded != 0 || st.Retries != 0 {
+		t.Errorf("got %+v after Reset", st)
+	}
+}
+
+func TestStatsConcurrent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+		io.WriteString(w, "abcd")
+	}))
+	defer srv.Close()
+	c := New()
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				st := c.Stats()
+				if st.Active < 0 {
+					t.Error("negative active count")
+				}
+				time.Sleep(100 * time.Microsecond)
+			}
+		}()
+	}
+
+	const workers, each = 8, 25
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < each; j++ {
+				resp, err := c.Post(srv.URL, "text/plain", strings.NewReader("xy"))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				io.Copy(ioutil.Discard, resp.Body)
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	readers.Wait()
+
+	st := c.Stats()
+	const n = workers * each
+	if st.Requests["POST"] != n || st.Responses["2xx"] != n {
+		t.Errorf("got %v requests and %v responses, want %d", st.Requests, st.Responses, n)
+	}
+	if st.BytesDownloaded != 4*n || st.BytesUploaded != 2*n {
+		t.Errorf("got %d down and %d up, want %d and %d", st.BytesDownloaded, st.BytesUploaded, 4*n, 2*n)
+	}
+	if st.Active != 0 {
+		t.Errorf("got %d active, want 0", st.Active)
+	}
+}
+
+func TestConnectionReuse(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		close bool
+	}{
+		{"keep-alive", false},
+		{"connection close", true},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.close {
+				w.Header().Set("Connection", "close")
+			}
+			io.WriteString(w, "hello")
+		}))
+
+		c := New(WithTimings())
+		var timings []*Timings
+		for i := 0; i < 5; i++ {
+			_, r, err := c.BytesResponse(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			timings = append(timings, r.Timings)
+		}
+		srv.Close()
+
+		for i, tm := range timings {
+			reused := !tt.close && i > 0
+			if tm.Reused != reused || tm.WasIdle != reused {
+				t.Errorf("%s: request %d: got Reused %v, WasIdle %v, want %v", tt.name, i, tm.Reused, tm.WasIdle, reused)
+			}
+			if !reused && tm.IdleTime != 0 {
+				t.Errorf("%s: request %d: got IdleTime %v on a new connection", tt.name, i, tm.IdleTime)
+			}
+		}
+
+		wantNew, wantReused := int64(1), int64(4)
+		if tt.close {
+			wantNew, wantReused = 5, 0
+		}
+		s := c.Stats()
+		if s.NewConnections != wantNew || s.ReusedConnections != wantReused {
+			t.Errorf("%s: got %d new and %d reused connections, want %d and %d", tt.name, s.NewConnections, s.ReusedConnections, wantNew, wantReused)
+		}
+		h := c.HostStats()[strings.TrimPrefix(srv.URL, "http://")]
+		if h.NewConnections != wantNew || h.ReusedConnections != wantReused {
+			t.Errorf("%s: host stats: got %d new and %d reused connections", tt.name, h.NewConnections, h.ReusedConnections)
+		}
+	}
+}
+
+func TestConnectionsInProcess(t *testing.T) {
+	c := NewForHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if _, err := c.Bytes("http://api.invalid/"); err != nil {
+		t.Fatal(err)
+	}
+	if s := c.Stats(); s.NewConnections != 0 || s.ReusedConnections != 0 {
+		t.Errorf("got %d new and %d reused connections without a network", s.NewConnections, s.ReusedConnections)
+	}
+}
@@ -0,0 +1,68 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// errorBodyLimit is how much of the body of a rejected response is kept in
+// its *Error.
+const errorBodyLimit = 4 << 10
+
+// SetAcceptedStatus sets the rule for which response statuses the helpers,
+// such as Bytes, JSON and Files, accept; the others fail with an *Error.
+// By default any 2xx status is accepted. A nil accept restores the default.
+// It must be called before c is used.
+func (c *httpClient) SetAcceptedStatus(accept func(code int) bool) {
+	c.accept = accept
+}
+
+// SetAcceptedStatus sets the rule for which response statuses the default
+// client accepts.
+func SetAcceptedStatus(accept func(code int) bool) {
+	defaultClient().SetAcceptedStatus(accept)
+}
+
+// WithAcceptStatus accepts the response of the request if its status is
+// one of codes, besides the statuses the client accepts, e.g. to read the
+// body of a 404 response.
+func WithAcceptStatus(codes ...int) RequestOption {
+	return func(o *requestOptions) {
+		o.accept = append(o.accept, codes...)
+	}
+}
+
+// accepted reports whether the status of resp counts as a success.
+func (c *httpClient) accepted(resp *http.Response, o *requestOptions) bool {
+	for _, code := range o.accept {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	if c.accept != nil {
+		return c.accept(resp.StatusCode)
+	}
+	return resp.StatusCode >= 200 && resp.StatusCode <= 299
+}
+
+// errorBody returns the start of the body of resp, redacted, without
+// consuming it: the body of resp still reads from the beginning. A longer
+// body that the redaction rules apply to is left out, since it cannot be
+// redacted whole.
+func (c *httpClient) errorBody(resp *http.Response) []byte {
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+	p, _ := io.ReadAll(io.LimitReader(resp.Body, errorBodyLimit+1))
+	resp.Body = &replayBody{Reader: io.MultiReader(bytes.NewReader(p), resp.Body), Closer: resp.Body}
+	ct := resp.Header.Get("Content-Type")
+	if len(p) == 0 || len(p) > errorBodyLimit && c.redact.redactsBody(ct) {
+		return nil
+	}
+	p = c.redact.body(ct, p)
+	if len(p) > errorBodyLimit {
+		p = p[:errorBodyLimit]
+	}
+	return p
+}
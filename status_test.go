@@ -0,0 +1,129 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// statusServer answers /NNN with the status NNN and a body naming it.
+func statusServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		w.WriteHeader(code)
+		fmt.Fprintf(w, "status %d", code)
+	}))
+}
+
+func TestSetAcceptedStatus(t *testing.T) {
+	srv := statusServer()
+	defer srv.Close()
+	codes := []int{200, 201, 299, 304, 404, 410, 500}
+
+	tests := []struct {
+		name   string
+		accept func(code int) bool
+		opts   []RequestOption
+		want   string // the accepted codes
+	}{
+		{"default", nil, nil, "200 201 299"},
+		{"not found too", func(code int) bool { return code/100 == 2 || code == 404 }, nil, "200 201 299 404"},
+		{"only 200", func(code int) bool { return code == 200 }, nil, "200"},
+		{"any", func(code int) bool { return true }, nil, "200 201 299 304 404 410 500"},
+		{"WithAcceptStatus", nil, []RequestOption{WithAcceptStatus(404, 410)}, "200 201 299 404 410"},
+		{"WithAcceptStatus and rule", func(code int) bool { return code == 200 }, []RequestOption{WithAcceptStatus(500)}, "200 500"},
+	}
+	for _, tt := range tests {
+		c := New()
+		c.SetAcceptedStatus(tt.accept)
+		var accepted []string
+		for _, code := range codes {
+			url := fmt.Sprintf("%s/%d", srv.URL, code)
+			_, err := c.Bytes(url, tt.opts...)
+			var files []File
+			ferr := c.Files([]string{url}, &files, tt.opts...)
+			if (err == nil) != (ferr == nil) {
+				t.Errorf("%s %d: Bytes got %v, but Files got %v", tt.name, code, err, ferr)
+			}
+			if err == nil {
+				accepted = append(accepted, strconv.Itoa(code))
+				continue
+			}
+			var herr *Error
+			if !errors.As(err, &herr) || herr.StatusCode != code {
+				t.Errorf("%s %d: got %v, want an *Error with the status", tt.name, code, err)
+			}
+		}
+		if got := strings.Join(accepted, " "); got != tt.want {
+			t.Errorf("%s: accepted %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	// A nil rule restores the default.
+	c := New()
+	c.SetAcceptedStatus(func(code int) bool { return true })
+	c.SetAcceptedStatus(nil)
+	if _, err := c.Bytes(srv.URL + "/404"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("nil: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestErrorBody(t *testing.T) {
+	long := strings.Repeat("x", errorBodyLimit+100)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/long":
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(long))
+		case "/long.json":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"password":"hunter2","pad":%q}`, long)
+		case "/empty":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"bad name","password":"hunter2"}`))
+		}
+	}))
+	defer srv.Close()
+	c := New(WithRedaction(nil, nil, []string{"password"}))
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/short", `{"error":"bad name","password":"[REDACTED]"}`},
+		{"/long", long[:errorBodyLimit]},
+		{"/long.json", ""}, // too long to redact whole
+		{"/empty", ""},
+	}
+	for _, tt := range tests {
+		_, err := c.Bytes(srv.URL + tt.path)
+		var herr *Error
+		if !errors.As(err, &herr) || herr.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: got %v, want a 400 *Error", tt.path, err)
+			continue
+		}
+		if string(herr.Body) != tt.want {
+			t.Errorf("%s: got body of %d bytes %.40q, want %d bytes %.40q", tt.path, len(herr.Body), herr.Body, len(tt.want), tt.want)
+		}
+	}
+
+	// Capturing the body does not consume it.
+	resp, err := c.NewRequest().Path(srv.URL + "/long").Do(context.Background())
+	var herr *Error
+	if !errors.As(err, &herr) || len(herr.Body) != errorBodyLimit || resp == nil {
+		t.Fatalf("got %v, %v", resp, err)
+	}
+	if resp.String() != long {
+		t.Errorf("got %d bytes of body, want %d", len(resp.String()), len(long))
+	}
+}
@@ -0,0 +1,72 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// JSONStream fetches url, whose body is newline-delimited JSON (NDJSON or
+// JSON Lines), and decodes it line by line as it arrives, without holding
+// the whole body: each line is decoded into a new value from newItem,
+// which onItem is then called with. Blank lines are skipped.
+//
+// An error of onItem stops the stream and is returned as it is. A line
+// that fails to decode fails with an *Error giving its line number.
+func (c *httpClient) JSONStream(url string, newItem func() interface{}, onItem func(interface{}) error, opts ...RequestOption) error {
+	o := newRequestOptions(opts)
+	resp, err := c.send("GET", url, nil, o)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if !c.accepted(resp, o) {
+		return c.err(resp, "")
+	}
+	r := bufio.NewReader(resp.Body)
+	for line := 1; ; line++ {
+		p, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			if _, ok := err.(*Error); ok {
+				return err
+			}
+			return c.bodyErr(resp, err)
+		}
+		if p = bytes.TrimSpace(p); len(p) > 0 {
+			v := newItem()
+			if derr := decodeLine(p, v, o.strict); derr != nil {
+				return c.err(resp, fmt.Sprintf("JSON syntax error at %s line %d: %v", c.redact.urlString(url), line, derr))
+			}
+			if cerr := onItem(v); cerr != nil {
+				return cerr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// decodeLine decodes the single JSON value of a line into v.
+func decodeLine(p []byte, v interface{}, strict bool) error {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("more than one value on the line")
+	}
+	return nil
+}
+
+// JSONStream fetches the newline-delimited JSON at url with the default
+// client and calls onItem with each of its values.
+func JSONStream(url string, newItem func() interface{}, onItem func(interface{}) error, opts ...RequestOption) error {
+	return defaultClient().JSONStream(url, newItem, onItem, opts...)
+}
@@ -0,0 +1,122 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type streamItem struct {
+	N int `json:"n"`
+}
+
+func TestJSONStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		switch r.URL.Path {
+		case "/events":
+			io.WriteString(w, "{\"n\":1}\n\n  \r\n{\"n\":2}\r\n\t{\"n\":3}  \n\n{\"n\":4}")
+		case "/syntax":
+			io.WriteString(w, "{\"n\":1}\n\n{\"n\":\n{\"n\":4}\n")
+		case "/type":
+			io.WriteString(w, "{\"n\":1}\n{\"n\":\"two\"}\n")
+		case "/two":
+			io.WriteString(w, "{\"n\":1} {\"n\":2}\n")
+		case "/unknown":
+			io.WriteString(w, "{\"n\":1,\"m\":2}\n")
+		case "/empty":
+		default:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		path  string
+		opts  []RequestOption
+		items []int
+		err   string
+	}{
+		{"blank lines", "/events", nil, []int{1, 2, 3, 4}, ""},
+		{"empty", "/empty", nil, nil, ""},
+		{"syntax", "/syntax?token=hunter2", nil, []int{1}, "JSON syntax error at " + srv.URL + "/syntax?token=[REDACTED] line 3: "},
+		{"type", "/type", nil, []int{1}, "line 2: json: cannot unmarshal string"},
+		{"two values", "/two", nil, nil, "line 1: more than one value on the line"},
+		{"strict", "/unknown", []RequestOption{WithDisallowUnknownFields()}, nil, `line 1: json: unknown field "m"`},
+		{"status", "/down", nil, nil, "Get " + srv.URL + "/down -> 503"},
+	}
+	for _, tt := range tests {
+		var items []int
+		err := JSONStream(srv.URL+tt.path, func() interface{} { return new(streamItem) }, func(v interface{}) error {
+			items = append(items, v.(*streamItem).N)
+			return nil
+		}, tt.opts...)
+		if fmt.Sprint(items) != fmt.Sprint(tt.items) {
+			t.Errorf("%s: got items %v, want %v", tt.name, items, tt.items)
+		}
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		var herr *Error
+		if !errors.As(err, &herr) || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got %v, want an *Error with %q", tt.name, err, tt.err)
+		}
+		if strings.Contains(err.Error(), "hunter2") {
+			t.Errorf("%s: the URL is not redacted: %v", tt.name, err)
+		}
+		if tt.name == "status" && herr != nil && herr.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("%s: got status %d", tt.name, herr.StatusCode)
+		}
+	}
+}
+
+func TestJSONStreamStop(t *testing.T) {
+	closed := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(closed)
+		// An endless stream, until the client goes away.
+		for i := 1; ; i++ {
+			if _, err := fmt.Fprintf(w, "{\"n\":%d}\n", i); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}))
+	defer srv.Close()
+
+	stop := errors.New("stop")
+	var items []int
+	err := JSONStream(srv.URL, func() interface{} { return new(streamItem) }, func(v interface{}) error {
+		items = append(items, v.(*streamItem).N)
+		if len(items) == 3 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("got %v, want the error of the callback", err)
+	}
+	if fmt.Sprint(items) != "[1 2 3]" {
+		t.Errorf("got items %v", items)
+	}
+	// The body was closed, and the server sees the connection go.
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Error("the stream was not closed")
+	}
+}
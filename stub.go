@@ -0,0 +1,242 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A StubTransport is a RoundTripper that answers requests with canned
+// responses chosen by method and URL pattern:
+//
+//	stub := httpclient.NewStub().
+//		Handle("GET", "https://api.example.com/users/*", httpclient.StubJSON(200, users)).
+//		HandleDefault(httpclient.Stub(404, ""))
+//	client := httpclient.NewWithTransport(stub)
+//
+// Routes are tried in the order they were added and the first match wins.
+// Requests no route matches get the default response, are passed through
+// to the transport given to PassThrough, or fail.
+type StubTransport struct {
+	mu     sync.Mutex
+	routes []*stubRoute
+	def    *StubResponse
+	next   http.RoundTripper
+}
+
+type stubRoute struct {
+	method  string
+	pattern string
+	re      *regexp.Regexp
+	resp    *StubResponse
+	calls   int
+}
+
+// NewStub returns a StubTransport without routes.
+func NewStub() *StubTransport {
+	return &StubTransport{}
+}
+
+// Handle answers requests with the method and a URL matching the glob
+// pattern with r. In the pattern, * matches any sequence of characters,
+// slashes included, and ? matches a single character. An empty method or
+// "*" matches any method.
+func (s *StubTransport) Handle(method, pattern string, r *StubResponse) *StubTransport {
+	return s.add(&stubRoute{method: method, pattern: pattern, re: globRegexp(pattern), resp: r})
+}
+
+// HandleRegexp answers requests with the method and a URL matching re with
+// r.
+func (s *StubTransport) HandleRegexp(method string, re *regexp.Regexp, r *StubResponse) *StubTransport {
+	return s.add(&stubRoute{method: method, pattern: re.String(), re: re, resp: r})
+}
+
+// HandleDefault answers requests that match no route with r.
+func (s *StubTransport) HandleDefault(r *StubResponse) *StubTransport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.def = r
+	return s
+}
+
+// PassThrough sends requests that match no route with rt, or with
+// http.DefaultTransport if rt is nil. This stubs some hosts or paths while
+// the rest goes to the real servers.
+func (s *StubTransport) PassThrough(rt http.RoundTripper) *StubTransport {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next = rt
+	return s
+}
+
+// Calls returns the number of requests answered by the route added with
+// the method and pattern.
+func (s *StubTransport) Calls(method, pattern string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, r := range s.routes {
+		if r.method == method && r.pattern == pattern {
+			n += r.calls
+		}
+	}
+	return n
+}
+
+func (s *StubTransport) add(r *stubRoute) *StubTransport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes = append(s.routes, r)
+	return s
+}
+
+// RoundTrip implements http.RoundTripper.
+func (s *StubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u := req.URL.String()
+	s.mu.Lock()
+	var resp *StubResponse
+	for _, r := range s.routes {
+		if (r.method == "" || r.method == "*" || r.method == req.Method) && r.re.MatchString(u) {
+			r.calls++
+			resp = r.resp
+			break
+		}
+	}
+	if resp == nil {
+		resp = s.def
+	}
+	next := s.next
+	s.mu.Unlock()
+	switch {
+	case resp != nil:
+		return resp.response(req)
+	case next != nil:
+		return next.RoundTrip(req)
+	}
+	return nil, fmt.Errorf("httpclient: no stub for %s %s", req.Method, u)
+}
+
+// globRegexp compiles a glob pattern into an anchored regular expression.
+func globRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// A StubResponse is a canned response of a StubTransport.
+type StubResponse struct {
+	status  int
+	header  http.Header
+	body    func() ([]byte, error)
+	latency time.Duration
+}
+
+// Stub returns a response with the status and body.
+func Stub(status int, body string) *StubResponse {
+	return StubBytes(status, []byte(body))
+}
+
+// StubBytes returns a response with the status and body.
+func StubBytes(status int, body []byte) *StubResponse {
+	return &StubResponse{
+		status: status,
+		header: make(http.Header),
+		body:   func() ([]byte, error) { return body, nil },
+	}
+}
+
+// StubJSON returns a response with the status and v encoded as JSON. If v
+// can not be encoded, requests fail with the encoding error.
+func StubJSON(status int, v interface{}) *StubResponse {
+	p, err := json.Marshal(v)
+	r := StubBytes(status, p).WithHeader("Content-Type", "application/json")
+	if err != nil {
+		r.body = func() ([]byte, error) { return nil, err }
+	}
+	return r
+}
+
+// StubFile returns a response with the status and the contents of the file
+// at path, read when the response is served.
+func StubFile(status int, path string) *StubResponse {
+	r := StubBytes(status, nil)
+	r.body = func() ([]byte, error) { return ioutil.ReadFile(path) }
+	return r
+}
+
+// WithHeader adds a header to the response.
+func (r *StubResponse) WithHeader(key, value string) *StubResponse {
+	r.header.Add(key, value)
+	return r
+}
+
+// WithLatency delays the response by d, or until the request is cancelled.
+func (r *StubResponse) WithLatency(d time.Duration) *StubResponse {
+	r.latency = d
+	return r
+}
+
+func (r *StubResponse) response(req *http.Request) (*http.Response, error) {
+	if r.latency > 0 {
+		if err := sleep(req.Context(), r.latency); err != nil {
+			return nil, err
+		}
+	}
+	p, err := r.body()
+	if err != nil {
+		return nil, err
+	}
+	return newResponse(req, r.status, r.header.Clone(), p), nil
+}
+
+// newResponse returns a response to req made of the status, header and
+// body, as produced by the in-process transports of this package.
+func newResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// sleep waits for d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,131 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStubPrecedence(t *testing.T) {
+	stub := NewStub().
+		Handle("GET", "https://api.example.com/users/me", Stub(200, "me")).
+		Handle("GET", "https://api.example.com/users/*", StubJSON(200, []string{"ada", "bob"})).
+		HandleRegexp("", regexp.MustCompile(`/items/\d+$`), Stub(200, "item")).
+		Handle("*", "https://api.example.com/v?/*", Stub(200, "versioned").WithHeader("X-Version", "1")).
+		HandleDefault(Stub(404, ""))
+	c := NewWithTransport(stub)
+
+	tests := []struct {
+		method, url string
+		status      int
+		body        string
+	}{
+		// The first route that matches wins, so the specific one is added
+		// before the glob.
+		{"GET", "https://api.example.com/users/me", 200, "me"},
+		{"GET", "https://api.example.com/users/42", 200, `["ada","bob"]`},
+		{"GET", "https://api.example.com/users/42/posts?page=2", 200, `["ada","bob"]`},
+		{"POST", "https://api.example.com/users/42", 404, ""},
+		{"DELETE", "https://shop.example.com/items/7", 200, "item"},
+		{"GET", "https://shop.example.com/items/7x", 404, ""},
+		{"PUT", "https://api.example.com/v2/things", 200, "versioned"},
+		{"GET", "https://api.example.com/v10/things", 404, ""},
+		{"GET", "https://other.example.com/", 404, ""},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, tt.url, nil)
+		resp, err := stub.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode != tt.status || string(body) != tt.body {
+			t.Errorf("%s %s: got %d %q, want %d %q", tt.method, tt.url, resp.StatusCode, body, tt.status, tt.body)
+		}
+	}
+
+	var users []string
+	if err := c.JSON("https://api.example.com/users/", &users); err != nil || len(users) != 2 {
+		t.Errorf("got %v, %v", users, err)
+	}
+	if got := stub.Calls("GET", "https://api.example.com/users/*"); got != 3 {
+		t.Errorf("got %d calls of the users route, want 3", got)
+	}
+	if got := stub.Calls("GET", "https://api.example.com/users/me"); got != 1 {
+		t.Errorf("got %d calls of the me route, want 1", got)
+	}
+}
+
+func TestStubPassThrough(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "real "+r.URL.Path)
+	}))
+	defer srv.Close()
+
+	stub := NewStub().Handle("GET", srv.URL+"/stubbed/*", Stub(200, "stubbed")).PassThrough(nil)
+	c := NewWithTransport(stub)
+	for path, want := range map[string]string{"/stubbed/a": "stubbed", "/other": "real /other"} {
+		if got, err := c.String(srv.URL + path); err != nil || got != want {
+			t.Errorf("%s: got %q, %v, want %q", path, got, err, want)
+		}
+	}
+
+	c = NewWithTransport(NewStub())
+	if _, err := c.Bytes(srv.URL); err == nil || !strings.Contains(err.Error(), "no stub for GET") {
+		t.Errorf("got %v without a route, default or pass through", err)
+	}
+}
+
+func TestStubLatency(t *testing.T) {
+	stub := NewStub().Handle("GET", "*", Stub(200, "late").WithLatency(50*time.Millisecond))
+	c := NewWithTransport(stub)
+
+	start := time.Now()
+	if s, err := c.String("https://api.example.com/"); err != nil || s != "late" {
+		t.Fatalf("got %q, %v", s, err)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("answered after %v, want at least 50ms", d)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, err := c.Bytes("https://api.example.com/", WithContext(ctx)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d >= 50*time.Millisecond {
+		t.Errorf("a cancelled request waited %v", d)
+	}
+}
+
+func TestStubFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	c := NewWithTransport(NewStub().Handle("GET", "*", StubFile(200, path).WithHeader("Content-Type", "application/json")))
+
+	// The file is read when the response is served.
+	if err := ioutil.WriteFile(path, []byte(`[{"name":"ada"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var users []struct{ Name string }
+	if err := c.JSON("https://api.example.com/users", &users); err != nil || len(users) != 1 || users[0].Name != "ada" {
+		t.Errorf("got %v, %v", users, err)
+	}
+
+	c = NewWithTransport(NewStub().Handle("GET", "*", StubFile(200, path+".missing")))
+	if _, err := c.Bytes("https://api.example.com/users"); err == nil {
+		t.Error("got no error for a missing file")
+	}
+	c = NewWithTransport(NewStub().Handle("GET", "*", StubJSON(200, make(chan int))))
+	if _, err := c.Bytes("https://api.example.com/users"); err == nil {
+		t.Error("got no error for a value JSON cannot encode")
+	}
+}
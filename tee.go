@@ -0,0 +1,86 @@
+package httpclient
+
+import (
+	"io"
+)
+
+// WithTee copies the response body to w as it is read, e.g. to archive the
+// exact bytes a server sent while JSON decodes them. When the body is
+// closed before its end, e.g. when decoding fails halfway, up to 4KB more
+// of it are copied, so that w gets the whole of a body that was nearly all
+// read, but closing an endless stream does not hang. An error writing to w
+// fails the call, unless WithTeeBestEffort is given.
+func WithTee(w io.Writer) RequestOption {
+	return func(o *requestOptions) {
+		o.tee = w
+	}
+}
+
+// WithTeeFactory is WithTee for Files and Download: each download is
+// copied to the writer returned by fn for its index and URL. fn may return
+// nil to skip a download.
+func WithTeeFactory(fn func(index int, url string) io.Writer) RequestOption {
+	return func(o *requestOptions) {
+		o.teeFactory = fn
+	}
+}
+
+// WithTeeBestEffort makes an error writing to the writer of WithTee be
+// logged rather than fail the call. Nothing more is written to the writer
+// after an error.
+func WithTeeBestEffort() RequestOption {
+	return func(o *requestOptions) {
+		o.teeBestEffort = true
+	}
+}
+
+// teeBody copies what is read from a response body to a writer.
+type teeBody struct {
+	io.ReadCloser
+	c          *httpClient
+	w          io.Writer
+	url        string
+	bestEffort bool
+	err        error
+}
+
+func (b *teeBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && b.err == nil {
+		if _, werr := b.w.Write(p[:n]); werr != nil {
+			b.failed(werr)
+			if !b.bestEffort {
+				// The call fails, so the bytes read do not matter, and
+				// readers such as io.ReadFull would drop an error that
+				// came with the last of them.
+				return 0, werr
+			}
+		}
+	}
+	return n, err
+}
+
+// Close copies up to drainLimit bytes of the rest of the body to the
+// writer, then closes the body.
+func (b *teeBody) Close() error {
+	if b.err == nil {
+		if _, err := io.Copy(b.w, io.LimitReader(b.ReadCloser, drainLimit)); err != nil && b.err == nil {
+			b.failed(err)
+		}
+	}
+	err := b.ReadCloser.Close()
+	if err == nil && !b.bestEffort {
+		err = b.err
+	}
+	return err
+}
+
+func (b *teeBody) failed(err error) {
+	b.err = err
+	if b.c.logs(LevelWarn) {
+		b.c.log(LevelWarn, "tee failed", map[string]interface{}{
+			"url":   b.url,
+			"error": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package httpclient
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTeeJSON(t *testing.T) {
+	const body = `{"name":"widget","count":3}` + "\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+	var tee bytes.Buffer
+	var v struct{ Name string }
+	if err := New().JSON(srv.URL, &v, WithTee(&tee)); err != nil {
+		t.Fatal(err)
+	}
+	if tee.String() != body {
+		t.Errorf("teed %q, want %q", tee.String(), body)
+	}
+}
+
+func TestTeeDecodeFailsHalfway(t *testing.T) {
+	body := `{"items":[` + strings.Repeat(`{"n":1},`, 200) + `{"n":oops}]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+	var tee bytes.Buffer
+	var v interface{}
+	if err := New().JSON(srv.URL, &v, WithTee(&tee)); err == nil {
+		t.Fatal("got no error")
+	}
+	if tee.String() != body {
+		t.Errorf("teed %d bytes, want the %d of the body", tee.Len(), len(body))
+	}
+}
+
+func TestTeeErrorBody(t *testing.T) {
+	const body = `{"error":"nope"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+	var tee bytes.Buffer
+	if _, err := New().Bytes(srv.URL, WithTee(&tee)); err == nil {
+		t.Fatal("got no error")
+	}
+	if tee.String() != body {
+		t.Errorf("teed %q, want %q", tee.String(), body)
+	}
+}
+
+type failingWriter struct{ n int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.n++
+	return 0, errors.New("disk full")
+}
+
+func TestTeeWriterError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+	defer srv.Close()
+	if _, err := New().Bytes(srv.URL, WithTee(&failingWriter{})); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("got %v, want the writer error", err)
+	}
+
+	logs := &recordLogger{}
+	w := &failingWriter{}
+	got, err := New(WithLogger(logs)).String(srv.URL, WithTee(w), WithTeeBestEffort())
+	if err != nil || got != "hello" {
+		t.Errorf("best effort: got %q, %v", got, err)
+	}
+	if w.n != 1 {
+		t.Errorf("writer called %d times after failing, want 1", w.n)
+	}
+	if !strings.Contains(logs.String(), "tee failed") {
+		t.Errorf("failure not logged: %s", logs)
+	}
+}
+
+func TestTeeCloseEndlessStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := bytes.Repeat([]byte("x"), 1024)
+		for r.Context().Err() == nil {
+			if _, err := w.Write(p); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+	var tee bytes.Buffer
+	resp, err := New().Get(srv.URL, WithTee(&tee))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadFull(resp.Body, make([]byte, 100))
+	done := make(chan struct{})
+	go func() {
+		resp.Body.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close hangs on an endless body")
+	}
+	if tee.Len() > 100+drainLimit {
+		t.Errorf("teed %d bytes, want at most %d", tee.Len(), 100+drainLimit)
+	}
+}
+
+func TestTeeFactory(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "file "+r.URL.Path)
+	}))
+	defer srv.Close()
+	urls := []string{srv.URL + "/a", srv.URL + "/b", srv.URL + "/c"}
+	var mu sync.Mutex
+	tees := make(map[int]*bytes.Buffer)
+	var files []File
+	err := New().Files(urls, &files, WithTeeFactory(func(i int, url string) io.Writer {
+		if i == 1 {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		tees[i] = &bytes.Buffer{}
+		return tees[i]
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tees) != 2 {
+		t.Fatalf("got %d writers, want 2", len(tees))
+	}
+	for i, b := range tees {
+		if want := fmt.Sprintf("file /%c", 'a'+i); b.String() != want {
+			t.Errorf("tee %d: got %q, want %q", i, b, want)
+		}
+	}
+}
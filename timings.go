@@ -0,0 +1,138 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings breaks down where the time of a request went. Zero times mean the
+// phase did not happen, e.g. no DNS lookup or TLS handshake on a reused
+// connection. When a request is redirected the phases of the last hop are
+// recorded.
+type Timings struct {
+	Start                time.Time
+	DNSStart             time.Time
+	DNSDone              time.Time
+	ConnectDone          time.Time
+	TLSHandshakeDone     time.Time
+	GotConn              time.Time
+	GotFirstResponseByte time.Time
+	BodyReadComplete     time.Time
+
+	// Reused reports whether the connection had been used for a previous
+	// request.
+	Reused bool
+
+	// WasIdle reports whether the connection was taken from the idle pool,
+	// and IdleTime for how long it had been idle.
+	WasIdle  bool
+	IdleTime time.Duration
+}
+
+// DNS returns the duration of the DNS lookup.
+func (t *Timings) DNS() time.Duration {
+	return since(t.DNSStart, t.DNSDone)
+}
+
+// TTFB returns the time from the start of the request to the first byte of
+// the response.
+func (t *Timings) TTFB() time.Duration {
+	return since(t.Start, t.GotFirstResponseByte)
+}
+
+// Total returns the time from the start of the request until its body was
+// read completely.
+func (t *Timings) Total() time.Duration {
+	return since(t.Start, t.BodyReadComplete)
+}
+
+func since(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// WithTimings records a Timings breakdown for every request. Use TimingsOf
+// to get it from a response, or the Timings field of a downloaded File.
+func WithTimings() Option {
+	return func(c *httpClient) {
+		c.timings = true
+	}
+}
+
+// TimingsOf returns the timings recorded for resp, or nil if the client
+// that made the request was not created with WithTimings.
+func TimingsOf(resp *http.Response) *Timings {
+	if resp == nil || resp.Request == nil {
+		return nil
+	}
+	r, ok := resp.Request.Context().Value(timingsKey{}).(*timingsRecorder)
+	if !ok {
+		return nil
+	}
+	return r.snapshot()
+}
+
+type timingsKey struct{}
+
+// timingsRecorder guards a Timings against the trace hooks, which may run
+// concurrently, e.g. while dialing several addresses at once.
+type timingsRecorder struct {
+	mu sync.Mutex
+	t  Timings
+}
+
+func (r *timingsRecorder) set(f func(t *Timings)) {
+	r.mu.Lock()
+	f(&r.t)
+	r.mu.Unlock()
+}
+
+func (r *timingsRecorder) snapshot() *Timings {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.t
+	return &t
+}
+
+// withTimings returns req with a client trace that records into a new
+// recorder.
+func withTimings(req *http.Request) (*http.Request, *timingsRecorder) {
+	r := &timingsRecorder{t: Timings{Start: time.Now()}}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			r.set(func(t *Timings) { t.DNSStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			r.set(func(t *Timings) { t.DNSDone = time.Now() })
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				r.set(func(t *Timings) { t.ConnectDone = time.Now() })
+			}
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				r.set(func(t *Timings) { t.TLSHandshakeDone = time.Now() })
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			r.set(func(t *Timings) {
+				t.GotConn = time.Now()
+				t.Reused = info.Reused
+				t.WasIdle = info.WasIdle
+				t.IdleTime = info.IdleTime
+			})
+		},
+		GotFirstResponseByte: func() {
+			r.set(func(t *Timings) { t.GotFirstResponseByte = time.Now() })
+		},
+	}
+	ctx := context.WithValue(req.Context(), timingsKey{}, r)
+	return req.WithContext(httptrace.WithClientTrace(ctx, trace)), r
+}
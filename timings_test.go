@@ -0,0 +1,132 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTimings(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		time.Sleep(time.Millisecond)
+		io.WriteString(w, "hello")
+	}))
+	defer srv.Close()
+	// Use a name so that the first request looks it up.
+	url := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+
+	c := New(WithTimings(), WithInsecureSkipVerify())
+	var hooked []*Timings
+	c.OnResponse(func(resp *http.Response, elapsed time.Duration) {
+		hooked = append(hooked, TimingsOf(resp))
+	})
+
+	_, first, err := c.BytesResponse(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := first.Timings
+	if tm == nil {
+		t.Fatal("no timings")
+	}
+	ordered := []struct {
+		name string
+		t    time.Time
+	}{
+		{"Start", tm.Start},
+		{"DNSStart", tm.DNSStart},
+		{"DNSDone", tm.DNSDone},
+		{"ConnectDone", tm.ConnectDone},
+		{"TLSHandshakeDone", tm.TLSHandshakeDone},
+		{"GotConn", tm.GotConn},
+		{"GotFirstResponseByte", tm.GotFirstResponseByte},
+		{"BodyReadComplete", tm.BodyReadComplete},
+	}
+	for i, p := range ordered {
+		if p.t.IsZero() {
+			t.Errorf("%s not set", p.name)
+		} else if i > 0 && p.t.Before(ordered[i-1].t) {
+			t.Errorf("%s %v before %s %v", p.name, p.t, ordered[i-1].name, ordered[i-1].t)
+		}
+	}
+	if tm.Reused {
+		t.Error("first request reused a connection")
+	}
+	if tm.TTFB() <= 0 || tm.Total() < tm.TTFB() {
+		t.Errorf("TTFB %v, Total %v", tm.TTFB(), tm.Total())
+	}
+
+	_, second, err := c.BytesResponse(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm = second.Timings
+	if !tm.Reused || !tm.WasIdle {
+		t.Errorf("second request: Reused %v, WasIdle %v", tm.Reused, tm.WasIdle)
+	}
+	if !tm.DNSStart.IsZero() || !tm.ConnectDone.IsZero() || !tm.TLSHandshakeDone.IsZero() {
+		t.Errorf("second request dialed: %+v", tm)
+	}
+	if tm.GotFirstResponseByte.IsZero() || tm.BodyReadComplete.Before(tm.GotFirstResponseByte) {
+		t.Errorf("second request: %+v", tm)
+	}
+
+	if len(hooked) != 2 || hooked[0] == nil || hooked[0].GotFirstResponseByte.IsZero() {
+		t.Errorf("OnResponse got timings %v", hooked)
+	}
+}
+
+func TestTimingsFiles(t *testing.T) {
+	// Hold every request until all have arrived, so that none reuses the
+	// connection of another.
+	const n = 8
+	var wg sync.WaitGroup
+	wg.Add(n)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wg.Done()
+		wg.Wait()
+		io.WriteString(w, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	var urls []string
+	for i := 0; i < n; i++ {
+		urls = append(urls, srv.URL+"/"+string(rune('a'+i)))
+	}
+	var files []File
+	if err := New(WithTimings(), WithInsecureSkipVerify()).Files(urls, &files, WithConcurrency(n)); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[*Timings]bool)
+	for _, f := range files {
+		tm := f.Timings
+		if tm == nil {
+			t.Fatalf("%s: no timings", f.Name)
+		}
+		if seen[tm] {
+			t.Errorf("%s: timings shared", f.Name)
+		}
+		seen[tm] = true
+		if tm.Reused || tm.TLSHandshakeDone.IsZero() || tm.GotFirstResponseByte.Before(tm.TLSHandshakeDone) ||
+			tm.BodyReadComplete.Before(tm.GotFirstResponseByte) {
+			t.Errorf("%s: %+v", f.Name, tm)
+		}
+	}
+}
+
+func TestTimingsOff(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	_, r, err := New().BytesResponse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Timings != nil {
+		t.Errorf("got timings %+v without WithTimings", r.Timings)
+	}
+}
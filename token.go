@@ -0,0 +1,183 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A TokenProvider returns bearer tokens for SetTokenProvider.
+type TokenProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenFunc adapts a function to a TokenProvider.
+type TokenFunc func(ctx context.Context) (string, error)
+
+// Token calls f.
+func (f TokenFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// TokenExpiredError is returned, without sending the request, when the JWT
+// bearer token has expired, or is about to, and could not be refreshed.
+type TokenExpiredError struct {
+	Expiry time.Time
+
+	// Err is the error of the TokenProvider, or nil if there is none.
+	Err error
+}
+
+// Error returns the error message.
+func (e *TokenExpiredError) Error() string {
+	msg := "httpclient: bearer token expires at " + e.Expiry.Format(time.RFC3339)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the error of the TokenProvider.
+func (e *TokenExpiredError) Unwrap() error {
+	return e.Err
+}
+
+type bearer struct {
+	mu       sync.Mutex
+	token    string
+	provider TokenProvider
+	check    bool
+	skew     time.Duration
+}
+
+func (c *httpClient) bearer() *bearer {
+	if c.auth == nil {
+		c.auth = &bearer{}
+	}
+	return c.auth
+}
+
+func (b *bearer) clone() *bearer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return &bearer{token: b.token, provider: b.provider, check: b.check, skew: b.skew}
+}
+
+// SetBearerToken makes c send token in an "Authorization: Bearer" header
+// with every request that does not set Authorization itself. It must be
+// called before c is used.
+func (c *httpClient) SetBearerToken(token string) {
+	b := c.bearer()
+	b.mu.Lock()
+	b.token = token
+	b.mu.Unlock()
+}
+
+// SetTokenProvider makes c send bearer tokens from p as SetBearerToken
+// does. p is asked for a token before the first request, and again before
+// a request if the token has expired and SetTokenExpiryCheck is on. It
+// must be called before c is used.
+func (c *httpClient) SetTokenProvider(p TokenProvider) {
+	b := c.bearer()
+	b.mu.Lock()
+	b.provider, b.token = p, ""
+	b.mu.Unlock()
+}
+
+// SetTokenExpiryCheck makes c read the exp claim of a JWT bearer token,
+// without verifying the token, and refresh it from the TokenProvider when
+// it expires within skew. If it cannot, a request with a token that has
+// expired or is about to fails with a *TokenExpiredError before reaching
+// the network. A token that is not a JWT with an exp claim is sent as is.
+// It must be called before c is used.
+func (c *httpClient) SetTokenExpiryCheck(skew time.Duration) {
+	b := c.bearer()
+	b.mu.Lock()
+	b.check, b.skew = true, skew
+	b.mu.Unlock()
+}
+
+// SetBearerToken sets the bearer token of the default client.
+func SetBearerToken(token string) {
+	defaultClient().SetBearerToken(token)
+}
+
+// SetTokenProvider sets the bearer token provider of the default client.
+func SetTokenProvider(p TokenProvider) {
+	defaultClient().SetTokenProvider(p)
+}
+
+// apply sets the Authorization header of req unless it is already set.
+func (b *bearer) apply(req *http.Request) error {
+	if req.Header.Get("Authorization") != "" {
+		return nil
+	}
+	token, err := b.get(req.Context())
+	if err != nil || token == "" {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (b *bearer) get(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.token == "" && b.provider != nil {
+		token, err := b.provider.Token(ctx)
+		if err != nil {
+			return "", err
+		}
+		b.token = token
+	}
+	if !b.check {
+		return b.token, nil
+	}
+	exp, ok := jwtExpiry(b.token)
+	if !ok || time.Until(exp) > b.skew {
+		return b.token, nil
+	}
+	if b.provider == nil {
+		if time.Now().Before(exp) {
+			return b.token, nil
+		}
+		return "", &TokenExpiredError{Expiry: exp}
+	}
+	token, err := b.provider.Token(ctx)
+	if err != nil {
+		return "", &TokenExpiredError{Expiry: exp, Err: err}
+	}
+	b.token = token
+	if exp, ok := jwtExpiry(token); ok && !time.Now().Before(exp) {
+		return "", &TokenExpiredError{Expiry: exp}
+	}
+	return token, nil
+}
+
+// jwtExpiry returns the exp claim of the JWT token.
+func jwtExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	p, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp *json.Number `json:"exp"`
+	}
+	if err := json.Unmarshal(p, &claims); err != nil || claims.Exp == nil {
+		return time.Time{}, false
+	}
+	exp, err := claims.Exp.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	sec := int64(exp)
+	return time.Unix(sec, int64((exp-float64(sec))*1e9)), true
+}
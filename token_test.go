@@ -0,0 +1,156 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwt returns an unsigned JWT with the claims given as JSON.
+func jwt(claims string) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	return enc([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + enc([]byte(claims)) + ".sig"
+}
+
+// jwtExp returns an unsigned JWT expiring in d.
+func jwtExp(d time.Duration) string {
+	return jwt(fmt.Sprintf(`{"sub":"ann","exp":%d}`, time.Now().Add(d).Unix()))
+}
+
+func TestTokenExpiryCheck(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer srv.Close()
+
+	fresh, soon := jwtExp(time.Hour), jwtExp(30*time.Second)
+	badJSON := "a." + base64.RawURLEncoding.EncodeToString([]byte("{")) + ".c"
+	refreshErr := errors.New("refresh failed")
+	tests := []struct {
+		name    string
+		token   string   // set with SetBearerToken, if not empty
+		tokens  []string // returned by the provider in turn, if any
+		fail    bool     // the provider fails after its tokens run out
+		want    string
+		refresh int // calls to the provider
+		expired bool
+	}{
+		{name: "fresh", tokens: []string{fresh}, want: fresh, refresh: 1},
+		{name: "fresh static", token: fresh, want: fresh},
+		{name: "near expiry", tokens: []string{soon, fresh}, want: fresh, refresh: 2},
+		{name: "near expiry static", token: soon, want: soon},
+		{name: "expired", tokens: []string{jwtExp(-time.Hour), fresh}, want: fresh, refresh: 2},
+		{name: "expired refresh fails", tokens: []string{jwtExp(-time.Hour)}, fail: true, refresh: 2, expired: true},
+		{name: "near expiry refresh fails", tokens: []string{jwtExp(time.Second)}, fail: true, refresh: 2, expired: true},
+		{name: "refreshed but expired", tokens: []string{jwtExp(-time.Hour), jwtExp(-time.Minute)}, refresh: 2, expired: true},
+		{name: "expired static", token: jwtExp(-time.Second), expired: true},
+		{name: "malformed", tokens: []string{"not-a-jwt"}, want: "not-a-jwt", refresh: 1},
+		{name: "malformed static", token: "a.b.c", want: "a.b.c"},
+		{name: "bad base64", token: "a.!!!.c", want: "a.!!!.c"},
+		{name: "bad json", token: badJSON, want: badJSON},
+		{name: "no exp", token: jwt(`{"sub":"ann"}`), want: jwt(`{"sub":"ann"}`)},
+		{name: "string exp", token: jwt(`{"exp":"soon"}`), want: jwt(`{"exp":"soon"}`)},
+	}
+	for _, tt := range tests {
+		c := New()
+		calls := 0
+		if tt.token != "" {
+			c.SetBearerToken(tt.token)
+		} else {
+			c.SetTokenProvider(TokenFunc(func(ctx context.Context) (string, error) {
+				calls++
+				if calls > len(tt.tokens) {
+					return "", refreshErr
+				}
+				return tt.tokens[calls-1], nil
+			}))
+		}
+		c.SetTokenExpiryCheck(time.Minute)
+
+		atomic.StoreInt32(&hits, 0)
+		got, err := c.String(srv.URL)
+		if calls != tt.refresh {
+			t.Errorf("%s: provider called %d times, want %d", tt.name, calls, tt.refresh)
+		}
+		if tt.expired {
+			var terr *TokenExpiredError
+			if !errors.As(err, &terr) || terr.Expiry.IsZero() {
+				t.Errorf("%s: got %v, want a *TokenExpiredError", tt.name, err)
+			} else if tt.fail != errors.Is(err, refreshErr) {
+				t.Errorf("%s: got %v, wrapping the provider error: %v", tt.name, err, tt.fail)
+			}
+			if n := atomic.LoadInt32(&hits); n != 0 {
+				t.Errorf("%s: got %d requests, want none", tt.name, n)
+			}
+			continue
+		}
+		if err != nil || got != "Bearer "+tt.want {
+			t.Errorf("%s: got %q, %v, want the token %s", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestTokenExpiryCheckKeepsToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer srv.Close()
+
+	// A fresh token is kept for the next requests, and a request setting
+	// Authorization itself is left alone.
+	calls := 0
+	c := New()
+	c.SetTokenProvider(TokenFunc(func(ctx context.Context) (string, error) {
+		calls++
+		return jwtExp(time.Duration(calls) * time.Hour), nil
+	}))
+	c.SetTokenExpiryCheck(time.Minute)
+	first, _ := c.String(srv.URL)
+	second, _ := c.String(srv.URL)
+	own, _ := c.String(srv.URL, WithRequestHeader("Authorization", "Basic x"))
+	if calls != 1 || first != second || first == "" || own != "Basic x" {
+		t.Errorf("got %d calls, %q, %q, %q", calls, first, second, own)
+	}
+
+	// Without the check, an expired token is sent.
+	expired := jwtExp(-time.Hour)
+	c = New()
+	c.SetBearerToken(expired)
+	if got, err := c.String(srv.URL); err != nil || got != "Bearer "+expired {
+		t.Errorf("no check: got %q, %v", got, err)
+	}
+}
+
+func TestJWTExpiry(t *testing.T) {
+	enc := base64.URLEncoding.EncodeToString
+	tests := []struct {
+		token string
+		want  time.Time
+		ok    bool
+	}{
+		{jwt(`{"exp":1700000000}`), time.Unix(1700000000, 0), true},
+		{jwt(`{"exp":1700000000.5}`), time.Unix(1700000000, 5e8), true},
+		{jwt(`{"exp":1.7e9}`), time.Unix(1700000000, 0), true},
+		{"h." + enc([]byte(`{"exp":1700000000}`)) + ".s", time.Unix(1700000000, 0), true},
+		{jwt(`{"exp":null}`), time.Time{}, false},
+		{jwt(`{"exp":"1700000000"}`), time.Unix(1700000000, 0), true},
+		{jwt(`[1]`), time.Time{}, false},
+		{"h.p", time.Time{}, false},
+		{"h.p.s.x", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := jwtExpiry(tt.token)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("%s: got %v, %v, want %v, %v", tt.token, got, ok, tt.want, tt.ok)
+		}
+	}
+}
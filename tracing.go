@@ -0,0 +1,52 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+)
+
+// A Tracer starts a span for every request the client sends. The otelclient
+// subpackage implements it on top of OpenTelemetry; the interface keeps that
+// dependency out of this package.
+type Tracer interface {
+	// Start starts a span for req, which is the child of any span found in
+	// the request context, and returns the request to send in its place,
+	// typically carrying the span in its context and propagation headers.
+	Start(req *http.Request) (*http.Request, Span)
+}
+
+// A Span is the tracing span of one request.
+type Span interface {
+	// End ends the span once the request has failed or its response body
+	// has been closed.
+	End(result SpanResult)
+}
+
+// SpanResult describes how the request of a span went.
+type SpanResult struct {
+	// StatusCode is the status of the response, zero if there was none.
+	StatusCode int
+
+	// Err is the error the request failed with, if any.
+	Err error
+
+	// Attempt is the number of the attempt of the request, from 1. Every
+	// retry of a request gets a span of its own.
+	Attempt int
+}
+
+// WithTracing traces every request with t.
+func WithTracing(t Tracer) Option {
+	return func(c *httpClient) {
+		c.tracer = t
+	}
+}
+
+// WithContext makes the request with ctx. Its deadline and cancellation
+// apply to the request and tracing spans are created as its children. Files
+// uses it for every download.
+func WithContext(ctx context.Context) RequestOption {
+	return func(o *requestOptions) {
+		o.ctx = ctx
+	}
+}
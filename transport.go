@@ -0,0 +1,113 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WithHTTPClient makes the client send its requests with a copy of hc,
+// keeping its transport, timeout, cookie jar and redirect policy. It
+// replaces the http.Client set by earlier options. The transports of the
+// other options, such as WithCache or WithSigner, still wrap that of hc.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *httpClient) {
+		n := *hc
+		c.client = &n
+		c.rt = hc.Transport
+	}
+}
+
+// WithTransport makes the client send its requests with rt, replacing the
+// transport set by earlier options. The transports of the other options,
+// such as WithCache or WithSigner, still wrap rt, including in a Clone.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *httpClient) {
+		c.rt = rt
+	}
+}
+
+// WithTimeout bounds the time of every request, from sending it to reading
+// the end of the response body, to d, as http.Client.Timeout does.
+func WithTimeout(d time.Duration) Option {
+	return func(c *httpClient) {
+		c.client.Timeout = d
+	}
+}
+
+// WithProxy sends the requests through the proxy at proxyURL, e.g.
+// "http://proxy.example.com:3128" or "socks5://localhost:1080". The
+// transport of the client must be an *http.Transport, which is the
+// default. An invalid proxy makes every request fail.
+func WithProxy(proxyURL string) Option {
+	return func(c *httpClient) {
+		u, err := url.Parse(proxyURL)
+		if err == nil && (u.Scheme == "" || u.Host == "") {
+			err = fmt.Errorf("not an absolute URL")
+		}
+		if err != nil {
+			c.configErr = fmt.Errorf("httpclient: invalid proxy URL %q: %v", proxyURL, err)
+			return
+		}
+		if t := c.httpTransport("WithProxy"); t != nil {
+			t.Proxy = http.ProxyURL(u)
+		}
+	}
+}
+
+// WithTLSConfig makes the client use a copy of cfg for its TLS
+// connections. The transport of the client must be an *http.Transport,
+// which is the default.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *httpClient) {
+		if t := c.httpTransport("WithTLSConfig"); t != nil {
+			t.TLSClientConfig = cfg.Clone()
+		}
+	}
+}
+
+// WithInsecureSkipVerify makes the client accept any TLS certificate,
+// which leaves its connections open to interception. Use it only against
+// test servers.
+func WithInsecureSkipVerify() Option {
+	return func(c *httpClient) {
+		if t := c.httpTransport("WithInsecureSkipVerify"); t != nil {
+			if t.TLSClientConfig == nil {
+				t.TLSClientConfig = &tls.Config{}
+			}
+			t.TLSClientConfig.InsecureSkipVerify = true
+		}
+	}
+}
+
+// httpTransport replaces the transport of c, which must be nil or an
+// *http.Transport, with a copy that the option may change, and returns it.
+// Otherwise it records an error that makes every request fail.
+func (c *httpClient) httpTransport(option string) *http.Transport {
+	var t *http.Transport
+	switch rt := c.rt.(type) {
+	case nil:
+		t = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		t = rt.Clone()
+	default:
+		c.configErr = fmt.Errorf("httpclient: %s needs an *http.Transport, not %T", option, rt)
+		return nil
+	}
+	c.rt = t
+	return t
+}
+
+// SetDefault makes c the client of the package-level functions, e.g. to
+// serve them from a test handler, and returns the client it replaces. It
+// may be called while they are in use; the calls in flight finish with the
+// client they started with.
+func SetDefault(c *httpClient) *httpClient {
+	clientMu.Lock()
+	defer clientMu.Unlock()
+	old := client
+	client = c
+	return old
+}
@@ -0,0 +1,161 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "proxied "+r.URL.String())
+	}))
+	defer proxy.Close()
+	own := &http.Transport{}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want string // the body, or the error
+	}{
+		{"proxy", []Option{WithProxy(proxy.URL)}, "proxied http://example.invalid/x"},
+		{"after WithHTTPClient", []Option{WithHTTPClient(&http.Client{Transport: own}), WithProxy(proxy.URL)}, "proxied http://example.invalid/x"},
+		{"after WithTransport", []Option{WithTransport(own), WithProxy(proxy.URL)}, "proxied http://example.invalid/x"},
+		{"replaced by WithTransport", []Option{WithProxy(proxy.URL), WithTransport(&staticTransport{body: []byte("direct"), length: -1})}, "direct"},
+		{"replaced by WithHTTPClient", []Option{WithProxy(proxy.URL), WithHTTPClient(&http.Client{Transport: &staticTransport{body: []byte("direct"), length: -1}})}, "direct"},
+		{"no scheme", []Option{WithProxy("proxy.example.com")}, `invalid proxy URL "proxy.example.com"`},
+		{"bad URL", []Option{WithProxy("http://[::1")}, `invalid proxy URL "http://[::1"`},
+		{"other transport", []Option{WithTransport(&staticTransport{}), WithProxy(proxy.URL)}, "WithProxy needs an *http.Transport, not *httpclient.staticTransport"},
+	}
+	for _, tt := range tests {
+		got, err := New(tt.opts...).String("http://example.invalid/x")
+		if err != nil {
+			got = err.Error()
+		}
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	// The transports given to the client are left alone.
+	if own.Proxy != nil {
+		t.Error("WithProxy changed the transport of the caller")
+	}
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "secure")
+	}))
+	defer srv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	trusted := &tls.Config{RootCAs: roots}
+	untrusted := &tls.Config{}
+
+	tests := []struct {
+		name string
+		opts []Option
+		ok   bool
+	}{
+		{"default", nil, false},
+		{"roots", []Option{WithTLSConfig(trusted)}, true},
+		{"insecure", []Option{WithInsecureSkipVerify()}, true},
+		{"insecure after config", []Option{WithTLSConfig(untrusted), WithInsecureSkipVerify()}, true},
+		{"config after insecure", []Option{WithInsecureSkipVerify(), WithTLSConfig(untrusted)}, false},
+		{"replaced by WithTransport", []Option{WithInsecureSkipVerify(), WithTransport(&http.Transport{})}, false},
+		{"after WithHTTPClient", []Option{WithHTTPClient(&http.Client{Transport: &http.Transport{}}), WithTLSConfig(trusted)}, true},
+	}
+	for _, tt := range tests {
+		got, err := New(tt.opts...).String(srv.URL)
+		if tt.ok && (err != nil || got != "secure") {
+			t.Errorf("%s: got %q, %v", tt.name, got, err)
+		}
+		if !tt.ok && (err == nil || !strings.Contains(err.Error(), "certificate")) {
+			t.Errorf("%s: got %q, %v, want a certificate error", tt.name, got, err)
+		}
+	}
+	if untrusted.InsecureSkipVerify {
+		t.Error("WithInsecureSkipVerify changed the config of the caller")
+	}
+	if _, err := New(WithTransport(&staticTransport{}), WithTLSConfig(trusted)).String(srv.URL); err == nil || !strings.Contains(err.Error(), "WithTLSConfig needs an *http.Transport") {
+		t.Errorf("other transport: got %v", err)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		io.WriteString(w, "late")
+	}))
+	defer srv.Close()
+	hc := &http.Client{}
+
+	tests := []struct {
+		name string
+		opts []Option
+		ok   bool
+	}{
+		{"timeout", []Option{WithTimeout(50 * time.Millisecond)}, false},
+		{"on WithHTTPClient", []Option{WithHTTPClient(hc), WithTimeout(50 * time.Millisecond)}, false},
+		{"replaced by WithHTTPClient", []Option{WithTimeout(50 * time.Millisecond), WithHTTPClient(hc)}, true},
+		{"of WithHTTPClient", []Option{WithHTTPClient(&http.Client{Timeout: 50 * time.Millisecond})}, false},
+		{"longer", []Option{WithTimeout(5 * time.Second)}, true},
+	}
+	for _, tt := range tests {
+		got, err := New(tt.opts...).String(srv.URL)
+		if tt.ok && (err != nil || got != "late") {
+			t.Errorf("%s: got %q, %v", tt.name, got, err)
+		}
+		if !tt.ok && (err == nil || !strings.Contains(err.Error(), "Timeout")) {
+			t.Errorf("%s: got %q, %v, want a timeout", tt.name, got, err)
+		}
+	}
+	if hc.Timeout != 0 {
+		t.Error("WithTimeout changed the http.Client of the caller")
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	stub := New(WithTransport(&staticTransport{body: []byte("stub"), length: 4}))
+	old := SetDefault(stub)
+	defer SetDefault(old)
+	if got, err := String("http://example.invalid/"); err != nil || got != "stub" {
+		t.Errorf("got %q, %v", got, err)
+	}
+
+	// Swapping the default races with none of the package-level functions.
+	other := New(WithTransport(&staticTransport{body: []byte("other"), length: 5}))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if got, err := String("http://example.invalid/"); err != nil || got != "stub" && got != "other" {
+					t.Errorf("got %q, %v", got, err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				SetDefault(other)
+				SetDefault(stub)
+			}
+		}()
+	}
+	wg.Wait()
+	if SetDefault(old) != stub {
+		t.Error("SetDefault did not return the client it replaced")
+	}
+}
@@ -0,0 +1,174 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WithUploadField sets the form field name of the file parts sent by
+// Upload and UploadFromDisk, "file" by default.
+func WithUploadField(name string) RequestOption {
+	return func(o *requestOptions) {
+		o.uploadField = name
+	}
+}
+
+// An uploadPart is a file part of a multipart upload.
+type uploadPart struct {
+	name        string
+	contentType string
+	open        func() (io.Reader, func() error, error)
+}
+
+// Upload issues a multipart/form-data POST to url with fields as form
+// values and each of files as a file part, named after its Name and typed
+// with its ContentType or, without one, a type guessed from its name or
+// contents. The body is streamed as it is sent, and a File kept on disk
+// with WithSpillToDisk is read from its Spill. A response with a status
+// the client does not accept fails with an *Error.
+func (c *httpClient) Upload(url string, files []File, fields map[string]string, opts ...RequestOption) (*http.Response, error) {
+	parts := make([]uploadPart, len(files))
+	for i, f := range files {
+		f := f
+		parts[i] = uploadPart{name: f.Name, contentType: f.ContentType, open: func() (io.Reader, func() error, error) {
+			if f.Spill == nil {
+				return bytes.NewReader(f.Data), nil, nil
+			}
+			if _, err := f.Spill.Seek(0, io.SeekStart); err != nil {
+				return nil, nil, err
+			}
+			return f.Spill, nil, nil
+		}}
+	}
+	return c.upload(url, parts, fields, newRequestOptions(opts))
+}
+
+// UploadFromDisk is Upload with the files at paths, which are read as the
+// body is sent.
+func (c *httpClient) UploadFromDisk(url string, paths []string, fields map[string]string, opts ...RequestOption) (*http.Response, error) {
+	parts := make([]uploadPart, len(paths))
+	for i, p := range paths {
+		p := p
+		parts[i] = uploadPart{name: filepath.Base(p), open: func() (io.Reader, func() error, error) {
+			f, err := os.Open(p)
+			if err != nil {
+				return nil, nil, err
+			}
+			return f, f.Close, nil
+		}}
+	}
+	return c.upload(url, parts, fields, newRequestOptions(opts))
+}
+
+// upload posts fields and parts as a multipart form, written to the
+// request body through a pipe as the transport reads it.
+func (c *httpClient) upload(url string, parts []uploadPart, fields map[string]string, o *requestOptions) (*http.Response, error) {
+	field := o.uploadField
+	if field == "" {
+		field = "file"
+	}
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	mw := multipart.NewWriter(pw)
+	werr := make(chan error, 1)
+	go func() {
+		err := writeMultipart(mw, field, parts, fields)
+		pw.CloseWithError(err)
+		werr <- err
+	}()
+	resp, err := c.sendBody("POST", url, mw.FormDataContentType(), pr, o)
+	select {
+	case e := <-werr:
+		// A file that could not be read is the cause of the failure, or
+		// of the truncated body the server answered.
+		if e != nil && e != io.ErrClosedPipe {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, e
+		}
+	default:
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !c.accepted(resp, o) {
+		err = c.err(resp, "")
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+func writeMultipart(mw *multipart.Writer, field string, parts []uploadPart, fields map[string]string) error {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := mw.WriteField(k, fields[k]); err != nil {
+			return err
+		}
+	}
+	for _, p := range parts {
+		if err := writePart(mw, field, p); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+func writePart(mw *multipart.Writer, field string, p uploadPart) error {
+	r, closer, err := p.open()
+	if err != nil {
+		return err
+	}
+	if closer != nil {
+		defer closer()
+	}
+	ct := p.contentType
+	if ct == "" {
+		ct = mime.TypeByExtension(filepath.Ext(p.name))
+	}
+	if ct == "" {
+		br := bufio.NewReader(r)
+		sniff, _ := br.Peek(512)
+		ct, r = http.DetectContentType(sniff), br
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(field), quoteEscaper.Replace(p.name)))
+	h.Set("Content-Type", ct)
+	w, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// quoteEscaper escapes the quoted strings of a Content-Disposition header,
+// as mime/multipart does.
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// Upload posts files and fields as a multipart form with the default
+// client.
+func Upload(url string, files []File, fields map[string]string, opts ...RequestOption) (*http.Response, error) {
+	return defaultClient().Upload(url, files, fields, opts...)
+}
+
+// UploadFromDisk posts the files at paths and fields as a multipart form
+// with the default client.
+func UploadFromDisk(url string, paths []string, fields map[string]string, opts ...RequestOption) (*http.Response, error) {
+	return defaultClient().UploadFromDisk(url, paths, fields, opts...)
+}
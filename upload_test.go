@@ -0,0 +1,151 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// uploaded is a part of a multipart form, as the server got it.
+type uploaded struct {
+	Field, File, Type, Data string
+}
+
+// uploadServer answers a multipart form with its parts, as JSON, or with
+// a 413 at /reject.
+func uploadServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/reject" {
+			http.Error(w, "too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		mr, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var parts []uploaded
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			data, _ := ioutil.ReadAll(p)
+			parts = append(parts, uploaded{p.FormName(), p.FileName(), p.Header.Get("Content-Type"), string(data)})
+		}
+		json.NewEncoder(w).Encode(parts)
+	}))
+}
+
+func readUploaded(t *testing.T, resp *http.Response) []uploaded {
+	t.Helper()
+	defer resp.Body.Close()
+	var parts []uploaded
+	if err := json.NewDecoder(resp.Body).Decode(&parts); err != nil {
+		t.Fatal(err)
+	}
+	return parts
+}
+
+func TestUpload(t *testing.T) {
+	srv := uploadServer()
+	defer srv.Close()
+
+	files := []File{
+		{Name: "notes.txt", Data: []byte("some notes")},
+		{Name: "photo", Data: []byte("GIF89a...")},
+		{Name: "report", Data: []byte("%PDF-1.4"), ContentType: "application/x-custom"},
+		{Name: `say "hi".json`, Data: []byte(`{}`)},
+	}
+	resp, err := Upload(srv.URL, files, map[string]string{"title": "Q3", "author": "ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uploaded{
+		{"author", "", "", "ann"},
+		{"title", "", "", "Q3"},
+		{"file", "notes.txt", "text/plain; charset=utf-8", "some notes"},
+		{"file", "photo", "image/gif", "GIF89a..."},
+		{"file", "report", "application/x-custom", "%PDF-1.4"},
+		{"file", `say "hi".json`, "application/json", "{}"},
+	}
+	got := readUploaded(t, resp)
+	if len(got) != len(want) {
+		t.Fatalf("got %d parts, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("part %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// The file parts can have another field name.
+	resp, err = Upload(srv.URL, files[:1], nil, WithUploadField("attachment"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readUploaded(t, resp); len(got) != 1 || got[0].Field != "attachment" {
+		t.Errorf("got %+v", got)
+	}
+
+	// A status the client does not accept fails with an *Error.
+	resp, err = Upload(srv.URL+"/reject", files, nil)
+	var herr *Error
+	if resp != nil || !errors.As(err, &herr) || herr.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("got %v, %v, want a 413 *Error", resp, err)
+	}
+}
+
+func TestUploadFromDisk(t *testing.T) {
+	srv := uploadServer()
+	defer srv.Close()
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"index.html": "<p>hi</p>",
+		"document":   "%PDF-1.4 body",
+		"notes":      "plain words",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths := []string{filepath.Join(dir, "index.html"), filepath.Join(dir, "document"), filepath.Join(dir, "notes")}
+
+	resp, err := UploadFromDisk(srv.URL, paths, map[string]string{"kind": "docs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uploaded{
+		{"kind", "", "", "docs"},
+		{"file", "index.html", "text/html; charset=utf-8", "<p>hi</p>"},
+		{"file", "document", "application/pdf", "%PDF-1.4 body"},
+		{"file", "notes", "text/plain; charset=utf-8", "plain words"},
+	}
+	got := readUploaded(t, resp)
+	if len(got) != len(want) {
+		t.Fatalf("got %d parts, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("part %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// A missing file fails the upload with its error.
+	resp, err = UploadFromDisk(srv.URL, append(paths, filepath.Join(dir, "missing.txt")), nil)
+	if resp != nil || !errors.Is(err, os.ErrNotExist) || !strings.Contains(err.Error(), "missing.txt") {
+		t.Errorf("missing: got %v, %v", resp, err)
+	}
+
+	resp, err = UploadFromDisk(srv.URL+"/reject", paths, nil)
+	var herr *Error
+	if resp != nil || !errors.As(err, &herr) || herr.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("reject: got %v, %v, want a 413 *Error", resp, err)
+	}
+}